@@ -0,0 +1,220 @@
+package main
+
+import (
+	"log"
+	"time"
+
+	"github.com/thiderman/drunkenfall/websockets"
+)
+
+// ComebackDeficit is how many kills behind the leader a player has to have
+// been to make a comeback
+const ComebackDeficit = 5
+
+// Achievement is something a player can earn in a match
+//
+// Most achievements can be earned as soon as a round is committed, but the
+// ones that are about winning are only checked when the match ends.
+type Achievement struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Ended       bool   `json:"ended"`
+
+	earned func(m *Match, p *Player) bool
+}
+
+// Award is an achievement earned by a player
+type Award struct {
+	Achievement Achievement `json:"achievement"`
+	Player      string      `json:"player"`
+	Tournament  string      `json:"tournament"`
+	Match       string      `json:"match"`
+	Time        time.Time   `json:"time"`
+}
+
+// AwardMessage is an achievement being earned, pushed over the websocket
+type AwardMessage struct {
+	Award Award `json:"award"`
+}
+
+// Achievements are all the achievements that can be earned
+var Achievements = []Achievement{
+	{
+		ID:          "flawless",
+		Name:        "Flawless",
+		Description: "Won a match without killing yourself",
+		Ended:       true,
+		earned: func(m *Match, p *Player) bool {
+			return m.isWinner(p.Name) && p.Self == 0
+		},
+	},
+	{
+		ID:          "comeback",
+		Name:        "Comeback",
+		Description: "Won a match after trailing the leader by 5 kills",
+		Ended:       true,
+		earned: func(m *Match, p *Player) bool {
+			return m.isWinner(p.Name) && m.deficit(p.Name) >= ComebackDeficit
+		},
+	},
+	{
+		ID:          "kamikaze",
+		Name:        "Kamikaze",
+		Description: "Killed yourself 3 times in a match",
+		earned: func(m *Match, p *Player) bool {
+			return p.Self >= 3
+		},
+	},
+	{
+		ID:          "sweeper",
+		Name:        "Sweeper",
+		Description: "Swept 3 rounds in a match",
+		earned: func(m *Match, p *Player) bool {
+			return p.Sweeps >= 3
+		},
+	},
+	{
+		ID:          "bottoms-up",
+		Name:        "Bottoms Up",
+		Description: "Took 5 shots in a match",
+		earned: func(m *Match, p *Player) bool {
+			return p.Shots >= 5
+		},
+	},
+}
+
+// GetAchievement returns the achievement with the ID
+func GetAchievement(id string) (Achievement, bool) {
+	for _, a := range Achievements {
+		if a.ID == id {
+			return a, true
+		}
+	}
+	return Achievement{}, false
+}
+
+// checkAchievements awards the players of the match the achievements they
+// have earned, and returns the awards
+//
+// The achievements that are about winning are only checked once the match
+// has ended. No achievement is awarded more than once per match.
+func (m *Match) checkAchievements() []Award {
+	awards := []Award{}
+	for i := range m.Players {
+		p := &m.Players[i]
+		if p.IsPrefill() {
+			continue
+		}
+
+		for _, a := range Achievements {
+			if a.Ended && !m.IsEnded() {
+				continue
+			}
+			if p.Achievements[a.ID] != 0 || !a.earned(m, p) {
+				continue
+			}
+
+			p.addAchievement(a.ID)
+			if m.Tournament != nil {
+				// Keep the profile up to date without summing up every match
+				if tp := m.Tournament.getPlayer(p.Name); tp != nil {
+					tp.addAchievement(a.ID)
+				}
+			}
+
+			award := Award{
+				Achievement: a,
+				Player:      p.Name,
+				Match:       m.bracketID(),
+				Time:        time.Now(),
+			}
+			if m.Tournament != nil {
+				award.Tournament = m.Tournament.ID
+			}
+			awards = append(awards, award)
+			log.Printf("%s earned %s in %s", p.Name, a.Name, m.String())
+		}
+	}
+
+	if len(awards) != 0 && m.Tournament != nil {
+		m.Tournament.sendAwards(awards)
+	}
+	return awards
+}
+
+// addAchievement counts an achievement the player has earned
+func (p *Player) addAchievement(id string) {
+	if p.Achievements == nil {
+		p.Achievements = make(map[string]int)
+	}
+	p.Achievements[id]++
+}
+
+// sendAwards pushes awards to the websocket
+func (t *Tournament) sendAwards(awards []Award) {
+	if t.server == nil || t.server.ws == nil {
+		return
+	}
+
+	ws := t.server.ws
+	go func() {
+		for _, a := range awards {
+			ws.SendAll(&websockets.Message{
+				Data:      AwardMessage{Award: a},
+				Transient: true,
+			})
+		}
+	}()
+}
+
+// isWinner returns boolean whether the player won the match, or is in the
+// team that did
+func (m *Match) isWinner(name string) bool {
+	if !m.IsEnded() {
+		return false
+	}
+
+	if m.Tournament != nil && m.Tournament.IsTeamMode() {
+		ts := m.TeamStandings()
+		if len(ts) == 0 {
+			return false
+		}
+		for _, p := range ts[0].Players {
+			if p.Name == name {
+				return true
+			}
+		}
+		return false
+	}
+
+	ps := m.Standings()
+	return len(ps) != 0 && ps[0].Name == name
+}
+
+// deficit returns the most kills the player has been behind the leader of
+// the match after any round
+func (m *Match) deficit(name string) int {
+	index := -1
+	for i, p := range m.Players {
+		if p.Name == name {
+			index = i
+		}
+	}
+	if index == -1 {
+		return 0
+	}
+
+	most := 0
+	for _, r := range m.Rounds {
+		if index >= len(r.Kills) {
+			continue
+		}
+		for _, k := range r.Kills {
+			if d := k - r.Kills[index]; d > most {
+				most = d
+			}
+		}
+	}
+	return most
+}
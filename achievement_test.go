@@ -0,0 +1,98 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// commitRounds commits the same round a number of times
+func commitRounds(m *Match, n int, scores [][]int) {
+	none := []bool{false, false, false, false}
+	for i := 0; i < n; i++ {
+		_ = m.Commit(scores, none)
+	}
+}
+
+func TestKamikaze(t *testing.T) {
+	assert := assert.New(t)
+	m := playingMatch()
+	name := m.Players[0].Name
+
+	commitRounds(m, 2, [][]int{{0, 1}, {0, 0}, {0, 0}, {0, 0}})
+	assert.Equal(0, m.Players[0].Achievements["kamikaze"])
+
+	commitRounds(m, 2, [][]int{{0, 1}, {0, 0}, {0, 0}, {0, 0}})
+	assert.Equal(1, m.Players[0].Achievements["kamikaze"])
+	assert.Equal(1, m.Tournament.getPlayer(name).Achievements["kamikaze"])
+	assert.Nil(m.Players[1].Achievements)
+}
+
+func TestSweeperAndComeback(t *testing.T) {
+	assert := assert.New(t)
+	m := playingMatch()
+
+	// Nobody is tied for the places that matter
+	commitRounds(m, 1, [][]int{{0, 0}, {0, 0}, {1, 0}, {0, 0}})
+
+	// The first player takes a lead of five
+	commitRounds(m, 5, [][]int{{1, 0}, {0, 0}, {0, 0}, {0, 0}})
+	assert.Equal(5, m.deficit(m.Players[1].Name))
+
+	commitRounds(m, 3, [][]int{{0, 0}, {3, 0}, {0, 0}, {0, 0}})
+	assert.Equal(1, m.Players[1].Achievements["sweeper"])
+	assert.Equal(0, m.Players[1].Achievements["comeback"])
+
+	commitRounds(m, 1, [][]int{{0, 0}, {1, 0}, {0, 0}, {0, 0}})
+	assert.Nil(m.End())
+
+	p := m.Players[1]
+	assert.Equal(1, p.Achievements["sweeper"])
+	assert.Equal(1, p.Achievements["comeback"])
+	assert.Equal(1, p.Achievements["flawless"])
+	assert.Equal(0, m.Players[0].Achievements["flawless"])
+}
+
+func TestFlawlessNeedsNoSelfKills(t *testing.T) {
+	assert := assert.New(t)
+	m := playingMatch()
+
+	commitRounds(m, 1, [][]int{{0, 1}, {2, 0}, {1, 0}, {0, 0}})
+	commitRounds(m, 10, [][]int{{1, 0}, {0, 0}, {0, 0}, {0, 0}})
+	assert.Nil(m.End())
+
+	assert.Equal(0, m.Players[0].Achievements["flawless"])
+	assert.Equal(0, m.Players[0].Achievements["comeback"])
+}
+
+func TestAchievementsAreSummedForPlayers(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTournament(8)
+	tm.StartTournament()
+	playMatch(tm.Tryouts[0])
+	playMatch(tm.Tryouts[1])
+
+	winner := tm.Tryouts[0].Players[0].Name
+	before := tm.getPlayer(winner).Achievements["flawless"]
+	assert.Nil(tm.UpdatePlayers())
+	assert.Equal(before, tm.getPlayer(winner).Achievements["flawless"])
+	assert.Equal(1, tm.getPlayer(winner).Achievements["flawless"])
+}
+
+func TestAddPlayerResetsAchievements(t *testing.T) {
+	assert := assert.New(t)
+
+	m := NewMatch(tm, 0, "final")
+	_ = m.AddPlayer(Player{Name: "a", Achievements: map[string]int{"sweeper": 1}})
+	assert.Nil(m.Players[0].Achievements)
+}
+
+func TestGetAchievement(t *testing.T) {
+	assert := assert.New(t)
+
+	a, ok := GetAchievement("kamikaze")
+	assert.True(ok)
+	assert.Equal("Kamikaze", a.Name)
+
+	_, ok = GetAchievement("nope")
+	assert.False(ok)
+}
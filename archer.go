@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/thiderman/drunkenfall/render"
+)
+
+// AltSuffix is added to the color of an archer to get its alternate skin
+const AltSuffix = "-alt"
+
+// Archer is one of the archers in the game
+//
+// Every archer has an alternate skin, so two players can play the same color
+// in a match as long as one of them plays the alternate.
+type Archer struct {
+	Color  string `json:"color"`
+	Name   string `json:"name"`
+	Hex    string `json:"hex"`
+	AltHex string `json:"alt_hex"`
+}
+
+// Skin is one skin of an archer, as it is listed for the players to pick
+type Skin struct {
+	ID       string `json:"id"`
+	Color    string `json:"color"`
+	Name     string `json:"name"`
+	Alt      bool   `json:"alt"`
+	Hex      string `json:"hex"`
+	Image    string `json:"image"`
+	Unpicked string `json:"unpicked"`
+}
+
+// Roster is every archer in the game, in the order of the archer select
+// screen
+var Roster = []Archer{
+	{Color: "green", Name: "Green Archer", Hex: "#4fb84a", AltHex: "#2b6528"},
+	{Color: "blue", Name: "Blue Archer", Hex: "#3f8fe3", AltHex: "#224e7c"},
+	{Color: "pink", Name: "Pink Archer", Hex: "#f28fc5", AltHex: "#854e6c"},
+	{Color: "orange", Name: "Orange Archer", Hex: "#f39c2a", AltHex: "#855517"},
+	{Color: "white", Name: "White Archer", Hex: "#e8e8e8", AltHex: "#7f7f7f"},
+	{Color: "yellow", Name: "Yellow Archer", Hex: "#f0d43a", AltHex: "#84741f"},
+	{Color: "cyan", Name: "Cyan Archer", Hex: "#3fd7d9", AltHex: "#227677"},
+	{Color: "purple", Name: "Purple Archer", Hex: "#a05fd8", AltHex: "#583476"},
+	{Color: "red", Name: "Red Archer", Hex: "#e0413b", AltHex: "#7b2320"},
+}
+
+// Colors is a list of the available player colors
+var Colors = rosterColors()
+
+// rosterColors returns the colors of the archers in the roster
+func rosterColors() []string {
+	colors := make([]string, 0, len(Roster))
+	for _, a := range Roster {
+		colors = append(colors, a.Color)
+	}
+	return colors
+}
+
+// Skin returns the primary or the alternate skin of the archer
+func (a Archer) Skin(alt bool) Skin {
+	s := Skin{
+		ID:    a.Color,
+		Color: a.Color,
+		Name:  a.Name,
+		Alt:   alt,
+		Hex:   a.Hex,
+	}
+	if alt {
+		s.ID += AltSuffix
+		s.Name += " (alternate)"
+		s.Hex = a.AltHex
+	}
+	s.Image = fmt.Sprintf("/static/img/%s-selected.png", s.ID)
+	s.Unpicked = fmt.Sprintf("/static/img/%s-unselected.png", s.ID)
+	return s
+}
+
+// Skins returns every skin in the roster, the primary ones first
+func Skins() []Skin {
+	skins := make([]Skin, 0, 2*len(Roster))
+	for _, alt := range []bool{false, true} {
+		for _, a := range Roster {
+			skins = append(skins, a.Skin(alt))
+		}
+	}
+	return skins
+}
+
+// GetSkin returns the skin with the ID, like `green` or `green-alt`
+func GetSkin(id string) (Skin, bool) {
+	color := strings.TrimSuffix(id, AltSuffix)
+	for _, a := range Roster {
+		if a.Color == color {
+			return a.Skin(color != id), true
+		}
+	}
+	return Skin{}, false
+}
+
+// otherSkin returns the ID of the other skin of the same archer
+func otherSkin(id string) string {
+	s, ok := GetSkin(id)
+	if !ok {
+		return ""
+	}
+	if s.Alt {
+		return s.Color
+	}
+	return s.Color + AltSuffix
+}
+
+// renderArchers returns the skins of the roster the way the renderer wants
+// them
+func renderArchers() []render.Archer {
+	archers := []render.Archer{}
+	for _, s := range Skins() {
+		archers = append(archers, render.Archer{
+			ID:    s.ID,
+			Hex:   s.Hex,
+			Image: strings.TrimPrefix(s.Image, "/static/"),
+		})
+	}
+	return archers
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"os"
+	"testing"
+)
+
+func TestSkins(t *testing.T) {
+	assert := assert.New(t)
+	skins := Skins()
+
+	assert.Equal(2*len(Roster), len(skins))
+	assert.Equal("green", skins[0].ID)
+	assert.False(skins[0].Alt)
+	assert.Equal("green-alt", skins[len(Roster)].ID)
+	assert.True(skins[len(Roster)].Alt)
+	assert.Equal("/static/img/green-alt-selected.png", skins[len(Roster)].Image)
+}
+
+func TestSkinImagesExist(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, s := range Skins() {
+		_, err := os.Stat("." + s.Image)
+		assert.Nil(err, s.Image)
+		_, err = os.Stat("." + s.Unpicked)
+		assert.Nil(err, s.Unpicked)
+	}
+}
+
+func TestGetSkin(t *testing.T) {
+	assert := assert.New(t)
+
+	s, ok := GetSkin("cyan-alt")
+	assert.True(ok)
+	assert.Equal("cyan", s.Color)
+	assert.True(s.Alt)
+	assert.Equal("#227677", s.Hex)
+
+	s, ok = GetSkin("cyan")
+	assert.True(ok)
+	assert.False(s.Alt)
+	assert.Equal("#3fd7d9", s.Hex)
+
+	_, ok = GetSkin("gold")
+	assert.False(ok)
+	_, ok = GetSkin("-alt")
+	assert.False(ok)
+}
+
+func TestOtherSkin(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("pink-alt", otherSkin("pink"))
+	assert.Equal("pink", otherSkin("pink-alt"))
+	assert.Equal("", otherSkin("gold"))
+}
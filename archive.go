@@ -0,0 +1,304 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The statuses a tournament can be listed with
+const (
+	StatusOpen    = "open"
+	StatusRunning = "running"
+	StatusEnded   = "ended"
+)
+
+// DefaultArchiveAge is how long ended tournaments are kept loaded before they
+// are archived
+const DefaultArchiveAge = 7 * 24 * time.Hour
+
+// DefaultPerPage is how many tournaments are listed on a page, unless asked
+// for otherwise, and MaxPerPage is the most that can be asked for
+const (
+	DefaultPerPage = 20
+	MaxPerPage     = 100
+)
+
+// Summary is the little there is to know about a tournament to list it
+type Summary struct {
+	ID       string    `json:"id"`
+	Name     string    `json:"name"`
+	Mode     string    `json:"mode"`
+	Status   string    `json:"status"`
+	Players  int       `json:"players"`
+	Winner   string    `json:"winner,omitempty"`
+	Archived bool      `json:"archived"`
+	Opened   time.Time `json:"opened"`
+	Started  time.Time `json:"started"`
+	Ended    time.Time `json:"ended"`
+	Deleted  time.Time `json:"deleted"`
+}
+
+// ListFilter decides which tournaments are listed
+//
+// Empty fields don't filter anything. From and To are compared to when the
+// tournament was opened, and Search matches parts of the name regardless of
+// case. With Trash, the deleted tournaments are listed instead.
+type ListFilter struct {
+	Trash   bool
+	Status  string
+	From    time.Time
+	To      time.Time
+	Search  string
+	Page    int
+	PerPage int
+}
+
+// TournamentPage is one page of listed tournaments, newest first
+type TournamentPage struct {
+	Tournaments []Summary `json:"tournaments"`
+	Total       int       `json:"total"`
+	Page        int       `json:"page"`
+	PerPage     int       `json:"per_page"`
+}
+
+// Summary returns the summary of the tournament
+func (t *Tournament) Summary() Summary {
+	s := Summary{
+		ID:       t.ID,
+		Name:     t.Name,
+		Mode:     t.Mode,
+		Status:   t.Status(),
+		Players:  len(t.Players),
+		Archived: t.archived,
+		Opened:   t.Opened,
+		Started:  t.Started,
+		Ended:    t.Ended,
+		Deleted:  t.Deleted,
+	}
+	if len(t.Winners) != 0 {
+		s.Winner = t.Winners[0].Name
+		if t.IsTeamMode() && t.Winners[0].Team != "" {
+			s.Winner = t.Winners[0].Team
+		}
+	}
+	return s
+}
+
+// Status returns whether the tournament is open, running or has ended
+func (t *Tournament) Status() string {
+	if !t.Ended.IsZero() {
+		return StatusEnded
+	}
+	if !t.Started.IsZero() {
+		return StatusRunning
+	}
+	return StatusOpen
+}
+
+// Get returns a tournament by its ID
+//
+// Archived tournaments are loaded from the store every time they are asked
+// for, and cannot be changed.
+func (d *Database) Get(id string) (*Tournament, error) {
+	if t, ok := d.lookup(id); ok {
+		return t, nil
+	}
+
+	data, err := d.Store.Archived(id)
+	if err != nil {
+		return nil, err
+	}
+
+	t, err := LoadTournament(data, d)
+	if err != nil {
+		return nil, err
+	}
+	t.archived = true
+	return t, nil
+}
+
+// exists returns boolean whether a tournament has the ID, wherever it is
+func (d *Database) exists(id string) bool {
+	if _, ok := d.lookup(id); ok {
+		return true
+	}
+	if _, ok := d.trashed(id); ok {
+		return true
+	}
+	_, err := d.Store.Archived(id)
+	return err == nil
+}
+
+// Archive moves an ended tournament out of memory and into the archive
+func (d *Database) Archive(t *Tournament) error {
+	if t.Ended.IsZero() {
+		return fmt.Errorf("%s has not ended", t.ID)
+	}
+	if t.archived {
+		return fmt.Errorf("%s is already archived", t.ID)
+	}
+
+	data, err := t.JSON()
+	if err != nil {
+		return err
+	}
+
+	t.archived = true
+	if err := d.Store.Archive(t.ID, data, t.Summary()); err != nil {
+		t.archived = false
+		return err
+	}
+
+	d.unlist(t)
+	log.Printf("Archived tournament %s", t.ID)
+	return nil
+}
+
+// ArchiveEnded archives the tournaments that ended longer ago than age, and
+// returns their IDs
+func (d *Database) ArchiveEnded(age time.Duration) ([]string, error) {
+	ended := []*Tournament{}
+	for _, t := range d.loaded() {
+		if !t.Ended.IsZero() && time.Since(t.Ended) > age {
+			ended = append(ended, t)
+		}
+	}
+
+	ids := []string{}
+	for _, t := range ended {
+		if err := d.Archive(t); err != nil {
+			return ids, err
+		}
+		ids = append(ids, t.ID)
+	}
+	return ids, nil
+}
+
+// List returns a page of the tournaments that pass the filter
+//
+// The loaded tournaments are summarized on the fly, and the archived ones
+// come from the summary index without being loaded.
+func (d *Database) List(f ListFilter) (TournamentPage, error) {
+	summaries := []Summary{}
+	if f.Trash {
+		d.mu.RLock()
+		for _, t := range d.trash {
+			summaries = append(summaries, t.Summary())
+		}
+		d.mu.RUnlock()
+	} else {
+		archived, err := d.Store.Summaries()
+		if err != nil {
+			return TournamentPage{}, err
+		}
+		summaries = append(summaries, archived...)
+		for _, t := range d.loaded() {
+			summaries = append(summaries, t.Summary())
+		}
+	}
+
+	matches := []Summary{}
+	for _, s := range summaries {
+		if f.matches(s) {
+			matches = append(matches, s)
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].Opened.Equal(matches[j].Opened) {
+			return matches[i].ID < matches[j].ID
+		}
+		return matches[i].Opened.After(matches[j].Opened)
+	})
+
+	if f.Page < 1 {
+		f.Page = 1
+	}
+	if f.PerPage < 1 {
+		f.PerPage = DefaultPerPage
+	}
+
+	page := TournamentPage{
+		Tournaments: []Summary{},
+		Total:       len(matches),
+		Page:        f.Page,
+		PerPage:     f.PerPage,
+	}
+
+	start := (f.Page - 1) * f.PerPage
+	if start < len(matches) {
+		end := start + f.PerPage
+		if end > len(matches) {
+			end = len(matches)
+		}
+		page.Tournaments = matches[start:end]
+	}
+	return page, nil
+}
+
+// matches returns boolean whether a tournament passes the filter
+func (f ListFilter) matches(s Summary) bool {
+	if f.Status != "" && s.Status != f.Status {
+		return false
+	}
+	if !f.From.IsZero() && s.Opened.Before(f.From) {
+		return false
+	}
+	if !f.To.IsZero() && !s.Opened.Before(f.To) {
+		return false
+	}
+	if f.Search != "" && !strings.Contains(strings.ToLower(s.Name), strings.ToLower(f.Search)) {
+		return false
+	}
+	return true
+}
+
+// ParseListFilter reads a filter from the query of a list request
+//
+// The dates are days like 2017-03-25, and the to day is included.
+func ParseListFilter(q url.Values) (ListFilter, error) {
+	f := ListFilter{
+		Trash:  q.Get("trash") == "true",
+		Status: q.Get("status"),
+		Search: q.Get("q"),
+	}
+
+	switch f.Status {
+	case "", StatusOpen, StatusRunning, StatusEnded:
+	default:
+		return f, fmt.Errorf("unknown status '%s'", f.Status)
+	}
+
+	var err error
+	if v := q.Get("from"); v != "" {
+		if f.From, err = time.ParseInLocation("2006-01-02", v, time.Local); err != nil {
+			return f, errors.New("from has to be a date like 2006-01-02")
+		}
+	}
+	if v := q.Get("to"); v != "" {
+		if f.To, err = time.ParseInLocation("2006-01-02", v, time.Local); err != nil {
+			return f, errors.New("to has to be a date like 2006-01-02")
+		}
+		f.To = f.To.AddDate(0, 0, 1)
+	}
+
+	if v := q.Get("page"); v != "" {
+		if f.Page, err = strconv.Atoi(v); err != nil || f.Page < 1 {
+			return f, errors.New("page has to be a positive number")
+		}
+	}
+	if v := q.Get("per_page"); v != "" {
+		if f.PerPage, err = strconv.Atoi(v); err != nil || f.PerPage < 1 {
+			return f, errors.New("per_page has to be a positive number")
+		}
+		if f.PerPage > MaxPerPage {
+			f.PerPage = MaxPerPage
+		}
+	}
+	return f, nil
+}
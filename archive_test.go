@@ -0,0 +1,197 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// endedTournament returns a tournament that has ended, as if it was played
+func endedTournament(count int) *Tournament {
+	tm := testTournament(count)
+	tm.StartTournament()
+	tm.Winners = []Player{tm.Players[0]}
+	tm.Ended = time.Now()
+	tm.Persist()
+	return registered(tm)
+}
+
+// listDatabase returns a database with tournaments opened a day apart,
+// newest last
+func listDatabase(count int) *Database {
+	db := MockDatabase()
+	s := testServer(db)
+	start := time.Date(2017, 3, 1, 18, 0, 0, 0, time.Local)
+
+	for i := 0; i < count; i++ {
+		id := strconv.Itoa(i)
+		tm, _ := NewTournament("Drunkenfall "+id, id, s)
+		tm.Opened = start.AddDate(0, 0, i)
+		db.list(tm)
+	}
+	return db
+}
+
+func TestArchiveTournament(t *testing.T) {
+	assert := assert.New(t)
+	tm := endedTournament(8)
+	db := tm.db
+
+	assert.Nil(db.Archive(tm))
+	assert.Equal(0, len(db.Tournaments))
+	assert.Nil(db.tournamentRef[tm.ID])
+
+	ts, _ := db.Store.Tournaments()
+	assert.Equal(0, len(ts))
+
+	summaries, _ := db.Store.Summaries()
+	assert.Equal(1, len(summaries))
+	assert.Equal(tm.Name, summaries[0].Name)
+	assert.Equal(StatusEnded, summaries[0].Status)
+	assert.Equal(8, summaries[0].Players)
+	assert.Equal(tm.Players[0].Name, summaries[0].Winner)
+	assert.True(summaries[0].Archived)
+}
+
+func TestArchiveUnendedTournamentFails(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTournament(8)
+	tm.StartTournament()
+
+	assert.NotNil(tm.db.Archive(tm))
+}
+
+func TestGetArchivedTournament(t *testing.T) {
+	assert := assert.New(t)
+	tm := endedTournament(8)
+	db := tm.db
+	db.Archive(tm)
+
+	at, err := db.Get(tm.ID)
+	assert.Nil(err)
+	assert.Equal(tm.Name, at.Name)
+	assert.Equal(at, at.Tryouts[0].Tournament)
+
+	// Archived tournaments are read only
+	assert.NotNil(at.Persist())
+
+	_, err = db.Get("nope")
+	assert.Equal(ErrNoTournament, err)
+}
+
+func TestGetMatchOfArchivedTournament(t *testing.T) {
+	assert := assert.New(t)
+	tm := endedTournament(8)
+	s := tm.server
+	s.DB.Archive(tm)
+
+	m := requestMatch(s, "/"+tm.ID+"/tryout/0/")
+	assert.NotNil(m)
+	assert.Equal(tm.Tryouts[0].Title(), m.Title())
+	assert.Nil(requestMatch(s, "/nope/tryout/0/"))
+}
+
+func TestArchiveEnded(t *testing.T) {
+	assert := assert.New(t)
+	tm := endedTournament(8)
+	db := tm.db
+
+	ids, err := db.ArchiveEnded(time.Hour)
+	assert.Nil(err)
+	assert.Equal(0, len(ids))
+
+	tm.Ended = time.Now().Add(-2 * time.Hour)
+	ids, err = db.ArchiveEnded(time.Hour)
+	assert.Nil(err)
+	assert.Equal([]string{tm.ID}, ids)
+}
+
+func TestImportDoesNotReuseArchivedID(t *testing.T) {
+	assert := assert.New(t)
+	tm := endedTournament(8)
+	db := tm.db
+	data := exportJSON(tm)
+	db.Archive(tm)
+
+	it, err := db.ImportTournament(data, ImportOptions{})
+	assert.Nil(err)
+	assert.Equal(tm.ID+"-2", it.ID)
+}
+
+func TestListIsNewestFirstAndPaginated(t *testing.T) {
+	assert := assert.New(t)
+	db := listDatabase(5)
+
+	page, err := db.List(ListFilter{PerPage: 2})
+	assert.Nil(err)
+	assert.Equal(5, page.Total)
+	assert.Equal(1, page.Page)
+	assert.Equal(2, len(page.Tournaments))
+	assert.Equal("4", page.Tournaments[0].ID)
+	assert.Equal("3", page.Tournaments[1].ID)
+
+	page, _ = db.List(ListFilter{Page: 3, PerPage: 2})
+	assert.Equal(1, len(page.Tournaments))
+	assert.Equal("0", page.Tournaments[0].ID)
+
+	page, _ = db.List(ListFilter{Page: 4, PerPage: 2})
+	assert.Equal(0, len(page.Tournaments))
+}
+
+func TestListIncludesArchived(t *testing.T) {
+	assert := assert.New(t)
+	db := listDatabase(3)
+	tm := db.tournamentRef["1"]
+	tm.Ended = tm.Opened.Add(time.Hour)
+	assert.Nil(db.Archive(tm))
+
+	page, _ := db.List(ListFilter{})
+	assert.Equal(3, page.Total)
+	assert.True(page.Tournaments[1].Archived)
+
+	page, _ = db.List(ListFilter{Status: StatusEnded})
+	assert.Equal(1, page.Total)
+	assert.Equal("1", page.Tournaments[0].ID)
+}
+
+func TestListFilters(t *testing.T) {
+	assert := assert.New(t)
+	db := listDatabase(5)
+	db.tournamentRef["2"].Name = "Special Edition"
+	db.tournamentRef["3"].Started = time.Now()
+
+	page, _ := db.List(ListFilter{Search: "special"})
+	assert.Equal(1, page.Total)
+	assert.Equal("2", page.Tournaments[0].ID)
+
+	page, _ = db.List(ListFilter{Status: StatusRunning})
+	assert.Equal(1, page.Total)
+	assert.Equal("3", page.Tournaments[0].ID)
+
+	f, err := ParseListFilter(url.Values{"from": {"2017-03-02"}, "to": {"2017-03-03"}})
+	assert.Nil(err)
+	page, _ = db.List(f)
+	assert.Equal(2, page.Total)
+	assert.Equal("2", page.Tournaments[0].ID)
+	assert.Equal("1", page.Tournaments[1].ID)
+}
+
+func TestParseListFilter(t *testing.T) {
+	assert := assert.New(t)
+
+	f, err := ParseListFilter(url.Values{"status": {"open"}, "q": {"drunk"}, "page": {"2"}, "per_page": {"500"}})
+	assert.Nil(err)
+	assert.Equal(StatusOpen, f.Status)
+	assert.Equal("drunk", f.Search)
+	assert.Equal(2, f.Page)
+	assert.Equal(MaxPerPage, f.PerPage)
+
+	_, err = ParseListFilter(url.Values{"status": {"sleeping"}})
+	assert.NotNil(err)
+	_, err = ParseListFilter(url.Values{"from": {"yesterday"}})
+	assert.NotNil(err)
+	_, err = ParseListFilter(url.Values{"page": {"0"}})
+	assert.NotNil(err)
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Backup defaults for the server
+const (
+	DefaultBackupDir      = "backups"
+	DefaultBackupInterval = 10 * time.Minute
+	DefaultBackupKeep     = 144
+)
+
+const backupTimeFormat = "20060102-150405.000"
+
+// Backups takes copies of the database while the server is running
+//
+// The copies are made by the store, so they are consistent even while
+// matches are being played. Only the newest Keep backups are kept,
+// and backups older than MaxAge are removed as well, if it is set.
+type Backups struct {
+	Dir      string
+	Interval time.Duration
+	Keep     int
+	MaxAge   time.Duration
+
+	db   *Database
+	lock sync.Mutex
+}
+
+// BackupInfo describes a backup file
+type BackupInfo struct {
+	Name    string    `json:"name"`
+	Path    string    `json:"path"`
+	Size    int64     `json:"size"`
+	Created time.Time `json:"created"`
+}
+
+// NewBackups returns backups of the database with the default settings
+func NewBackups(db *Database, dir string) *Backups {
+	return &Backups{
+		Dir:      dir,
+		Interval: DefaultBackupInterval,
+		Keep:     DefaultBackupKeep,
+		db:       db,
+	}
+}
+
+// Run takes a backup every interval until done is closed
+func (b *Backups) Run(done <-chan struct{}) {
+	ticker := time.NewTicker(b.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if _, err := b.Take(); err != nil {
+				log.Printf("Backup failed: %s", err)
+			}
+		case <-done:
+			return
+		}
+	}
+}
+
+// Take makes a backup right away and then applies the retention policy
+func (b *Backups) Take() (BackupInfo, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if err := os.MkdirAll(b.Dir, 0700); err != nil {
+		return BackupInfo{}, err
+	}
+
+	now := time.Now().UTC()
+	fn := filepath.Join(b.Dir, "drunkenfall-"+now.Format(backupTimeFormat)+".db")
+	store, ok := b.db.Store.(Backupper)
+	if !ok {
+		return BackupInfo{}, errors.New("the database cannot be backed up")
+	}
+	if err := store.Backup(fn); err != nil {
+		return BackupInfo{}, err
+	}
+
+	if _, err := b.prune(now); err != nil {
+		log.Printf("Pruning backups failed: %s", err)
+	}
+
+	st, err := os.Stat(fn)
+	if err != nil {
+		return BackupInfo{}, err
+	}
+	log.Printf("Backed up the database to %s", fn)
+	return BackupInfo{Name: filepath.Base(fn), Path: fn, Size: st.Size(), Created: now}, nil
+}
+
+// List returns the backups, newest first
+func (b *Backups) List() ([]BackupInfo, error) {
+	files, err := ioutil.ReadDir(b.Dir)
+	if os.IsNotExist(err) {
+		return []BackupInfo{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	out := []BackupInfo{}
+	for _, f := range files {
+		name := f.Name()
+		if f.IsDir() || !strings.HasPrefix(name, "drunkenfall-") || !strings.HasSuffix(name, ".db") {
+			continue
+		}
+
+		ts := strings.TrimSuffix(strings.TrimPrefix(name, "drunkenfall-"), ".db")
+		created, err := time.Parse(backupTimeFormat, ts)
+		if err != nil {
+			continue
+		}
+
+		out = append(out, BackupInfo{
+			Name:    name,
+			Path:    filepath.Join(b.Dir, name),
+			Size:    f.Size(),
+			Created: created,
+		})
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Created.After(out[j].Created)
+	})
+	return out, nil
+}
+
+// prune removes the backups that fall outside of the retention policy
+//
+// The newest backup is always kept, however old it is.
+func (b *Backups) prune(now time.Time) ([]string, error) {
+	bs, err := b.List()
+	if err != nil {
+		return nil, err
+	}
+
+	removed := []string{}
+	for i, bi := range bs {
+		if i == 0 {
+			continue
+		}
+
+		tooMany := b.Keep > 0 && i >= b.Keep
+		tooOld := b.MaxAge > 0 && now.Sub(bi.Created) > b.MaxAge
+		if !tooMany && !tooOld {
+			continue
+		}
+
+		if err := os.Remove(bi.Path); err != nil {
+			return removed, err
+		}
+		removed = append(removed, bi.Name)
+	}
+	return removed, nil
+}
+
+// RestoreBackup replaces a database file with a backup
+//
+// The backup is checked to be a database that the tournaments can be loaded
+// from before anything is replaced, and the current database is kept next to
+// it. This has to be done while the server is stopped.
+func RestoreBackup(backup, target string) (string, error) {
+	// Opening a store creates it if it is missing
+	if _, err := os.Stat(backup); err != nil {
+		return "", err
+	}
+
+	store, err := OpenStore(backup)
+	if err != nil {
+		return "", fmt.Errorf("cannot open backup: %s", err)
+	}
+
+	d := NewStoreDatabase(store)
+	err = d.LoadTournaments()
+	store.Close()
+	if err != nil {
+		return "", fmt.Errorf("backup is broken: %s", err)
+	}
+	if len(d.Tournaments) == 0 {
+		return "", errors.New("backup has no tournaments")
+	}
+
+	target = strings.TrimPrefix(target, "sqlite:")
+	kept := ""
+	if _, err := os.Stat(target); err == nil {
+		kept = target + ".before-restore-" + time.Now().UTC().Format(backupTimeFormat)
+		if err := os.Rename(target, kept); err != nil {
+			return "", err
+		}
+	}
+
+	if err := copyFile(backup, target); err != nil {
+		return kept, err
+	}
+	return kept, nil
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+func testBackups(dir string) (*Backups, *Tournament) {
+	os.RemoveAll("test/" + dir)
+	db := fileDatabase("backup.db")
+	tm := testTournament(8)
+	tm.db = db
+	tm.db.Persist(tm)
+	return NewBackups(tm.db, "test/"+dir), tm
+}
+
+func takeBackups(b *Backups, count int) {
+	for i := 0; i < count; i++ {
+		b.Take()
+		time.Sleep(2 * time.Millisecond)
+	}
+}
+
+func TestTakeBackup(t *testing.T) {
+	assert := assert.New(t)
+	b, tm := testBackups("backups")
+
+	bi, err := b.Take()
+	assert.Nil(err)
+	assert.True(bi.Size > 0)
+
+	d, err := NewDatabase(bi.Path)
+	assert.Nil(err)
+	defer d.Close()
+
+	assert.Nil(d.LoadTournaments())
+	assert.Equal(tm.Name, d.tournamentRef[tm.ID].Name)
+}
+
+func TestListBackupsNewestFirst(t *testing.T) {
+	assert := assert.New(t)
+	b, _ := testBackups("backups")
+	takeBackups(b, 3)
+
+	// Other files in the directory are left alone
+	ioutil.WriteFile("test/backups/notes.txt", []byte("hi"), 0600)
+
+	bs, err := b.List()
+	assert.Nil(err)
+	assert.Equal(3, len(bs))
+	assert.True(bs[0].Created.After(bs[1].Created))
+	assert.True(bs[1].Created.After(bs[2].Created))
+}
+
+func TestBackupsAreKeptByCount(t *testing.T) {
+	assert := assert.New(t)
+	b, _ := testBackups("backups")
+	b.Keep = 2
+	takeBackups(b, 4)
+
+	bs, _ := b.List()
+	assert.Equal(2, len(bs))
+}
+
+func TestBackupsAreKeptByAge(t *testing.T) {
+	assert := assert.New(t)
+	b, _ := testBackups("backups")
+	takeBackups(b, 3)
+
+	b.MaxAge = time.Hour
+	removed, err := b.prune(time.Now().Add(2 * time.Hour))
+	assert.Nil(err)
+	assert.Equal(2, len(removed))
+
+	// The newest one stays no matter what
+	bs, _ := b.List()
+	assert.Equal(1, len(bs))
+}
+
+func TestRestoreBackup(t *testing.T) {
+	assert := assert.New(t)
+	b, tm := testBackups("backups")
+	bi, err := b.Take()
+	assert.Nil(err)
+
+	target := "test/restored.db"
+	os.Remove(target)
+	ioutil.WriteFile(target, []byte("broken"), 0600)
+
+	kept, err := RestoreBackup(bi.Path, target)
+	assert.Nil(err)
+	assert.NotEqual("", kept)
+	defer os.Remove(kept)
+
+	data, _ := ioutil.ReadFile(kept)
+	assert.Equal("broken", string(data))
+
+	db, err := NewDatabase(target)
+	assert.Nil(err)
+	defer db.Close()
+	assert.Nil(db.LoadTournaments())
+	assert.NotNil(db.tournamentRef[tm.ID])
+}
+
+func TestRestoreBrokenBackupFails(t *testing.T) {
+	assert := assert.New(t)
+	os.MkdirAll("test/", 0700)
+	ioutil.WriteFile("test/broken.db", []byte("broken"), 0600)
+
+	_, err := RestoreBackup("test/broken.db", "test/restored.db")
+	assert.NotNil(err)
+}
+
+func TestTakeSQLiteBackup(t *testing.T) {
+	assert := assert.New(t)
+	os.RemoveAll("test/backups")
+	db := fileDatabase("sqlite:backup.sqlite")
+	defer db.Close()
+	tm := testTournament(8)
+	tm.db = db
+	tm.db.Persist(tm)
+
+	bi, err := NewBackups(db, "test/backups").Take()
+	assert.Nil(err)
+
+	d, err := NewDatabase(bi.Path)
+	assert.Nil(err)
+	defer d.Close()
+
+	_, ok := d.Store.(*SQLiteStore)
+	assert.True(ok)
+	assert.Nil(d.LoadTournaments())
+	assert.Equal(tm.Name, d.tournamentRef[tm.ID].Name)
+}
+
+func TestBackupUnsupportedStoreFails(t *testing.T) {
+	assert := assert.New(t)
+	_, err := NewBackups(MockDatabase(), "test/backups").Take()
+	assert.NotNil(err)
+}
@@ -0,0 +1,331 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"github.com/boltdb/bolt"
+	"time"
+)
+
+var (
+	// TournamentKey is the byte string identifying the tournament buckets
+	TournamentKey = []byte("tournaments")
+
+	// ArchiveKey is the byte string identifying the archived tournaments
+	ArchiveKey = []byte("archive")
+
+	// SummaryKey is the byte string identifying the summaries of the
+	// archived tournaments
+	SummaryKey = []byte("summaries")
+
+	// EventKey is the byte string identifying the event buckets
+	EventKey = []byte("events")
+
+	// WebhookKey is the byte string identifying the webhooks of the
+	// tournaments
+	WebhookKey = []byte("webhooks")
+
+	// DeliveryKey is the byte string identifying the webhook delivery log
+	// buckets
+	DeliveryKey = []byte("deliveries")
+)
+
+// BoltStore keeps the tournaments in a bolt database
+type BoltStore struct {
+	DB *bolt.DB
+}
+
+// NewBoltStore opens a bolt database, creating it if needed
+func NewBoltStore(fn string) (*BoltStore, error) {
+	db, err := bolt.Open(fn, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, err
+	}
+	return &BoltStore{DB: db}, nil
+}
+
+// Tournaments returns the records of all the active tournaments, by ID
+func (s *BoltStore) Tournaments() (map[string][]byte, error) {
+	return s.records(TournamentKey)
+}
+
+// records returns everything in one of the tournament buckets
+func (s *BoltStore) records(key []byte) (map[string][]byte, error) {
+	out := make(map[string][]byte)
+	err := s.DB.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(key)
+		if b == nil {
+			return nil
+		}
+
+		return b.ForEach(func(k []byte, v []byte) error {
+			// The values are only valid during the transaction
+			out[string(k)] = append([]byte{}, v...)
+			return nil
+		})
+	})
+	return out, err
+}
+
+// Save stores the record of an active tournament
+func (s *BoltStore) Save(id string, data []byte) error {
+	return s.DB.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists(TournamentKey)
+		if err != nil {
+			return err
+		}
+		if err := b.Put([]byte(id), data); err != nil {
+			return err
+		}
+		return deleteFrom(tx, []byte(id), ArchiveKey, SummaryKey)
+	})
+}
+
+// Archive moves the record of a tournament into the archive
+func (s *BoltStore) Archive(id string, data []byte, summary Summary) error {
+	sd, err := json.Marshal(summary)
+	if err != nil {
+		return err
+	}
+
+	return s.DB.Update(func(tx *bolt.Tx) error {
+		archive, err := tx.CreateBucketIfNotExists(ArchiveKey)
+		if err != nil {
+			return err
+		}
+		summaries, err := tx.CreateBucketIfNotExists(SummaryKey)
+		if err != nil {
+			return err
+		}
+
+		if err := archive.Put([]byte(id), data); err != nil {
+			return err
+		}
+		if err := summaries.Put([]byte(id), sd); err != nil {
+			return err
+		}
+		return deleteFrom(tx, []byte(id), TournamentKey)
+	})
+}
+
+// Archived returns the record of an archived tournament
+func (s *BoltStore) Archived(id string) ([]byte, error) {
+	var out []byte
+	err := s.DB.View(func(tx *bolt.Tx) error {
+		if b := tx.Bucket(ArchiveKey); b != nil {
+			if v := b.Get([]byte(id)); v != nil {
+				out = append([]byte{}, v...)
+			}
+		}
+		return nil
+	})
+	if err == nil && out == nil {
+		err = ErrNoTournament
+	}
+	return out, err
+}
+
+// Summaries returns the summaries of the archived tournaments
+//
+// Only the summary bucket is read, which is what keeps listing the archive
+// cheap.
+func (s *BoltStore) Summaries() ([]Summary, error) {
+	out := []Summary{}
+	err := s.DB.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(SummaryKey)
+		if b == nil {
+			return nil
+		}
+
+		return b.ForEach(func(k []byte, v []byte) error {
+			var summary Summary
+			if err := json.Unmarshal(v, &summary); err != nil {
+				return err
+			}
+			out = append(out, summary)
+			return nil
+		})
+	})
+	return out, err
+}
+
+// Delete removes a tournament, its events and its webhooks
+func (s *BoltStore) Delete(id string) error {
+	return s.DB.Update(func(tx *bolt.Tx) error {
+		err := deleteFrom(tx, []byte(id), TournamentKey, ArchiveKey, SummaryKey, WebhookKey)
+		if err != nil {
+			return err
+		}
+		for _, key := range [][]byte{EventKey, DeliveryKey} {
+			if b := tx.Bucket(key); b != nil && b.Bucket([]byte(id)) != nil {
+				if err := b.DeleteBucket([]byte(id)); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// deleteFrom removes a key from the buckets that exist
+func deleteFrom(tx *bolt.Tx, key []byte, buckets ...[]byte) error {
+	for _, name := range buckets {
+		if b := tx.Bucket(name); b != nil {
+			if err := b.Delete(key); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Players returns the stats of every player over all the tournaments
+func (s *BoltStore) Players() ([]Player, error) {
+	ts, err := s.records(TournamentKey)
+	if err != nil {
+		return nil, err
+	}
+	archived, err := s.records(ArchiveKey)
+	if err != nil {
+		return nil, err
+	}
+
+	for id, data := range archived {
+		ts[id] = data
+	}
+	return careerStats(ts)
+}
+
+// AddEvent records something that happened in a tournament
+//
+// Every tournament has a bucket of its own, keyed by a sequence so that the
+// events are kept in the order they happened.
+func (s *BoltStore) AddEvent(e Event) error {
+	return s.appendTo(EventKey, e.Tournament, e)
+}
+
+// Events returns the events of a tournament, oldest first
+func (s *BoltStore) Events(id string) ([]Event, error) {
+	out := []Event{}
+	err := s.each(EventKey, id, func(v []byte) error {
+		var e Event
+		if err := json.Unmarshal(v, &e); err != nil {
+			return err
+		}
+		out = append(out, e)
+		return nil
+	})
+	return out, err
+}
+
+// Webhooks returns the webhooks of a tournament
+func (s *BoltStore) Webhooks(id string) ([]Webhook, error) {
+	out := []Webhook{}
+	err := s.DB.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(WebhookKey)
+		if b == nil {
+			return nil
+		}
+		if data := b.Get([]byte(id)); data != nil {
+			return json.Unmarshal(data, &out)
+		}
+		return nil
+	})
+	return out, err
+}
+
+// SaveWebhooks replaces the webhooks of a tournament
+func (s *BoltStore) SaveWebhooks(id string, hooks []Webhook) error {
+	data, err := json.Marshal(hooks)
+	if err != nil {
+		return err
+	}
+
+	return s.DB.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists(WebhookKey)
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte(id), data)
+	})
+}
+
+// AddDelivery logs an attempt to deliver an event to a webhook
+//
+// The log is kept like the events, in a bucket per tournament.
+func (s *BoltStore) AddDelivery(d Delivery) error {
+	return s.appendTo(DeliveryKey, d.Tournament, d)
+}
+
+// Deliveries returns the delivery log of a tournament, oldest first
+func (s *BoltStore) Deliveries(id string) ([]Delivery, error) {
+	out := []Delivery{}
+	err := s.each(DeliveryKey, id, func(v []byte) error {
+		var d Delivery
+		if err := json.Unmarshal(v, &d); err != nil {
+			return err
+		}
+		out = append(out, d)
+		return nil
+	})
+	return out, err
+}
+
+// appendTo adds a record to the bucket of a tournament, under a sequence
+// key so that the records stay in order
+func (s *BoltStore) appendTo(key []byte, id string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	return s.DB.Update(func(tx *bolt.Tx) error {
+		parent, err := tx.CreateBucketIfNotExists(key)
+		if err != nil {
+			return err
+		}
+		b, err := parent.CreateBucketIfNotExists([]byte(id))
+		if err != nil {
+			return err
+		}
+
+		seq, err := b.NextSequence()
+		if err != nil {
+			return err
+		}
+		k := make([]byte, 8)
+		binary.BigEndian.PutUint64(k, seq)
+		return b.Put(k, data)
+	})
+}
+
+// each calls fn with the records in the bucket of a tournament, in order
+func (s *BoltStore) each(key []byte, id string, fn func([]byte) error) error {
+	return s.DB.View(func(tx *bolt.Tx) error {
+		parent := tx.Bucket(key)
+		if parent == nil {
+			return nil
+		}
+		b := parent.Bucket([]byte(id))
+		if b == nil {
+			return nil
+		}
+
+		return b.ForEach(func(k []byte, v []byte) error {
+			return fn(v)
+		})
+	})
+}
+
+// Backup copies the database in a read transaction, so that the copy is
+// consistent even while matches are being played
+func (s *BoltStore) Backup(fn string) error {
+	return s.DB.View(func(tx *bolt.Tx) error {
+		return tx.CopyFile(fn, 0600)
+	})
+}
+
+// Close closes the database
+func (s *BoltStore) Close() error {
+	return s.DB.Close()
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"fmt"
+)
+
+// Bracket is the graph of how players make their way through the tournament
+//
+// Every match is a node, including the ones nobody has been placed into yet.
+// The edges say which finishing positions of a match feed into which match,
+// so that a client can draw the tree without knowing the rules.
+type Bracket struct {
+	Nodes []BracketNode `json:"nodes"`
+	Edges []BracketEdge `json:"edges"`
+}
+
+// BracketNode is a match in the bracket
+type BracketNode struct {
+	ID      string        `json:"id"`
+	Kind    string        `json:"kind"`
+	Index   int           `json:"index"`
+	Title   string        `json:"title"`
+	State   string        `json:"state"`
+	Station int           `json:"station"`
+	Slots   []BracketSlot `json:"slots"`
+}
+
+// BracketSlot is a seat in a match. Empty seats have no name.
+type BracketSlot struct {
+	Name       string `json:"name"`
+	Color      string `json:"color"`
+	Team       string `json:"team,omitempty"`
+	Kills      int    `json:"kills"`
+	Placement  int    `json:"placement"`
+	Backfilled bool   `json:"backfilled"`
+}
+
+// BracketEdge says that the player finishing in Position (1-indexed) of the
+// From match advances into the To match
+type BracketEdge struct {
+	From     string `json:"from"`
+	Position int    `json:"position"`
+	To       string `json:"to"`
+}
+
+// Bracket builds the bracket graph of the tournament
+func (t *Tournament) Bracket() Bracket {
+	b := Bracket{
+		Nodes: []BracketNode{},
+		Edges: []BracketEdge{},
+	}
+
+	// In team mode, the positions are the ones in the team standings
+	positions := 4
+	if t.IsTeamMode() {
+		positions = 4 / TeamSize
+	}
+
+	for _, m := range t.Matches() {
+		b.Nodes = append(b.Nodes, m.bracketNode())
+
+		for pos := 0; pos < positions; pos++ {
+			next := t.advanceTo(m, pos)
+			if next == nil {
+				continue
+			}
+
+			b.Edges = append(b.Edges, BracketEdge{
+				From:     m.bracketID(),
+				Position: pos + 1,
+				To:       next.bracketID(),
+			})
+		}
+	}
+
+	return b
+}
+
+// advanceTo returns the match that a finishing position (0-indexed) of a
+// match advances into, or nil if that position does not advance
+//
+// The winners of the tryouts are spread over the semis so that they do not
+// face off immediately. In team mode, the position is the one in the team
+// standings.
+func (t *Tournament) advanceTo(m *Match, pos int) *Match {
+	if pos >= m.placements() {
+		return nil
+	}
+
+	switch m.Kind {
+	case "tryout":
+		return t.Semis[(pos+m.Index)%2]
+	case "semi":
+		return t.Final
+	}
+	return nil
+}
+
+// bracketID returns the identifier of the match in the bracket graph
+func (m *Match) bracketID() string {
+	return fmt.Sprintf("%s-%d", m.Kind, m.Index)
+}
+
+// bracketNode returns the match as a node in the bracket graph
+func (m *Match) bracketNode() BracketNode {
+	n := BracketNode{
+		ID:      m.bracketID(),
+		Kind:    m.Kind,
+		Index:   m.Index,
+		Title:   m.Title(),
+		State:   m.getState(),
+		Station: m.Station,
+		Slots:   make([]BracketSlot, 0, len(m.Players)),
+	}
+
+	placement := make(map[string]int)
+	if m.IsEnded() {
+		for i, p := range m.Standings() {
+			placement[p.Name] = i + 1
+		}
+	}
+
+	for _, p := range m.Players {
+		if p.IsPrefill() {
+			n.Slots = append(n.Slots, BracketSlot{})
+			continue
+		}
+
+		backfilled := false
+		for _, name := range m.Backfilled {
+			if name == p.Name {
+				backfilled = true
+				break
+			}
+		}
+
+		n.Slots = append(n.Slots, BracketSlot{
+			Name:       p.Name,
+			Color:      p.PreferredColor,
+			Team:       p.Team,
+			Kills:      p.Kills,
+			Placement:  placement[p.Name],
+			Backfilled: backfilled,
+		})
+	}
+	return n
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestBracketHasAllMatches(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTournament(16)
+
+	b := tm.Bracket()
+	assert.Equal(7, len(b.Nodes))
+	assert.Equal("tryout-0", b.Nodes[0].ID)
+	assert.Equal("semi-1", b.Nodes[5].ID)
+	assert.Equal("final-0", b.Nodes[6].ID)
+
+	// The future matches are there with empty slots
+	assert.Equal(4, len(b.Nodes[6].Slots))
+	assert.Equal("", b.Nodes[6].Slots[0].Name)
+}
+
+func TestBracketEdgesWithFourTryouts(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTournament(16)
+
+	b := tm.Bracket()
+	assert.Equal(4*2+2*2, len(b.Edges))
+	assert.Contains(b.Edges, BracketEdge{From: "tryout-0", Position: 1, To: "semi-0"})
+	assert.Contains(b.Edges, BracketEdge{From: "tryout-0", Position: 2, To: "semi-1"})
+	assert.Contains(b.Edges, BracketEdge{From: "tryout-1", Position: 1, To: "semi-1"})
+	assert.Contains(b.Edges, BracketEdge{From: "tryout-1", Position: 2, To: "semi-0"})
+	assert.Contains(b.Edges, BracketEdge{From: "semi-1", Position: 2, To: "final-0"})
+}
+
+func TestBracketEdgesWithEightTryouts(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTournament(24)
+
+	b := tm.Bracket()
+	assert.Equal(8+2*2, len(b.Edges))
+	assert.Contains(b.Edges, BracketEdge{From: "tryout-5", Position: 1, To: "semi-1"})
+	assert.NotContains(b.Edges, BracketEdge{From: "tryout-5", Position: 2, To: "semi-0"})
+}
+
+func TestBracketEdgesInTeamMode(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTeamTournament(8)
+
+	b := tm.Bracket()
+	assert.Equal(4+2, len(b.Edges))
+	assert.Contains(b.Edges, BracketEdge{From: "tryout-2", Position: 1, To: "semi-0"})
+	assert.Contains(b.Edges, BracketEdge{From: "semi-0", Position: 1, To: "final-0"})
+}
+
+func TestBracketEdgesMatchWherePlayersGo(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTournament(16)
+	tm.StartTournament()
+	endTryouts(tm)
+
+	b := tm.Bracket()
+	nodes := make(map[string]BracketNode)
+	for _, n := range b.Nodes {
+		nodes[n.ID] = n
+	}
+
+	for _, e := range b.Edges {
+		from := nodes[e.From]
+		if from.Kind != "tryout" {
+			continue
+		}
+
+		var name string
+		for _, s := range from.Slots {
+			if s.Placement == e.Position {
+				name = s.Name
+			}
+		}
+
+		found := false
+		for _, s := range nodes[e.To].Slots {
+			if s.Name == name {
+				found = true
+			}
+		}
+		assert.True(found, "%s #%d is not in %s", e.From, e.Position, e.To)
+	}
+}
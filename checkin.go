@@ -0,0 +1,168 @@
+package main
+
+import (
+	"fmt"
+)
+
+// CheckIn marks a player as present for a match
+//
+// Players check in from their phones, or the judge does it for them.
+func (m *Match) CheckIn(name string) error {
+	if m.IsStarted() || m.IsDone() {
+		return fmt.Errorf("cannot check in to %s match", m.getState())
+	}
+	if name == "" || m.getPlayer(name) == nil {
+		return fmt.Errorf("%s is not in %s", name, m.String())
+	}
+
+	if !m.IsCheckedIn(name) {
+		m.CheckedIn = append(m.CheckedIn, name)
+	}
+
+	if m.Tournament != nil {
+		return m.Tournament.Persist()
+	}
+	return nil
+}
+
+// IsCheckedIn returns boolean whether a player has checked in to the match
+func (m *Match) IsCheckedIn(name string) bool {
+	for _, n := range m.CheckedIn {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// NoShow removes a player that did not show up from the match
+//
+// The player forfeits the match and their slot is backfilled with the best
+// runnerup when the match starts. In team mode, the whole team forfeits.
+func (m *Match) NoShow(name string) error {
+	if err := m.noShow(name); err != nil {
+		return err
+	}
+
+	m.updateReadiness()
+	if m.Tournament != nil {
+		return m.Tournament.Persist()
+	}
+	return nil
+}
+
+// noShow is NoShow() without persisting
+func (m *Match) noShow(name string) error {
+	if m.IsStarted() || m.IsDone() {
+		return fmt.Errorf("cannot remove players from %s match", m.getState())
+	}
+
+	p := m.getPlayer(name)
+	if name == "" || p == nil {
+		return fmt.Errorf("%s is not in %s", name, m.String())
+	}
+
+	names := []string{name}
+	if p.Team != "" && m.Tournament != nil {
+		if team := m.Tournament.getTeam(p.Team); team != nil {
+			names = team.Members
+		}
+	}
+
+	for _, n := range names {
+		m.removePlayer(n)
+		m.Forfeits = append(m.Forfeits, n)
+		if m.Tournament != nil {
+			m.Tournament.removeRunnerups([]Player{{Name: n}})
+		}
+	}
+	return nil
+}
+
+// HasForfeited returns boolean whether the player forfeited the match
+func (m *Match) HasForfeited(name string) bool {
+	for _, n := range m.Forfeits {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// replaceNoShows removes all players that have not checked in
+//
+// This is only done when the tournament requires check-ins, as part of
+// starting the match. Nothing is persisted, since the match might not start.
+func (m *Match) replaceNoShows() error {
+	names := []string{}
+	for _, p := range m.Players {
+		if !p.IsPrefill() && !m.IsCheckedIn(p.Name) {
+			names = append(names, p.Name)
+		}
+	}
+
+	for _, n := range names {
+		// Team members are removed together, so the second one might
+		// already be gone.
+		if m.getPlayer(n) == nil {
+			continue
+		}
+		if err := m.noShow(n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// snapshot returns a function that puts the players of the match, and the
+// runnerups of the tournament, back the way they are now
+func (m *Match) snapshot() func() {
+	players := append([]Player{}, m.Players...)
+	forfeits := append([]string{}, m.Forfeits...)
+	checkedIn := append([]string{}, m.CheckedIn...)
+	backfilled := append([]string{}, m.Backfilled...)
+	state := m.State
+
+	var runnerups []string
+	if m.Tournament != nil {
+		runnerups = append([]string{}, m.Tournament.Runnerups...)
+	}
+
+	return func() {
+		m.Players = players
+		m.Forfeits = forfeits
+		m.CheckedIn = checkedIn
+		m.Backfilled = backfilled
+		m.State = state
+		if m.Tournament != nil {
+			m.Tournament.Runnerups = runnerups
+		}
+	}
+}
+
+// removePlayer replaces a player in the match with a prefill player
+func (m *Match) removePlayer(name string) {
+	for i, p := range m.Players {
+		if p.Name == name {
+			m.Players[i] = Player{Match: m}
+		}
+	}
+
+	for i, n := range m.CheckedIn {
+		if n == name {
+			m.CheckedIn = append(m.CheckedIn[:i], m.CheckedIn[i+1:]...)
+			break
+		}
+	}
+}
+
+// availableRunnerups returns how many runnerups could backfill a match
+func (t *Tournament) availableRunnerups(m *Match) int {
+	count := 0
+	for _, r := range t.Runnerups {
+		if m.getPlayer(r) == nil && !m.HasForfeited(r) {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestCheckIn(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTournament(16)
+	tm.StartTournament()
+	m := tm.Tryouts[0]
+	name := m.Players[0].Name
+
+	assert.Nil(m.CheckIn(name))
+	assert.True(m.IsCheckedIn(name))
+	assert.False(m.IsCheckedIn(m.Players[1].Name))
+}
+
+func TestCheckInPlayerNotInMatchFails(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTournament(16)
+	tm.StartTournament()
+
+	assert.NotNil(tm.Tryouts[0].CheckIn(tm.Tryouts[1].Players[0].Name))
+}
+
+func TestCheckInStartedMatchFails(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTournament(16)
+	tm.StartTournament()
+	m := tm.Tryouts[0]
+	m.Start()
+
+	assert.NotNil(m.CheckIn(m.Players[0].Name))
+}
+
+func TestNoShowIsReplacedByRunnerup(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTournament(16)
+	tm.StartTournament()
+	playMatch(tm.Tryouts[0])
+
+	m := tm.Tryouts[1]
+	gone := m.Players[2].Name
+	assert.Nil(m.NoShow(gone))
+	assert.Equal(3, m.ActualPlayers())
+
+	// There are runnerups from the first tryout, so it can start right away
+	assert.Equal(MatchReady, m.State)
+	best := tm.Runnerups[0]
+
+	assert.Nil(m.Start())
+	assert.Equal(4, m.ActualPlayers())
+	assert.Nil(m.getPlayer(gone))
+	assert.NotNil(m.getPlayer(best))
+}
+
+func TestNoShowIsRecordedAsForfeit(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTournament(16)
+	tm.StartTournament()
+	playMatch(tm.Tryouts[0])
+
+	m := tm.Tryouts[1]
+	gone := m.Players[0].Name
+	assert.Nil(m.NoShow(gone))
+	assert.True(m.HasForfeited(gone))
+
+	tm.UpdatePlayers()
+	assert.Equal(1, tm.getPlayer(gone).Forfeits)
+}
+
+func TestNoShowWithoutRunnerupsWaits(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTournament(16)
+	tm.StartTournament()
+
+	m := tm.Tryouts[0]
+	assert.Nil(m.NoShow(m.Players[0].Name))
+	assert.Equal(MatchScheduled, m.State)
+	assert.NotNil(m.Start())
+}
+
+func TestForfeitedRunnerupIsNotBackfilled(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTournament(16)
+	tm.StartTournament()
+	playMatch(tm.Tryouts[0])
+	playMatch(tm.Tryouts[1])
+
+	// Backfill the best runnerup into the match, and have them not show up
+	m := tm.Tryouts[2]
+	m.NoShow(m.Players[0].Name)
+	best := tm.Runnerups[0]
+	assert.Nil(tm.PopulateRunnerups(m))
+	assert.NotNil(m.getPlayer(best))
+
+	assert.Nil(m.NoShow(best))
+	assert.NotContains(tm.Runnerups, best)
+
+	assert.Nil(m.Start())
+	assert.Equal(4, m.ActualPlayers())
+	assert.Nil(m.getPlayer(best))
+}
+
+func TestRequireCheckInReplacesPlayersNotCheckedIn(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTournament(16)
+	tm.RequireCheckIn = true
+	tm.StartTournament()
+
+	m := tm.Tryouts[0]
+	for _, p := range m.Players {
+		m.CheckIn(p.Name)
+	}
+	assert.Nil(m.Start())
+	playMatch(m)
+
+	m2 := tm.Tryouts[1]
+	gone := m2.Players[3].Name
+	for _, p := range m2.Players[:3] {
+		m2.CheckIn(p.Name)
+	}
+
+	assert.Nil(m2.Start())
+	assert.Equal(4, m2.ActualPlayers())
+	assert.Nil(m2.getPlayer(gone))
+	assert.Equal([]string{gone}, m2.Forfeits)
+	assert.Equal(4, len(m2.CheckedIn))
+}
+
+func TestFailedStartKeepsPlayersNotCheckedIn(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTournament(16)
+	tm.RequireCheckIn = true
+	tm.StartTournament()
+
+	// Nobody has checked in, and there are no runnerups to replace them
+	m := tm.Tryouts[0]
+	names := []string{}
+	for _, p := range m.Players {
+		names = append(names, p.Name)
+	}
+
+	assert.NotNil(m.Start())
+	assert.Equal(4, m.ActualPlayers())
+	assert.Equal(0, len(m.Forfeits))
+	for i, p := range m.Players {
+		assert.Equal(names[i], p.Name)
+	}
+	assert.False(m.IsStarted())
+}
+
+func TestNoShowInTeamModeForfeitsTeam(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTeamTournament(8)
+	tm.StartTournament()
+
+	m := tm.Tryouts[0]
+	team := m.Players[0].Team
+	assert.Nil(m.NoShow(m.Players[0].Name))
+	assert.Equal(2, m.ActualPlayers())
+	assert.False(m.HasTeam(team))
+	assert.Equal(2, len(m.Forfeits))
+}
@@ -0,0 +1,350 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+)
+
+// runCommand runs one of the command line tools instead of the server
+//
+// The tools open the database file themselves, so they have to be run while
+// the server is stopped. Use the HTTP endpoints for a running server.
+func runCommand(args []string) error {
+	switch args[0] {
+	case "export":
+		return exportCommand(args[1:])
+	case "import":
+		return importCommand(args[1:])
+	case "migrate":
+		return migrateCommand(args[1:])
+	case "backup":
+		return backupCommand(args[1:])
+	case "restore":
+		return restoreCommand(args[1:])
+	case "archive":
+		return archiveCommand(args[1:])
+	case "copy":
+		return copyCommand(args[1:])
+	case "query":
+		return queryCommand(args[1:])
+	}
+	return fmt.Errorf("unknown command '%s', expected one of: export, import, migrate, backup, restore, archive, copy, query", args[0])
+}
+
+// DatabaseSpec returns the database to use, as given to OpenStore
+//
+// It is production.db unless DRUNKENFALL_DB says otherwise.
+func DatabaseSpec() string {
+	if spec := os.Getenv("DRUNKENFALL_DB"); spec != "" {
+		return spec
+	}
+	return "production.db"
+}
+
+// AdminToken returns the token that the admin routes need
+//
+// Without DRUNKENFALL_ADMIN_TOKEN, the admin routes are turned off.
+func AdminToken() string {
+	return os.Getenv("DRUNKENFALL_ADMIN_TOKEN")
+}
+
+// exportCommand writes a tournament out in one of the export formats
+func exportCommand(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	fn := fs.String("db", DatabaseSpec(), "database file to read from")
+	out := fs.String("o", "", "file to write to (default: standard output)")
+	format := fs.String("format", ExportJSON, "one of: "+strings.Join(ExportFormats, ", "))
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: drunkenfall export [flags] <tournament id>")
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("need the id of a tournament to export")
+	}
+
+	db, err := NewDatabase(*fn)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if err := db.LoadTournaments(); err != nil {
+		return err
+	}
+
+	t, ok := db.lookup(fs.Arg(0))
+	if !ok {
+		return fmt.Errorf("no tournament with id '%s'", fs.Arg(0))
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	return t.Export(w, *format)
+}
+
+// importCommand restores a tournament from an archive file
+func importCommand(args []string) error {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	fn := fs.String("db", DatabaseSpec(), "database file to import into")
+	id := fs.String("id", "", "import under this id instead")
+	overwrite := fs.Bool("overwrite", false, "replace a tournament with the same id")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: drunkenfall import [flags] <archive file>")
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("need an archive to import")
+	}
+
+	data, err := ioutil.ReadFile(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	db, err := NewDatabase(*fn)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if err := db.LoadTournaments(); err != nil {
+		return err
+	}
+
+	t, err := db.ImportTournament(data, ImportOptions{ID: *id, Overwrite: *overwrite})
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(t.ID)
+	return nil
+}
+
+// migrateCommand upgrades all stored tournaments to the current schema
+func migrateCommand(args []string) error {
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	fn := fs.String("db", DatabaseSpec(), "database file to migrate")
+	dry := fs.Bool("dry-run", false, "only list the tournaments that need upgrading")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	db, err := NewDatabase(*fn)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	ids, err := db.Migrate(*dry)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		fmt.Println(id)
+	}
+	if *dry {
+		log.Printf("%d tournaments need upgrading to schema version %d", len(ids), SchemaVersion)
+	} else {
+		log.Printf("%d tournaments upgraded to schema version %d", len(ids), SchemaVersion)
+	}
+	return nil
+}
+
+// backupCommand takes a backup of the database
+func backupCommand(args []string) error {
+	fs := flag.NewFlagSet("backup", flag.ContinueOnError)
+	fn := fs.String("db", DatabaseSpec(), "database file to back up")
+	dir := fs.String("dir", DefaultBackupDir, "directory to put the backup in")
+	keep := fs.Int("keep", DefaultBackupKeep, "how many backups to keep")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	db, err := NewDatabase(*fn)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	b := NewBackups(db, *dir)
+	b.Keep = *keep
+	bi, err := b.Take()
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(bi.Path)
+	return nil
+}
+
+// restoreCommand replaces the database with a backup
+func restoreCommand(args []string) error {
+	fs := flag.NewFlagSet("restore", flag.ContinueOnError)
+	fn := fs.String("db", DatabaseSpec(), "database file to replace")
+	dir := fs.String("dir", DefaultBackupDir, "directory with the backups")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: drunkenfall restore [flags] <backup file | latest>")
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("need a backup to restore")
+	}
+
+	backup := fs.Arg(0)
+	if backup == "latest" {
+		bs, err := (&Backups{Dir: *dir}).List()
+		if err != nil {
+			return err
+		}
+		if len(bs) == 0 {
+			return fmt.Errorf("no backups in %s", *dir)
+		}
+		backup = bs[0].Path
+	}
+
+	kept, err := RestoreBackup(backup, *fn)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Restored %s from %s", *fn, backup)
+	if kept != "" {
+		log.Printf("The replaced database was kept as %s", kept)
+	}
+	return nil
+}
+
+// archiveCommand archives the tournaments that ended a while ago
+func archiveCommand(args []string) error {
+	fs := flag.NewFlagSet("archive", flag.ContinueOnError)
+	fn := fs.String("db", DatabaseSpec(), "database to archive in")
+	age := fs.Duration("age", DefaultArchiveAge, "archive tournaments that ended longer ago than this")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	db, err := NewDatabase(*fn)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if err := db.LoadTournaments(); err != nil {
+		return err
+	}
+
+	ids, err := db.ArchiveEnded(*age)
+	for _, id := range ids {
+		fmt.Println(id)
+	}
+	return err
+}
+
+// copyCommand copies all the tournaments and their events into another store
+//
+// This is how a bolt database is moved over to SQLite.
+func copyCommand(args []string) error {
+	fs := flag.NewFlagSet("copy", flag.ContinueOnError)
+	from := fs.String("from", DatabaseSpec(), "database to copy from")
+	to := fs.String("to", "", "database to copy into, e.g. sqlite:drunkenfall.sqlite")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *to == "" {
+		fs.Usage()
+		return errors.New("need a database to copy into")
+	}
+
+	src, err := OpenStore(*from)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := OpenStore(*to)
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+
+	n, err := CopyStore(dst, src)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Copied %d tournaments from %s to %s", n, *from, *to)
+	return nil
+}
+
+// queryCommand runs an ad-hoc query and prints the rows as JSON lines
+func queryCommand(args []string) error {
+	fs := flag.NewFlagSet("query", flag.ContinueOnError)
+	fn := fs.String("db", DatabaseSpec(), "database to query, has to be SQLite")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: drunkenfall query [flags] <sql>")
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("need a query to run")
+	}
+
+	s, err := OpenStore(*fn)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	q, ok := s.(Querier)
+	if !ok {
+		return fmt.Errorf("%s cannot be queried, only SQLite databases can", *fn)
+	}
+
+	rows, err := q.Query(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	for _, row := range rows {
+		if err := enc.Encode(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -1,85 +1,158 @@
 package main
 
 import (
-	"github.com/boltdb/bolt"
+	"bytes"
+	"fmt"
 	"log"
+	"sort"
+	"sync"
+	"time"
 )
 
 // Database is the persisting class
+//
+// Tournaments and tournamentRef are read by the websocket updates while the
+// handlers change them, so they are only touched under mu once the server
+// runs.
 type Database struct {
-	DB            *bolt.DB
+	Store         Store
 	Server        *Server
 	Tournaments   []*Tournament
 	tournamentRef map[string]*Tournament
+	trash         map[string]*Tournament
+	mu            sync.RWMutex
 }
 
-var (
-	// TournamentKey is the byte string identifying the tournament buckets
-	TournamentKey = []byte("tournaments")
-)
-
 // NewDatabase returns a new database object
-func NewDatabase(fn string) (*Database, error) {
-	bolt, err := bolt.Open(fn, 0600, nil)
+//
+// See OpenStore for what the spec can be.
+func NewDatabase(spec string) (*Database, error) {
+	store, err := OpenStore(spec)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
-	log.Printf("Using %s", fn)
+	log.Printf("Using %s", spec)
 
-	db := &Database{DB: bolt}
-	db.tournamentRef = make(map[string]*Tournament)
+	return NewStoreDatabase(store), nil
+}
 
-	return db, nil
+// NewStoreDatabase returns a database object on top of an opened store
+func NewStoreDatabase(store Store) *Database {
+	return &Database{
+		Store:         store,
+		tournamentRef: make(map[string]*Tournament),
+		trash:         make(map[string]*Tournament),
+	}
 }
 
 // LoadTournaments loads the tournaments from the database and into memory
+//
+// Deleted tournaments are loaded into the trash.
 func (d *Database) LoadTournaments() error {
-	err := d.DB.View(func(tx *bolt.Tx) error {
-		b := tx.Bucket(TournamentKey)
-		if b == nil {
-			// If there is no bucket, bail silently.
-			// This only really happens in tests.
-			// TODO: Fix pls
-			return nil
+	ts, err := d.Store.Tournaments()
+	if err != nil {
+		return err
+	}
+
+	ids := make([]string, 0, len(ts))
+	for id := range ts {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		t, err := LoadTournament(ts[id], d)
+		if err != nil {
+			return fmt.Errorf("%s: %s", id, err)
 		}
 
-		err := b.ForEach(func(k []byte, v []byte) error {
-			t, err := LoadTournament(v, d)
-			if err != nil {
-				return err
-			}
+		if t.IsDeleted() {
+			d.toTrash(t)
+			continue
+		}
+		d.list(t)
+	}
+	return nil
+}
 
-			d.Tournaments = append(d.Tournaments, t)
-			d.tournamentRef[t.ID] = t
-			return nil
-		})
-		return err
-	})
+// loaded returns a copy of the loaded tournaments
+func (d *Database) loaded() []*Tournament {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return append([]*Tournament{}, d.Tournaments...)
+}
 
-	return err
+// lookup returns the loaded tournament with the ID
+func (d *Database) lookup(id string) (*Tournament, bool) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	t, ok := d.tournamentRef[id]
+	return t, ok
 }
 
 // Persist stores the current state of the tournaments into the db
 func (d *Database) Persist(t *Tournament) error {
-	ret := d.DB.Update(func(tx *bolt.Tx) error {
-		b, err := tx.CreateBucketIfNotExists(TournamentKey)
-		if err != nil {
-			return err
-		}
+	json, err := t.JSON()
+	if err != nil {
+		return err
+	}
+	return d.Store.Save(t.ID, json)
+}
+
+// AddEvent records something that happened in a tournament, and sends it to
+// the webhooks of the tournament
+func (d *Database) AddEvent(t *Tournament, kind, match, player string) error {
+	e := Event{
+		Tournament: t.ID,
+		Match:      match,
+		Kind:       kind,
+		Player:     player,
+		Time:       time.Now(),
+	}
+	if err := d.Store.AddEvent(e); err != nil {
+		return err
+	}
+
+	d.notify(t, e)
+	return nil
+}
 
-		json, _ := t.JSON()
-		err = b.Put([]byte(t.ID), json)
+// Migrate upgrades all the stored tournaments to the current schema version
+//
+// It returns the IDs of the tournaments that needed upgrading. With dry set,
+// nothing is written.
+func (d *Database) Migrate(dry bool) ([]string, error) {
+	ts, err := d.Store.Tournaments()
+	if err != nil {
+		return nil, err
+	}
+
+	ids := []string{}
+	upgraded := make(map[string][]byte)
+	for id, v := range ts {
+		data, err := Migrate(v)
 		if err != nil {
-			log.Fatal(err)
+			return nil, fmt.Errorf("%s: %s", id, err)
 		}
+		if !bytes.Equal(data, v) {
+			upgraded[id] = data
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
 
-		return nil
-	})
-
-	return ret
+	if dry {
+		return ids, nil
+	}
+	for _, id := range ids {
+		if err := d.Store.Save(id, upgraded[id]); err != nil {
+			return ids, err
+		}
+	}
+	return ids, nil
 }
 
 // Close closes the database
 func (d *Database) Close() error {
-	return d.DB.Close()
+	return d.Store.Close()
 }
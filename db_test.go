@@ -2,65 +2,73 @@ package main
 
 import (
 	"encoding/json"
-	"github.com/boltdb/bolt"
 	"github.com/stretchr/testify/assert"
+	"github.com/thiderman/drunkenfall/websockets"
 	"log"
 	"os"
+	"strings"
 	"testing"
 )
 
-// TestDatabase returns a clean test database
-func MockDatabase(arg ...string) *Database {
-	var fn string
-	if len(arg) != 0 {
-		fn = "test/" + arg[0]
-	} else {
-		fn = "test/test.db"
-	}
+// MockDatabase returns a clean test database, kept in memory
+func MockDatabase() *Database {
+	return NewStoreDatabase(NewMemoryStore())
+}
 
-	os.Remove(fn)
+// fileDatabase returns a clean test database in a file under test/
+//
+// It is for the tests that need the store on disk, like the backups. The
+// spec is given to OpenStore, so "sqlite:" can be prefixed.
+func fileDatabase(spec string) *Database {
 	os.Mkdir("test/", 0700)
 
-	db, err := NewDatabase(fn)
+	prefix := ""
+	if strings.HasPrefix(spec, "sqlite:") {
+		prefix, spec = "sqlite:", strings.TrimPrefix(spec, "sqlite:")
+	}
+	os.Remove("test/" + spec)
+
+	db, err := NewDatabase(prefix + "test/" + spec)
 	if err != nil {
 		log.Fatal(err)
 	}
-
 	return db
 }
 
+// testServer returns a server with a listening websocket hub, without any of
+// the HTTP routing set up
+func testServer(db *Database) *Server {
+	s := &Server{DB: db, ws: websockets.NewServer()}
+	db.Server = s
+	go s.ws.Listen()
+	return s
+}
+
 func TestPersist(t *testing.T) {
 	assert := assert.New(t)
-	fn := "persist.db"
-	db := MockDatabase(fn)
+	db := MockDatabase()
 
 	id := "1241234"
 	tm := Tournament{Name: "hehe", ID: id}
+	assert.Nil(db.Persist(&tm))
 
-	db.Persist(&tm)
-	db.Close()
-
-	boltd, err := bolt.Open("test/"+fn, 0600, nil)
-	if err != nil {
-		log.Fatal(err)
-	}
+	ts, err := db.Store.Tournaments()
+	assert.Nil(err)
 
 	ct := Tournament{}
-	boltd.View(func(tx *bolt.Tx) error {
-		b := tx.Bucket(TournamentKey)
-		if b == nil {
-			t.Fatal("bucket not created")
-		}
-
-		data := b.Get([]byte(id))
-		err := json.Unmarshal(data, &ct)
-		if err != nil {
-			t.Fatal(err)
-		}
-
-		return nil
-	})
-
+	assert.Nil(json.Unmarshal(ts[id], &ct))
 	assert.Equal(ct.Name, tm.Name)
 	assert.Equal(ct.ID, tm.ID)
 }
+
+func TestLoadTournaments(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTournament(8)
+	tm.StartTournament()
+
+	db := NewStoreDatabase(tm.db.Store)
+	assert.Nil(db.LoadTournaments())
+	assert.Equal(1, len(db.Tournaments))
+	assert.Equal(tm.Name, db.tournamentRef[tm.ID].Name)
+	assert.Equal(db.tournamentRef[tm.ID], db.tournamentRef[tm.ID].Tryouts[0].Tournament)
+}
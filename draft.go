@@ -0,0 +1,352 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/thiderman/drunkenfall/websockets"
+)
+
+// ColorDraft is what a player needs to know about their archer for their
+// next match
+type ColorDraft struct {
+	Player      string   `json:"player"`
+	Preferences []string `json:"preferences"`
+	Match       string   `json:"match,omitempty"`
+	URL         string   `json:"url,omitempty"`
+	Color       string   `json:"color,omitempty"`
+}
+
+// DraftMessage is the new archer of a player, pushed over the websocket when
+// the draft changes it
+type DraftMessage struct {
+	Draft ColorDraft `json:"draft"`
+}
+
+// draftPick is a player in the draft, with what decides when they pick
+type draftPick struct {
+	index  int
+	seed   int
+	score  int
+	joined int
+	wants  []string
+}
+
+// Preferences returns the archers the player wants, most wanted first
+func (p *Player) Preferences() []string {
+	if len(p.Colors) != 0 {
+		return p.Colors
+	}
+	if p.PreferredColor != "" {
+		return []string{p.PreferredColor}
+	}
+	return []string{}
+}
+
+// DraftColors assigns an archer to every player in the match
+//
+// The players pick in order of priority. Seeded players go first, by their
+// seed, then the players with the best score in the tournament, and then the
+// ones that joined first. Everyone gets the first of their preferred archers
+// that is still free. If they are all taken, the other skin of the archers
+// they want is used, so that two players can play green as long as one plays
+// the alternate. A player without any of that left gets the first archer that
+// nobody picking after them wants.
+//
+// The assigned archer is the PreferredColor of the player in the match, and
+// the preferences are kept in Colors. Matches that have started keep the
+// archers they were started with. Players whose archer changes are sent their
+// new one when the tournament is persisted.
+func (m *Match) DraftColors() {
+	if m.IsStarted() {
+		return
+	}
+
+	picks := []draftPick{}
+	for i := range m.Players {
+		p := &m.Players[i]
+		if p.IsPrefill() {
+			continue
+		}
+
+		// The preference has to be kept before the archer is assigned
+		if len(p.Colors) == 0 && p.PreferredColor != "" {
+			p.Colors = []string{p.PreferredColor}
+		}
+
+		picks = append(picks, draftPick{
+			index:  i,
+			seed:   m.seed(p.Name),
+			score:  m.draftScore(p),
+			joined: m.joinOrder(p.Name, i),
+			wants:  p.Preferences(),
+		})
+	}
+
+	sort.SliceStable(picks, func(i, j int) bool {
+		a, b := picks[i], picks[j]
+		if a.seed != b.seed {
+			if a.seed == 0 || b.seed == 0 {
+				return b.seed == 0
+			}
+			return a.seed < b.seed
+		}
+		if a.score != b.score {
+			return a.score > b.score
+		}
+		return a.joined < b.joined
+	})
+
+	taken := make(map[string]bool)
+	for i, pick := range picks {
+		color := ""
+		for _, c := range pick.wants {
+			if !taken[c] {
+				color = c
+				break
+			}
+		}
+		for _, c := range pick.wants {
+			if o := otherSkin(c); color == "" && o != "" && !taken[o] {
+				color = o
+			}
+		}
+		if color == "" {
+			color = freeColor(taken, picks[i+1:])
+		}
+
+		taken[color] = true
+		p := &m.Players[pick.index]
+		if p.PreferredColor != color && m.Tournament != nil {
+			m.Tournament.redrafted = append(m.Tournament.redrafted, p.Name)
+		}
+		p.PreferredColor = color
+	}
+}
+
+// freeColor returns the first archer that is not taken, preferring the ones
+// that none of the remaining players want
+func freeColor(taken map[string]bool, rest []draftPick) string {
+	wanted := make(map[string]bool)
+	for _, pick := range rest {
+		for _, c := range pick.wants {
+			wanted[c] = true
+		}
+	}
+
+	for _, s := range Skins() {
+		if !taken[s.ID] && !wanted[s.ID] {
+			return s.ID
+		}
+	}
+	for _, s := range Skins() {
+		if !taken[s.ID] {
+			return s.ID
+		}
+	}
+	return ""
+}
+
+// seed returns where the player placed in the last match they played, or 0
+// if they have not played yet
+func (m *Match) seed(name string) int {
+	t := m.Tournament
+	if t == nil {
+		return 0
+	}
+
+	var last *Match
+	for _, o := range t.Matches() {
+		if o == m || !o.IsEnded() || o.getPlayer(name) == nil {
+			continue
+		}
+		if last == nil || o.Ended.After(last.Ended) {
+			last = o
+		}
+	}
+	if last == nil {
+		return 0
+	}
+
+	if t.IsTeamMode() {
+		for i, ts := range last.TeamStandings() {
+			for _, p := range ts.Players {
+				if p.Name == name {
+					return i + 1
+				}
+			}
+		}
+		return 0
+	}
+
+	for i, p := range last.Standings() {
+		if p.Name == name {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// draftScore returns the score of a player over the matches they have played
+func (m *Match) draftScore(p *Player) int {
+	score := p.Score()
+	if m.Tournament == nil {
+		return score
+	}
+
+	for _, o := range m.Tournament.Matches() {
+		if o == m || !o.IsEnded() {
+			continue
+		}
+		if op := o.getPlayer(p.Name); op != nil {
+			score += op.Score()
+		}
+	}
+	return score
+}
+
+// joinOrder returns how early the player joined the tournament
+//
+// Without a tournament, the place in the match is used instead.
+func (m *Match) joinOrder(name string, index int) int {
+	t := m.Tournament
+	if t == nil {
+		return index
+	}
+
+	for i, p := range t.Players {
+		if p.Name == name {
+			return i
+		}
+	}
+	return len(t.Players) + index
+}
+
+// SetColors sets the archers a player wants, most wanted first
+//
+// The archers of the matches the player has not started yet are drafted
+// again.
+func (t *Tournament) SetColors(name string, colors []string) error {
+	if len(colors) == 0 {
+		return errors.New("need at least one color")
+	}
+
+	wants := []string{}
+	seen := make(map[string]bool)
+	for _, c := range colors {
+		if !isColor(c) {
+			return fmt.Errorf("unknown color %s", c)
+		}
+		if !seen[c] {
+			seen[c] = true
+			wants = append(wants, c)
+		}
+	}
+
+	found := false
+	for i := range t.Players {
+		if t.Players[i].Name == name {
+			t.Players[i].Colors = wants
+			t.Players[i].PreferredColor = wants[0]
+			found = true
+		}
+	}
+	if pos := t.WaitlistPosition(name); pos != 0 {
+		t.Waitlist[pos-1].Colors = wants
+		t.Waitlist[pos-1].PreferredColor = wants[0]
+		found = true
+	}
+	if !found {
+		return fmt.Errorf("no player named %s", name)
+	}
+
+	for _, m := range t.Matches() {
+		p := m.getPlayer(name)
+		if p == nil || m.IsStarted() {
+			continue
+		}
+
+		p.Colors = wants
+		m.DraftColors()
+	}
+
+	return t.Persist()
+}
+
+// ColorDraft returns the archer of a player for their next match
+//
+// Until the match is full, the archer is what the player would get with the
+// players that are already in it.
+func (t *Tournament) ColorDraft(name string) (ColorDraft, error) {
+	var p *Player
+	for i := range t.Players {
+		if t.Players[i].Name == name {
+			p = &t.Players[i]
+		}
+	}
+	if pos := t.WaitlistPosition(name); pos != 0 {
+		p = &t.Waitlist[pos-1]
+	}
+	if p == nil {
+		return ColorDraft{}, fmt.Errorf("no player named %s", name)
+	}
+
+	d := ColorDraft{Player: name, Preferences: p.Preferences()}
+	for _, m := range t.Matches() {
+		mp := m.getPlayer(name)
+		if mp == nil || m.IsEnded() {
+			continue
+		}
+
+		d.Match = m.Title()
+		d.URL = m.URL()
+		d.Color = mp.PreferredColor
+		break
+	}
+	return d, nil
+}
+
+// isColor returns boolean whether there is an archer skin with the ID
+func isColor(c string) bool {
+	_, ok := GetSkin(c)
+	return ok
+}
+
+// drafts returns the drafts of the players whose archers changed since the
+// last time, and forgets about them
+func (t *Tournament) drafts() []ColorDraft {
+	ds := []ColorDraft{}
+	seen := make(map[string]bool)
+	for _, name := range t.redrafted {
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		// The player might have been taken out again since
+		if d, err := t.ColorDraft(name); err == nil {
+			ds = append(ds, d)
+		}
+	}
+	t.redrafted = nil
+	return ds
+}
+
+// sendDrafts pushes the changed drafts to the websocket
+func (t *Tournament) sendDrafts() {
+	ds := t.drafts()
+	if len(ds) == 0 || t.server == nil || t.server.ws == nil {
+		return
+	}
+
+	ws := t.server.ws
+	go func() {
+		for _, d := range ds {
+			ws.SendAll(&websockets.Message{
+				Data:      DraftMessage{Draft: d},
+				Transient: true,
+			})
+		}
+	}()
+}
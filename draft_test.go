@@ -0,0 +1,196 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestDraftColorsGoesByScore(t *testing.T) {
+	assert := assert.New(t)
+
+	m := NewMatch(tm, 0, "final")
+	_ = m.AddPlayer(Player{Name: "a", Colors: []string{"red", "blue"}})
+	_ = m.AddPlayer(Player{Name: "b", Colors: []string{"red", "green"}})
+	_ = m.AddPlayer(Player{Name: "c", Colors: []string{"red", "green"}})
+	m.Players[2].AddKill(2)
+	m.Players[1].AddKill(1)
+
+	m.DraftColors()
+
+	assert.Equal("blue", m.Players[0].PreferredColor)
+	assert.Equal("green", m.Players[1].PreferredColor)
+	assert.Equal("red", m.Players[2].PreferredColor)
+}
+
+func TestDraftColorsGoesByJoinOrder(t *testing.T) {
+	assert := assert.New(t)
+
+	m := NewMatch(tm, 0, "final")
+	_ = m.AddPlayer(Player{Name: "a", Colors: []string{"red"}})
+	_ = m.AddPlayer(Player{Name: "b", Colors: []string{"red", "pink"}})
+
+	assert.Equal("red", m.Players[0].PreferredColor)
+	assert.Equal("pink", m.Players[1].PreferredColor)
+}
+
+func TestDraftColorsFallbackAvoidsLaterPicks(t *testing.T) {
+	assert := assert.New(t)
+
+	m := NewMatch(tm, 0, "final")
+	_ = m.AddPlayer(Player{Name: "a", Colors: []string{"green"}})
+	_ = m.AddPlayer(Player{Name: "b", Colors: []string{"green-alt"}})
+	_ = m.AddPlayer(Player{Name: "c", Colors: []string{"green"}})
+	_ = m.AddPlayer(Player{Name: "d", Colors: []string{"blue"}})
+
+	assert.Equal("green", m.Players[0].PreferredColor)
+	assert.Equal("green-alt", m.Players[1].PreferredColor)
+	assert.Equal("pink", m.Players[2].PreferredColor)
+	assert.Equal("blue", m.Players[3].PreferredColor)
+}
+
+func TestDraftColorsUsesAlternateSkin(t *testing.T) {
+	assert := assert.New(t)
+
+	m := NewMatch(tm, 0, "final")
+	_ = m.AddPlayer(Player{Name: "a", Colors: []string{"green"}})
+	_ = m.AddPlayer(Player{Name: "b", Colors: []string{"green"}})
+	_ = m.AddPlayer(Player{Name: "c", Colors: []string{"cyan-alt"}})
+	_ = m.AddPlayer(Player{Name: "d", Colors: []string{"cyan-alt"}})
+
+	assert.Equal("green", m.Players[0].PreferredColor)
+	assert.Equal("green-alt", m.Players[1].PreferredColor)
+	assert.Equal("cyan-alt", m.Players[2].PreferredColor)
+	assert.Equal("cyan", m.Players[3].PreferredColor)
+}
+
+func TestDraftColorsKeepsPreferences(t *testing.T) {
+	assert := assert.New(t)
+
+	m := NewMatch(tm, 0, "final")
+	_ = m.AddPlayer(Player{Name: "a", PreferredColor: "green"})
+	_ = m.AddPlayer(Player{Name: "b", PreferredColor: "green"})
+
+	assert.Equal([]string{"green"}, m.Players[1].Preferences())
+	m.Players[0].AddKill(-1)
+	m.DraftColors()
+
+	assert.Equal("green", m.Players[1].PreferredColor)
+	assert.NotEqual("green", m.Players[0].PreferredColor)
+}
+
+func TestDraftColorsGoesBySeed(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTournament(16)
+	assert.Nil(tm.StartTournament())
+	endTryouts(tm)
+
+	m := tm.Semis[0]
+	var first, rest *Player
+	for i := range m.Players {
+		p := &m.Players[i]
+		if m.seed(p.Name) == 1 && first == nil {
+			first = p
+		} else if m.seed(p.Name) > 1 && rest == nil {
+			rest = p
+		}
+	}
+	assert.NotNil(first)
+	assert.NotNil(rest)
+
+	// Nobody else in the match can want green
+	others := []string{"blue", "pink", "orange", "white"}
+	for i, p := range m.Players {
+		assert.Nil(tm.SetColors(p.Name, []string{others[i]}))
+	}
+	assert.Nil(tm.SetColors(rest.Name, []string{"green"}))
+	assert.Nil(tm.SetColors(first.Name, []string{"green"}))
+
+	assert.Equal("green", first.PreferredColor)
+	assert.NotEqual("green", rest.PreferredColor)
+}
+
+func TestSetColors(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTournament(8)
+	assert.Nil(tm.StartTournament())
+
+	m := tm.Tryouts[1]
+	name := m.Players[0].Name
+	assert.Nil(tm.SetColors(name, []string{"green", "red", "green"}))
+
+	assert.Equal([]string{"green", "red"}, tm.getPlayer(name).Colors)
+	assert.Equal([]string{"green", "red"}, m.Players[0].Colors)
+	assert.Equal("green", m.Players[0].PreferredColor)
+	for _, p := range m.Players[1:] {
+		assert.NotEqual("green", p.PreferredColor)
+	}
+}
+
+func TestSetColorsLeavesStartedMatches(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTournament(8)
+	assert.Nil(tm.StartTournament())
+
+	m := tm.Tryouts[0]
+	assert.Nil(m.Start())
+	name := m.Players[0].Name
+	color := m.Players[0].PreferredColor
+
+	// None of the 8 players start out with green
+	assert.Nil(tm.SetColors(name, []string{"green"}))
+	assert.Equal(color, m.Players[0].PreferredColor)
+	assert.Equal([]string{"green"}, tm.getPlayer(name).Colors)
+}
+
+func TestSetColorsUnknown(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTournament(8)
+
+	assert.NotNil(tm.SetColors("1", []string{"green", "gold"}))
+	assert.NotNil(tm.SetColors("1", []string{"gold-alt"}))
+	assert.Nil(tm.SetColors("1", []string{"red-alt"}))
+	assert.NotNil(tm.SetColors("1", []string{}))
+	assert.NotNil(tm.SetColors("nobody", []string{"green"}))
+}
+
+func TestRedraftedPlayersAreSent(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTournament(8)
+	assert.Nil(tm.StartTournament())
+
+	m := tm.Tryouts[1]
+	name := m.Players[0].Name
+	m.Players[0].Colors = []string{"green"}
+	m.DraftColors()
+
+	ds := tm.drafts()
+	assert.Equal(1, len(ds))
+	assert.Equal(name, ds[0].Player)
+	assert.Equal("green", ds[0].Color)
+	assert.Equal(0, len(tm.drafts()))
+
+	// Drafting again without changes sends nothing
+	m.DraftColors()
+	assert.Equal(0, len(tm.drafts()))
+}
+
+func TestColorDraft(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTournament(8)
+	assert.Nil(tm.StartTournament())
+
+	m := tm.Tryouts[1]
+	name := m.Players[0].Name
+	assert.Nil(tm.SetColors(name, []string{"green", "cyan"}))
+
+	d, err := tm.ColorDraft(name)
+	assert.Nil(err)
+	assert.Equal(name, d.Player)
+	assert.Equal([]string{"green", "cyan"}, d.Preferences)
+	assert.Equal(m.Title(), d.Match)
+	assert.Equal(m.URL(), d.URL)
+	assert.Equal("green", d.Color)
+
+	_, err = tm.ColorDraft("nobody")
+	assert.NotNil(err)
+}
@@ -1,7 +1,11 @@
 package main
 
 import (
+	"bytes"
+	"crypto/subtle"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"github.com/gorilla/handlers"
 	"github.com/gorilla/mux"
 	"github.com/gorilla/sessions"
@@ -10,7 +14,11 @@ import (
 	"net/http"
 	"os"
 	"strconv"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/thiderman/drunkenfall/render"
 	"github.com/thiderman/drunkenfall/websockets"
 	"golang.org/x/net/websocket"
 )
@@ -22,10 +30,21 @@ var (
 
 // Server is an abstraction that runs via a web interface
 type Server struct {
-	DB     *Database
-	router http.Handler
-	logger http.Handler
-	ws     *websockets.Server
+	DB       *Database
+	router   http.Handler
+	logger   http.Handler
+	ws       *websockets.Server
+	renderer *render.Renderer
+	overlays overlayCache
+	backups  *Backups
+
+	overlayQueue overlayQueue
+
+	locksMu sync.Mutex
+	locks   map[string]*sync.Mutex
+
+	// adminToken is what the admin routes need to be used
+	adminToken string
 }
 
 // JSONMessage defines a message to be returned to the frontend
@@ -39,6 +58,13 @@ type UpdateMessage struct {
 	Tournament *Tournament `json:"tournament"`
 }
 
+// WaitlistMessage tells a player that they are on the waitlist
+type WaitlistMessage struct {
+	Message  string `json:"message"`
+	Redirect string `json:"redirect"`
+	Position int    `json:"position"`
+}
+
 // UpdateMatchMessage returns an update to the current match
 type UpdateMatchMessage struct {
 	Match *Match `json:"match"`
@@ -51,6 +77,25 @@ type UpdateStateMessage struct {
 
 // NewRequest is the request to make a new tournament
 type NewRequest struct {
+	Name    string `json:"name"`
+	ID      string `json:"id"`
+	Mode    string `json:"mode"`
+	CheckIn bool   `json:"check_in"`
+}
+
+// ColorsRequest is the request to set the archers a player wants
+type ColorsRequest struct {
+	Name   string   `json:"name"`
+	Colors []string `json:"colors"`
+}
+
+// RenameRequest is the request to rename a tournament
+type RenameRequest struct {
+	Name string `json:"name"`
+}
+
+// CloneRequest is the request to clone a tournament into a new one
+type CloneRequest struct {
 	Name string `json:"name"`
 	ID   string `json:"id"`
 }
@@ -61,6 +106,13 @@ type JoinRequest struct {
 	Color string `json:"color"`
 }
 
+// TeamJoinRequest is the request to join a team tournament
+type TeamJoinRequest struct {
+	Name    string        `json:"name"`
+	Color   string        `json:"color"`
+	Members []JoinRequest `json:"members"`
+}
+
 // CommitPlayer is one state for a player in a commit message
 type CommitPlayer struct {
 	Ups    int    `json:"ups"`
@@ -74,12 +126,67 @@ type CommitRequest struct {
 	State []CommitPlayer `json:"state"`
 }
 
+// WebhookRequest is the request to add a webhook to a tournament
+type WebhookRequest struct {
+	URL    string   `json:"url"`
+	Secret string   `json:"secret"`
+	Events []string `json:"events"`
+}
+
+// PredictRequest is a spectator predicting the winner of a match
+type PredictRequest struct {
+	Spectator string `json:"spectator"`
+	Winner    string `json:"winner"`
+}
+
+// ReplayRequest is a request to replay a match over the websocket
+//
+// The interval is in milliseconds.
+type ReplayRequest struct {
+	Interval int `json:"interval"`
+}
+
+// OverrideRequest is a request from an organizer to force a match state
+type OverrideRequest struct {
+	State  string `json:"state"`
+	Reason string `json:"reason"`
+}
+
+// StationCountRequest is a request to set the amount of game stations
+type StationCountRequest struct {
+	Count int `json:"count"`
+}
+
+// PlayerRequest is a request concerning a single player in a match
+type PlayerRequest struct {
+	Name string `json:"name"`
+}
+
+// AssignRequest is a request to put a match on a station
+type AssignRequest struct {
+	Station int `json:"station"`
+}
+
+// TieRequest is a request to resolve a tie with the winner of a sudden death
+// round
+type TieRequest struct {
+	Winner string `json:"winner"`
+}
+
 // NewServer instantiates a server with an active database
 func NewServer(db *Database) *Server {
-	s := Server{DB: db}
+	s := Server{DB: db, adminToken: AdminToken()}
 	s.ws = websockets.NewServer()
 	s.router = s.BuildRouter(s.ws)
 
+	// Without the assets there are no overlay images, but everything else
+	// still works.
+	renderer, err := render.NewRenderer("static", renderArchers())
+	if err != nil {
+		log.Printf("Not rendering overlays: %s", err)
+	}
+	s.renderer = renderer
+
 	http.Handle("/", s.router)
 	s.logger = handlers.LoggingHandler(os.Stdout, s.router)
 
@@ -89,7 +196,10 @@ func NewServer(db *Database) *Server {
 	return &s
 }
 
-// NewHandler shows the page to create a new tournament
+// NewHandler creates a new tournament
+//
+// Unless an ID is given, one is made from the name. A given ID that is
+// already taken is a conflict.
 func (s *Server) NewHandler(w http.ResponseWriter, r *http.Request) {
 	var req NewRequest
 	body, err := ioutil.ReadAll(r.Body)
@@ -100,27 +210,191 @@ func (s *Server) NewHandler(w http.ResponseWriter, r *http.Request) {
 
 	err = json.Unmarshal(body, &req)
 	if err != nil {
-		log.Fatal(err)
+		http.Error(w, err.Error(), 400)
+		return
+	}
+
+	if strings.TrimSpace(req.Name) == "" {
+		http.Error(w, "need a name", 400)
+		return
+	}
+	// The mode is checked before anything is created, since the tournament
+	// is persisted as soon as it is made.
+	if err := (&Tournament{}).SetMode(req.Mode); err != nil {
+		http.Error(w, err.Error(), 400)
+		return
+	}
+
+	id, err := s.DB.TournamentID(req.Name, req.ID)
+	if err == ErrIDTaken {
+		http.Error(w, err.Error(), 409)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), 400)
+		return
+	}
+
+	t, _ := NewTournament(strings.TrimSpace(req.Name), id, s)
+	_ = t.SetMode(req.Mode)
+	t.RequireCheckIn = req.CheckIn
+	t.Persist()
+	log.Printf("Created %s tournament %s!", t.Mode, t.Name)
+
+	s.DB.list(t)
+
+	s.redirect(w, t.URL())
+}
+
+// ImportHandler restores a tournament from an exported archive
+//
+// If a tournament with the same ID exists, the import gets a new ID unless
+// `overwrite` is set. The ID can also be set with `id`.
+func (s *Server) ImportHandler(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.Print(err)
+		return
 	}
 
-	t, _ := NewTournament(req.Name, req.ID, s)
-	log.Printf("Created tournament %s!", t.Name)
+	q := r.URL.Query()
+	opts := ImportOptions{
+		ID:        q.Get("id"),
+		Overwrite: q.Get("overwrite") == "true",
+	}
 
-	s.DB.Tournaments = append(s.DB.Tournaments, t)
-	s.DB.tournamentRef[t.ID] = t
+	t, err := s.DB.ImportTournament(body, opts)
+	if err != nil {
+		http.Error(w, err.Error(), 400)
+		return
+	}
 
 	s.redirect(w, t.URL())
 }
 
+// BackupHandler lists the database backups, or takes one when posted to
+func (s *Server) BackupHandler(w http.ResponseWriter, r *http.Request) {
+	if s.backups == nil {
+		http.Error(w, "backups are not enabled", 503)
+		return
+	}
+
+	var out interface{}
+	var err error
+	if r.Method == "POST" {
+		out, err = s.backups.Take()
+	} else {
+		out, err = s.backups.List()
+	}
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+
+	data, err := json.Marshal(out)
+	if err != nil {
+		log.Print(err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(data)
+}
+
+// QueryRequest is an ad-hoc query against the stats tables
+type QueryRequest struct {
+	Query string `json:"query"`
+}
+
+// QueryHandler runs an ad-hoc read only query, for stats
+//
+// Only the SQLite store can be queried, and only with the admin token.
+func (s *Server) QueryHandler(w http.ResponseWriter, r *http.Request) {
+	q, ok := s.DB.Store.(Querier)
+	if !ok {
+		http.Error(w, "the database cannot be queried", 501)
+		return
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), 400)
+		return
+	}
+
+	var req QueryRequest
+	if err := json.Unmarshal(body, &req); err != nil || req.Query == "" {
+		http.Error(w, "need a query", 400)
+		return
+	}
+
+	rows, err := q.Query(req.Query)
+	if err != nil {
+		http.Error(w, err.Error(), 400)
+		return
+	}
+
+	data, err := json.Marshal(rows)
+	if err != nil {
+		log.Print(err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(data)
+}
+
+// PlayersHandler returns the stats of all players over all tournaments
+func (s *Server) PlayersHandler(w http.ResponseWriter, r *http.Request) {
+	ps, err := s.DB.Store.Players()
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+
+	data, err := json.Marshal(ps)
+	if err != nil {
+		log.Print(err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(data)
+}
+
+// ArchersHandler returns every archer skin the players can pick from
+func (s *Server) ArchersHandler(w http.ResponseWriter, r *http.Request) {
+	data, err := json.Marshal(Skins())
+	if err != nil {
+		log.Print(err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(data)
+}
+
+// AchievementsHandler returns every achievement that can be earned
+func (s *Server) AchievementsHandler(w http.ResponseWriter, r *http.Request) {
+	data, err := json.Marshal(Achievements)
+	if err != nil {
+		log.Print(err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(data)
+}
+
 // TournamentHandler returns the current state of the tournament
 func (s *Server) TournamentHandler(w http.ResponseWriter, r *http.Request) {
 	canJoin := false
-	vars := mux.Vars(r)
+	waitlisted := 0
+	tm := s.getTournament(r)
+	if tm == nil {
+		http.Error(w, "no such tournament", 404)
+		return
+	}
 
-	tm := s.DB.tournamentRef[vars["id"]]
 	session, _ := store.Get(r, tm.Name)
 	if name, ok := session.Values["player"]; ok {
 		canJoin = tm.CanJoin(name.(string))
+		waitlisted = tm.WaitlistPosition(name.(string))
 	} else {
 		canJoin = true
 	}
@@ -128,9 +402,11 @@ func (s *Server) TournamentHandler(w http.ResponseWriter, r *http.Request) {
 	out := struct {
 		Tournament *Tournament
 		CanJoin    bool
+		Waitlisted int
 	}{
 		tm,
 		canJoin,
+		waitlisted,
 	}
 
 	data, err := json.Marshal(out)
@@ -145,6 +421,10 @@ func (s *Server) TournamentHandler(w http.ResponseWriter, r *http.Request) {
 func (s *Server) JoinHandler(w http.ResponseWriter, r *http.Request) {
 	var req JoinRequest
 	tm := s.getTournament(r)
+	if tm == nil {
+		http.Error(w, "no such tournament", 404)
+		return
+	}
 
 	body, err := ioutil.ReadAll(r.Body)
 	if err != nil {
@@ -154,7 +434,7 @@ func (s *Server) JoinHandler(w http.ResponseWriter, r *http.Request) {
 
 	err = json.Unmarshal(body, &req)
 	if err != nil {
-		log.Print(err)
+		http.Error(w, err.Error(), 400)
 		return
 	}
 	log.Print(req)
@@ -162,18 +442,14 @@ func (s *Server) JoinHandler(w http.ResponseWriter, r *http.Request) {
 	name := req.Name
 	color := req.Color
 
-	if !tm.CanJoin(name) {
-		http.Error(w, "too many players", 500)
-		return
-	}
 	if color == "" {
-		http.Error(w, "need a color", 500)
+		http.Error(w, "need a color", 400)
 		return
 	}
 
-	err = tm.AddPlayer(name, color)
+	pos, err := tm.Join(name, color)
 	if err != nil {
-		http.Error(w, err.Error(), 500)
+		http.Error(w, err.Error(), 400)
 		return
 	}
 
@@ -191,59 +467,119 @@ func (s *Server) JoinHandler(w http.ResponseWriter, r *http.Request) {
 	session.Values["player"] = name
 	session.Save(r, w)
 
+	if pos != 0 {
+		log.Printf("%s is #%d on the waitlist for %s", name, pos, tm.Name)
+		data, err := json.Marshal(WaitlistMessage{
+			Message:  fmt.Sprintf("The tournament is full. You are #%d on the waitlist.", pos),
+			Redirect: tm.URL(),
+			Position: pos,
+		})
+		if err != nil {
+			log.Print(err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write(data)
+		return
+	}
+
 	s.redirect(w, tm.URL())
 }
 
-// StartTournamentHandler starts tournaments
-func (s *Server) StartTournamentHandler(w http.ResponseWriter, r *http.Request) {
+// TeamJoinHandler adds a team into a team tournament
+func (s *Server) TeamJoinHandler(w http.ResponseWriter, r *http.Request) {
+	var req TeamJoinRequest
 	tm := s.getTournament(r)
-	err := tm.StartTournament()
+	if tm == nil {
+		http.Error(w, "no such tournament", 404)
+		return
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
 	if err != nil {
-		http.Error(w, err.Error(), 500)
+		log.Print(err)
 		return
 	}
 
+	err = json.Unmarshal(body, &req)
+	if err != nil {
+		http.Error(w, err.Error(), 400)
+		return
+	}
+	log.Print(req)
+
+	if req.Color == "" {
+		http.Error(w, "need a team color", 400)
+		return
+	}
+
+	members := make([]Player, 0, len(req.Members))
+	for _, m := range req.Members {
+		if m.Color == "" {
+			http.Error(w, "need a color", 400)
+			return
+		}
+		members = append(members, Player{Name: m.Name, PreferredColor: m.Color})
+	}
+
+	err = tm.AddTeam(req.Name, req.Color, members)
+	if err != nil {
+		http.Error(w, err.Error(), 400)
+		return
+	}
+
+	// TODO: This should not be here...
+	_ = tm.SetMatchPointers()
+
+	log.Printf("Team %s has joined %s!", req.Name, tm.Name)
 	s.redirect(w, tm.URL())
 }
 
-// NextHandler starts tournaments
-func (s *Server) NextHandler(w http.ResponseWriter, r *http.Request) {
+// LateJoinHandler lets an organizer add a player into a running tournament
+func (s *Server) LateJoinHandler(w http.ResponseWriter, r *http.Request) {
+	var req JoinRequest
 	tm := s.getTournament(r)
-	m, err := tm.NextMatch()
+	if tm == nil {
+		http.Error(w, "no such tournament", 404)
+		return
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
 	if err != nil {
-		http.Error(w, err.Error(), 500)
+		log.Print(err)
 		return
 	}
 
-	s.redirect(w, m.URL())
-}
+	err = json.Unmarshal(body, &req)
+	if err != nil {
+		http.Error(w, err.Error(), 400)
+		return
+	}
 
-// MatchToggleHandler starts and stops matches
-func (s *Server) MatchToggleHandler(w http.ResponseWriter, r *http.Request) {
-	m := s.getMatch(r)
-	if !m.IsStarted() {
-		log.Printf("%s started", m.String())
-		m.Start()
-	} else {
-		log.Printf("%s ended", m.String())
-		m.End()
+	if req.Color == "" {
+		http.Error(w, "need a color", 400)
+		return
 	}
 
-	data, err := json.Marshal(UpdateMessage{
-		Tournament: m.Tournament,
-	})
+	err = tm.LateRegister(req.Name, req.Color)
 	if err != nil {
-		log.Print(err)
+		http.Error(w, err.Error(), errorStatus(err))
 		return
 	}
-	w.Header().Set("Content-Type", "application/json")
-	_, _ = w.Write(data)
+
+	log.Printf("%s has joined %s late!", req.Name, tm.Name)
+	s.redirect(w, tm.URL())
 }
 
-// MatchCommitHandler commits a single round of a match
-func (s *Server) MatchCommitHandler(w http.ResponseWriter, r *http.Request) {
-	var req CommitRequest
-	// tm := s.getTournament(r)
+// WithdrawHandler lets an organizer remove a player from a tournament
+func (s *Server) WithdrawHandler(w http.ResponseWriter, r *http.Request) {
+	var req PlayerRequest
+	tm := s.getTournament(r)
+	if tm == nil {
+		http.Error(w, "no such tournament", 404)
+		return
+	}
 
 	body, err := ioutil.ReadAll(r.Body)
 	if err != nil {
@@ -253,48 +589,929 @@ func (s *Server) MatchCommitHandler(w http.ResponseWriter, r *http.Request) {
 
 	err = json.Unmarshal(body, &req)
 	if err != nil {
-		log.Print(err)
+		http.Error(w, err.Error(), 400)
 		return
 	}
-	log.Print(req)
 
-	m := s.getMatch(r)
-	states := req.State
-	scores := [][]int{
-		[]int{states[0].Ups, states[0].Downs},
-		[]int{states[1].Ups, states[1].Downs},
-		[]int{states[2].Ups, states[2].Downs},
-		[]int{states[3].Ups, states[3].Downs},
+	err = tm.WithdrawPlayer(req.Name)
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
 	}
-	shots := []bool{
-		states[0].Shot,
-		states[1].Shot,
-		states[2].Shot,
-		states[3].Shot,
+
+	log.Printf("%s has withdrawn from %s", req.Name, tm.Name)
+	s.redirect(w, tm.URL())
+}
+
+// ColorsHandler shows a player the archer they are drafted for their next
+// match, and sets the archers they want on a POST
+//
+// The player is the one in the session, unless `player` is given.
+func (s *Server) ColorsHandler(w http.ResponseWriter, r *http.Request) {
+	tm := s.getTournament(r)
+	if tm == nil {
+		http.Error(w, "no such tournament", 404)
+		return
+	}
+
+	if r.Method == "POST" {
+		var req ColorsRequest
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			log.Print(err)
+			return
+		}
+
+		err = json.Unmarshal(body, &req)
+		if err != nil {
+			http.Error(w, err.Error(), 400)
+			return
+		}
+
+		err = tm.SetColors(req.Name, req.Colors)
+		if err != nil {
+			http.Error(w, err.Error(), 400)
+			return
+		}
+
+		s.redirect(w, tm.URL())
+		return
 	}
 
-	m.Commit(scores, shots)
+	name := r.URL.Query().Get("player")
+	if name == "" {
+		session, _ := store.Get(r, tm.Name)
+		if n, ok := session.Values["player"]; ok {
+			name = n.(string)
+		}
+	}
 
-	data, err := json.Marshal(UpdateMatchMessage{
-		Match: m,
-	})
+	draft, err := tm.ColorDraft(name)
+	if err != nil {
+		http.Error(w, err.Error(), 404)
+		return
+	}
+
+	data, err := json.Marshal(draft)
 	if err != nil {
 		log.Print(err)
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
 	_, _ = w.Write(data)
-
-	return
 }
 
-// TournamentListHandler returns a list of all tournaments
-func (s *Server) TournamentListHandler(w http.ResponseWriter, r *http.Request) {
-	tournaments := s.DB.Tournaments
-	// tournaments := sort.Reverse(s.DB.Tournaments)
-	data, err := json.Marshal(tournaments)
-	if err != nil {
-		log.Fatal(err)
+// RenameHandler renames a tournament
+func (s *Server) RenameHandler(w http.ResponseWriter, r *http.Request) {
+	var req RenameRequest
+	tm := s.getTournament(r)
+	if tm == nil {
+		http.Error(w, "no such tournament", 404)
+		return
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.Print(err)
+		return
+	}
+
+	err = json.Unmarshal(body, &req)
+	if err != nil {
+		http.Error(w, err.Error(), 400)
+		return
+	}
+
+	err = tm.Rename(req.Name)
+	if err != nil {
+		http.Error(w, err.Error(), 400)
+		return
+	}
+
+	s.redirect(w, tm.URL())
+}
+
+// CloneHandler makes a new tournament with the settings and players of
+// another one
+func (s *Server) CloneHandler(w http.ResponseWriter, r *http.Request) {
+	var req CloneRequest
+	tm := s.getTournament(r)
+	if tm == nil {
+		http.Error(w, "no such tournament", 404)
+		return
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.Print(err)
+		return
+	}
+
+	// An empty body clones with the default name and ID
+	if len(body) != 0 {
+		if err := json.Unmarshal(body, &req); err != nil {
+			http.Error(w, err.Error(), 400)
+			return
+		}
+	}
+
+	c, err := s.DB.Clone(tm, req.Name, req.ID)
+	if err != nil {
+		http.Error(w, err.Error(), 400)
+		return
+	}
+
+	go s.SendWebsocketUpdate()
+	s.redirect(w, c.URL())
+}
+
+// DeleteHandler moves a tournament to the trash
+func (s *Server) DeleteHandler(w http.ResponseWriter, r *http.Request) {
+	tm := s.getTournament(r)
+	if tm == nil {
+		http.Error(w, "no such tournament", 404)
+		return
+	}
+
+	err := s.DB.Trash(tm)
+	if err != nil {
+		http.Error(w, err.Error(), 400)
+		return
+	}
+
+	go s.SendWebsocketUpdate()
+	s.redirect(w, "/")
+}
+
+// TrashHandler restores a tournament from the trash on a POST, and purges
+// it for good on a DELETE
+//
+// The trash itself is listed with `trash=true` on the tournament list.
+func (s *Server) TrashHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	switch r.Method {
+	case "POST":
+		tm, err := s.DB.Restore(id)
+		if err != nil {
+			http.Error(w, err.Error(), 404)
+			return
+		}
+
+		go s.SendWebsocketUpdate()
+		s.redirect(w, tm.URL())
+	case "DELETE":
+		if err := s.DB.Purge(id); err != nil {
+			http.Error(w, err.Error(), 404)
+			return
+		}
+		s.redirect(w, "/")
+	default:
+		http.Error(w, "restore with POST or purge with DELETE", 405)
+	}
+}
+
+// StartTournamentHandler starts tournaments
+func (s *Server) StartTournamentHandler(w http.ResponseWriter, r *http.Request) {
+	tm := s.getTournament(r)
+	if tm == nil {
+		http.Error(w, "no such tournament", 404)
+		return
+	}
+
+	err := tm.StartTournament()
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+
+	s.redirect(w, tm.URL())
+}
+
+// NextHandler starts tournaments
+func (s *Server) NextHandler(w http.ResponseWriter, r *http.Request) {
+	tm := s.getTournament(r)
+	if tm == nil {
+		http.Error(w, "no such tournament", 404)
+		return
+	}
+
+	m, err := tm.NextMatch()
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+
+	s.redirect(w, m.URL())
+}
+
+// ScheduleHandler returns the predicted schedule of the remaining matches
+func (s *Server) ScheduleHandler(w http.ResponseWriter, r *http.Request) {
+	tm := s.getTournament(r)
+	if tm == nil {
+		http.Error(w, "no such tournament", 404)
+		return
+	}
+
+	data, err := json.Marshal(tm.Schedule(time.Now()))
+	if err != nil {
+		log.Print(err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(data)
+}
+
+// BracketHandler returns the bracket graph of the tournament
+func (s *Server) BracketHandler(w http.ResponseWriter, r *http.Request) {
+	tm := s.getTournament(r)
+	if tm == nil {
+		http.Error(w, "no such tournament", 404)
+		return
+	}
+
+	data, err := json.Marshal(tm.Bracket())
+	if err != nil {
+		log.Print(err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(data)
+}
+
+// EventsHandler returns what has happened in the tournament, oldest first
+func (s *Server) EventsHandler(w http.ResponseWriter, r *http.Request) {
+	tm := s.getTournament(r)
+	if tm == nil {
+		http.Error(w, "no such tournament", 404)
+		return
+	}
+
+	events, err := s.DB.Store.Events(tm.ID)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+
+	data, err := json.Marshal(events)
+	if err != nil {
+		log.Print(err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(data)
+}
+
+// OverlayHandler serves the rendered bracket and scoreboard images
+//
+// Images that have not been rendered yet are rendered from the tournament,
+// so this holds the lock of the tournament.
+func (s *Server) OverlayHandler(w http.ResponseWriter, r *http.Request) {
+	tm := s.getTournament(r)
+	if tm == nil {
+		http.Error(w, "no such tournament", 404)
+		return
+	}
+
+	name := mux.Vars(r)["image"]
+	data, err := s.overlay(tm, name)
+	if err != nil {
+		http.Error(w, err.Error(), 503)
+		return
+	}
+
+	if strings.HasSuffix(name, ".svg") {
+		w.Header().Set("Content-Type", "image/svg+xml")
+	} else {
+		w.Header().Set("Content-Type", "image/png")
+	}
+	w.Header().Set("Cache-Control", "no-cache")
+	_, _ = w.Write(data)
+}
+
+// ExportHandler downloads the tournament in one of the export formats
+func (s *Server) ExportHandler(w http.ResponseWriter, r *http.Request) {
+	tm := s.getTournament(r)
+	if tm == nil {
+		http.Error(w, "no such tournament", 404)
+		return
+	}
+
+	format := mux.Vars(r)["format"]
+	var buf bytes.Buffer
+	err := tm.Export(&buf, format)
+	if err != nil {
+		http.Error(w, err.Error(), 400)
+		return
+	}
+
+	if format == ExportCSV {
+		w.Header().Set("Content-Type", "text/csv")
+	} else {
+		w.Header().Set("Content-Type", "application/json")
+	}
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", tm.ExportFilename(format)))
+	_, _ = w.Write(buf.Bytes())
+}
+
+// StationCountHandler sets how many matches can be played at once
+func (s *Server) StationCountHandler(w http.ResponseWriter, r *http.Request) {
+	var req StationCountRequest
+	tm := s.getTournament(r)
+	if tm == nil {
+		http.Error(w, "no such tournament", 404)
+		return
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.Print(err)
+		return
+	}
+
+	err = json.Unmarshal(body, &req)
+	if err != nil {
+		http.Error(w, err.Error(), 400)
+		return
+	}
+
+	err = tm.SetStationCount(req.Count)
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	s.redirect(w, tm.URL())
+}
+
+// StationHandler returns the judge view of a station
+func (s *Server) StationHandler(w http.ResponseWriter, r *http.Request) {
+	tm := s.getTournament(r)
+	if tm == nil {
+		http.Error(w, "no such tournament", 404)
+		return
+	}
+	n, err := strconv.Atoi(mux.Vars(r)["station"])
+	if err != nil {
+		http.Error(w, err.Error(), 400)
+		return
+	}
+
+	st, err := tm.GetStation(n)
+	if err != nil {
+		http.Error(w, err.Error(), 404)
+		return
+	}
+
+	data, err := json.Marshal(StationView{
+		Station: st,
+		Match:   tm.StationMatch(n),
+	})
+	if err != nil {
+		log.Print(err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(data)
+}
+
+// StationNextHandler puts the next playable match on a station
+func (s *Server) StationNextHandler(w http.ResponseWriter, r *http.Request) {
+	tm := s.getTournament(r)
+	if tm == nil {
+		http.Error(w, "no such tournament", 404)
+		return
+	}
+	n, err := strconv.Atoi(mux.Vars(r)["station"])
+	if err != nil {
+		http.Error(w, err.Error(), 400)
+		return
+	}
+
+	m, err := tm.NextStationMatch(n)
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	s.redirect(w, m.URL())
+}
+
+// MatchAssignHandler puts a match on a specific station
+func (s *Server) MatchAssignHandler(w http.ResponseWriter, r *http.Request) {
+	var req AssignRequest
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.Print(err)
+		return
+	}
+
+	err = json.Unmarshal(body, &req)
+	if err != nil {
+		http.Error(w, err.Error(), 400)
+		return
+	}
+
+	m := s.getMatch(r)
+	if m == nil {
+		http.Error(w, "no such match", 404)
+		return
+	}
+	err = m.Tournament.AssignMatch(m, req.Station)
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	s.redirect(w, m.URL())
+}
+
+// MatchCheckInHandler checks a player in to a match
+//
+// Players checking in from their phones are identified by their session if
+// no name is given.
+func (s *Server) MatchCheckInHandler(w http.ResponseWriter, r *http.Request) {
+	var req PlayerRequest
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.Print(err)
+		return
+	}
+
+	err = json.Unmarshal(body, &req)
+	if err != nil {
+		http.Error(w, err.Error(), 400)
+		return
+	}
+
+	m := s.getMatch(r)
+	if m == nil {
+		http.Error(w, "no such match", 404)
+		return
+	}
+	if req.Name == "" {
+		session, _ := store.Get(r, m.Tournament.Name)
+		if name, ok := session.Values["player"]; ok {
+			req.Name = name.(string)
+		}
+	}
+
+	err = m.CheckIn(req.Name)
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+	log.Printf("%s checked in to %s", req.Name, m.String())
+
+	s.redirect(w, m.URL())
+}
+
+// MatchNoShowHandler removes a player that did not show up from a match
+func (s *Server) MatchNoShowHandler(w http.ResponseWriter, r *http.Request) {
+	var req PlayerRequest
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.Print(err)
+		return
+	}
+
+	err = json.Unmarshal(body, &req)
+	if err != nil {
+		http.Error(w, err.Error(), 400)
+		return
+	}
+
+	m := s.getMatch(r)
+	if m == nil {
+		http.Error(w, "no such match", 404)
+		return
+	}
+	err = m.NoShow(req.Name)
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+	log.Printf("%s did not show up for %s", req.Name, m.String())
+
+	s.redirect(w, m.URL())
+}
+
+// MatchToggleHandler starts and stops matches
+func (s *Server) MatchToggleHandler(w http.ResponseWriter, r *http.Request) {
+	var err error
+	m := s.getMatch(r)
+	if m == nil {
+		http.Error(w, "no such match", 404)
+		return
+	}
+	if !m.IsStarted() {
+		log.Printf("%s started", m.String())
+		err = m.Start()
+	} else {
+		err = m.End()
+		if err == nil {
+			log.Printf("%s ended", m.String())
+		}
+	}
+
+	if err != nil {
+		log.Print(err)
+		http.Error(w, err.Error(), 400)
+		return
+	}
+
+	data, err := json.Marshal(UpdateMessage{
+		Tournament: m.Tournament,
+	})
+	if err != nil {
+		log.Print(err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(data)
+}
+
+// MatchCommitHandler commits a single round of a match
+func (s *Server) MatchCommitHandler(w http.ResponseWriter, r *http.Request) {
+	var req CommitRequest
+	// tm := s.getTournament(r)
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.Print(err)
+		return
+	}
+
+	err = json.Unmarshal(body, &req)
+	if err != nil {
+		log.Print(err)
+		return
+	}
+	log.Print(req)
+
+	m := s.getMatch(r)
+	if m == nil {
+		http.Error(w, "no such match", 404)
+		return
+	}
+	states := req.State
+	if len(states) != 4 {
+		http.Error(w, "need state for four players", 400)
+		return
+	}
+	scores := [][]int{
+		[]int{states[0].Ups, states[0].Downs},
+		[]int{states[1].Ups, states[1].Downs},
+		[]int{states[2].Ups, states[2].Downs},
+		[]int{states[3].Ups, states[3].Downs},
+	}
+	shots := []bool{
+		states[0].Shot,
+		states[1].Shot,
+		states[2].Shot,
+		states[3].Shot,
+	}
+
+	err = m.Commit(scores, shots)
+	if err != nil {
+		log.Print(err)
+		http.Error(w, err.Error(), 400)
+		return
+	}
+
+	data, err := json.Marshal(UpdateMatchMessage{
+		Match: m,
+	})
+	if err != nil {
+		log.Print(err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(data)
+
+	return
+}
+
+// MatchOverrideHandler lets an organizer force the state of a match
+func (s *Server) MatchOverrideHandler(w http.ResponseWriter, r *http.Request) {
+	var req OverrideRequest
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.Print(err)
+		return
+	}
+
+	err = json.Unmarshal(body, &req)
+	if err != nil {
+		http.Error(w, err.Error(), 400)
+		return
+	}
+
+	m := s.getMatch(r)
+	if m == nil {
+		http.Error(w, "no such match", 404)
+		return
+	}
+	err = m.Override(req.State, req.Reason)
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+	log.Printf("%s overridden to %s: %s", m.String(), req.State, req.Reason)
+
+	data, err := json.Marshal(UpdateMatchMessage{
+		Match: m,
+	})
+	if err != nil {
+		log.Print(err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(data)
+}
+
+// MatchPredictHandler lets a spectator predict the winner of a match
+//
+// Spectators are remembered by their session, so the name only has to be
+// given the first time.
+func (s *Server) MatchPredictHandler(w http.ResponseWriter, r *http.Request) {
+	var req PredictRequest
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.Print(err)
+		return
+	}
+
+	err = json.Unmarshal(body, &req)
+	if err != nil {
+		http.Error(w, err.Error(), 400)
+		return
+	}
+
+	m := s.getMatch(r)
+	if m == nil {
+		http.Error(w, "no such match", 404)
+		return
+	}
+	session, _ := store.Get(r, m.Tournament.Name)
+	if req.Spectator == "" {
+		if name, ok := session.Values["spectator"]; ok {
+			req.Spectator = name.(string)
+		}
+	}
+
+	err = m.Predict(req.Spectator, req.Winner)
+	if err != nil {
+		http.Error(w, err.Error(), 400)
+		return
+	}
+
+	session.Values["spectator"] = req.Spectator
+	session.Save(r, w)
+
+	m.Tournament.sendLeaderboard()
+	s.redirect(w, m.URL())
+}
+
+// WebhooksHandler lists the webhooks of a tournament, and adds one on a POST
+//
+// The secret of a new webhook is only in the response to the POST.
+func (s *Server) WebhooksHandler(w http.ResponseWriter, r *http.Request) {
+	tm := s.getTournament(r)
+	if tm == nil {
+		http.Error(w, "no such tournament", 404)
+		return
+	}
+
+	var out interface{}
+	if r.Method == "POST" {
+		var req WebhookRequest
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			log.Print(err)
+			return
+		}
+
+		err = json.Unmarshal(body, &req)
+		if err != nil {
+			http.Error(w, err.Error(), 400)
+			return
+		}
+
+		hook, err := s.DB.AddWebhook(tm, Webhook{URL: req.URL, Secret: req.Secret, Events: req.Events})
+		if err != nil {
+			http.Error(w, err.Error(), 400)
+			return
+		}
+		out = hook
+	} else {
+		hooks, err := s.DB.Webhooks(tm)
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
+		out = hooks
+	}
+
+	data, err := json.Marshal(out)
+	if err != nil {
+		log.Print(err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(data)
+}
+
+// WebhookHandler removes a webhook from a tournament on a DELETE
+func (s *Server) WebhookHandler(w http.ResponseWriter, r *http.Request) {
+	tm := s.getTournament(r)
+	if tm == nil {
+		http.Error(w, "no such tournament", 404)
+		return
+	}
+	if r.Method != "DELETE" {
+		http.Error(w, "remove webhooks with DELETE", 405)
+		return
+	}
+
+	err := s.DB.RemoveWebhook(tm, mux.Vars(r)["hook"])
+	if err != nil {
+		http.Error(w, err.Error(), 404)
+		return
+	}
+	s.redirect(w, tm.URL())
+}
+
+// DeliveriesHandler returns the webhook delivery log of a tournament
+func (s *Server) DeliveriesHandler(w http.ResponseWriter, r *http.Request) {
+	tm := s.getTournament(r)
+	if tm == nil {
+		http.Error(w, "no such tournament", 404)
+		return
+	}
+
+	ds, err := s.DB.Store.Deliveries(tm.ID)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+
+	data, err := json.Marshal(ds)
+	if err != nil {
+		log.Print(err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(data)
+}
+
+// LeaderboardHandler returns the spectator leaderboard of a tournament
+func (s *Server) LeaderboardHandler(w http.ResponseWriter, r *http.Request) {
+	tm := s.getTournament(r)
+	if tm == nil {
+		http.Error(w, "no such tournament", 404)
+		return
+	}
+
+	data, err := json.Marshal(tm.Leaderboard())
+	if err != nil {
+		log.Print(err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(data)
+}
+
+// MatchTimelineHandler returns how a match unfolded, round by round
+func (s *Server) MatchTimelineHandler(w http.ResponseWriter, r *http.Request) {
+	m := s.getMatch(r)
+	if m == nil {
+		http.Error(w, "no such match", 404)
+		return
+	}
+
+	data, err := json.Marshal(m.Timeline())
+	if err != nil {
+		log.Print(err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(data)
+}
+
+// MatchReplayHandler replays the rounds of a match to the websocket, so that
+// the big screen can show how it unfolded
+func (s *Server) MatchReplayHandler(w http.ResponseWriter, r *http.Request) {
+	var req ReplayRequest
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.Print(err)
+		return
+	}
+
+	// An empty body replays with the default interval
+	if len(body) != 0 {
+		if err := json.Unmarshal(body, &req); err != nil {
+			http.Error(w, err.Error(), 400)
+			return
+		}
+	}
+
+	m := s.getMatch(r)
+	if m == nil {
+		http.Error(w, "no such match", 404)
+		return
+	}
+	if len(m.Rounds) == 0 {
+		http.Error(w, "no rounds to replay", 400)
+		return
+	}
+
+	interval := DefaultReplayInterval
+	if req.Interval > 0 {
+		interval = time.Duration(req.Interval) * time.Millisecond
+	}
+
+	// The timeline is built here, since commits change the rounds while the
+	// replay runs
+	go s.Replay(m.Timeline(), interval)
+	s.redirect(w, m.URL())
+}
+
+// MatchTieHandler resolves a tie pending match with a sudden death winner
+func (s *Server) MatchTieHandler(w http.ResponseWriter, r *http.Request) {
+	var req TieRequest
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.Print(err)
+		return
+	}
+
+	err = json.Unmarshal(body, &req)
+	if err != nil {
+		http.Error(w, err.Error(), 400)
+		return
+	}
+
+	m := s.getMatch(r)
+	if m == nil {
+		http.Error(w, "no such match", 404)
+		return
+	}
+	err = m.ResolveTie(req.Winner)
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+	log.Printf("%s won the sudden death in %s", req.Winner, m.String())
+
+	data, err := json.Marshal(UpdateMatchMessage{
+		Match: m,
+	})
+	if err != nil {
+		log.Print(err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(data)
+}
+
+// TournamentListHandler returns a page of the tournaments, filtered by the
+// query of the request
+func (s *Server) TournamentListHandler(w http.ResponseWriter, r *http.Request) {
+	f, err := ParseListFilter(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), 400)
+		return
+	}
+
+	page, err := s.DB.List(f)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+
+	data, err := json.Marshal(page)
+	if err != nil {
+		log.Fatal(err)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+// StateHandler returns the loaded tournaments in full, as the websocket
+// updates send them
+func (s *Server) StateHandler(w http.ResponseWriter, r *http.Request) {
+	data, err := json.Marshal(UpdateStateMessage{Tournaments: s.DB.loaded()})
+	if err != nil {
+		log.Fatal(err)
 	}
 	w.Header().Set("Content-Type", "application/json")
 	w.Write(data)
@@ -306,18 +1523,53 @@ func (s *Server) BuildRouter(ws *websockets.Server) http.Handler {
 	r := n.PathPrefix("/api/towerfall").Subrouter()
 
 	r.HandleFunc("/tournament/", s.TournamentListHandler)
+	r.HandleFunc("/state/", s.StateHandler)
 	r.HandleFunc("/tournament/{id}/", s.TournamentHandler)
 	r.HandleFunc("/new/", s.NewHandler)
-	r.HandleFunc("/{id}/start/", s.StartTournamentHandler)
-	r.HandleFunc("/{id}/join/", s.JoinHandler)
+	r.HandleFunc("/import/", s.admin(s.ImportHandler))
+	r.HandleFunc("/admin/backups/", s.admin(s.BackupHandler))
+	r.HandleFunc("/admin/query/", s.admin(s.QueryHandler))
+	r.HandleFunc("/players/", s.PlayersHandler)
+	r.HandleFunc("/archers/", s.ArchersHandler)
+	r.HandleFunc("/achievements/", s.AchievementsHandler)
+	r.HandleFunc("/trash/{id}/", s.admin(s.locked(s.TrashHandler)))
+	r.HandleFunc("/{id}/start/", s.locked(s.StartTournamentHandler))
+	r.HandleFunc("/{id}/join/", s.locked(s.JoinHandler))
+	r.HandleFunc("/{id}/join-team/", s.locked(s.TeamJoinHandler))
+	r.HandleFunc("/{id}/late/", s.locked(s.LateJoinHandler))
+	r.HandleFunc("/{id}/withdraw/", s.locked(s.WithdrawHandler))
+	r.HandleFunc("/{id}/colors/", s.locked(s.ColorsHandler))
+	r.HandleFunc("/{id}/rename/", s.admin(s.locked(s.RenameHandler)))
+	r.HandleFunc("/{id}/clone/", s.admin(s.locked(s.CloneHandler)))
+	r.HandleFunc("/{id}/delete/", s.admin(s.locked(s.DeleteHandler)))
 	r.HandleFunc("/{id}/next/", s.NextHandler)
+	r.HandleFunc("/{id}/schedule/", s.locked(s.ScheduleHandler))
+	r.HandleFunc("/{id}/bracket/", s.locked(s.BracketHandler))
+	r.HandleFunc("/{id}/events/", s.EventsHandler)
+	r.HandleFunc("/{id}/leaderboard/", s.LeaderboardHandler)
+	r.HandleFunc("/{id}/webhooks/", s.admin(s.locked(s.WebhooksHandler)))
+	r.HandleFunc("/{id}/webhooks/{hook}/", s.admin(s.locked(s.WebhookHandler)))
+	r.HandleFunc("/{id}/deliveries/", s.admin(s.DeliveriesHandler))
+	r.HandleFunc("/{id}/export/{format}/", s.ExportHandler)
+	r.HandleFunc("/{id}/{image:(?:bracket|scoreboard)\\.(?:svg|png)}", s.locked(s.OverlayHandler))
+	r.HandleFunc("/{id}/stations/", s.locked(s.StationCountHandler))
+	r.HandleFunc("/{id}/station/{station:[0-9]+}/", s.locked(s.StationHandler))
+	r.HandleFunc("/{id}/station/{station:[0-9]+}/next/", s.locked(s.StationNextHandler))
 
 	// Install the websockets
 	r.Handle("/auto-updater", websocket.Handler(ws.OnConnected))
 
 	m := r.PathPrefix("/tournament/{id}/{kind:(tryout|runnerup|semi|final)}/{index:[0-9]+}").Subrouter()
-	m.HandleFunc("/toggle/", s.MatchToggleHandler)
-	m.HandleFunc("/commit/", s.MatchCommitHandler)
+	m.HandleFunc("/toggle/", s.locked(s.MatchToggleHandler))
+	m.HandleFunc("/commit/", s.locked(s.MatchCommitHandler))
+	m.HandleFunc("/tie/", s.locked(s.MatchTieHandler))
+	m.HandleFunc("/override/", s.locked(s.MatchOverrideHandler))
+	m.HandleFunc("/assign/", s.locked(s.MatchAssignHandler))
+	m.HandleFunc("/checkin/", s.locked(s.MatchCheckInHandler))
+	m.HandleFunc("/noshow/", s.locked(s.MatchNoShowHandler))
+	m.HandleFunc("/timeline/", s.locked(s.MatchTimelineHandler))
+	m.HandleFunc("/replay/", s.locked(s.MatchReplayHandler))
+	m.HandleFunc("/predict/", s.locked(s.MatchPredictHandler))
 
 	return n
 }
@@ -332,38 +1584,123 @@ func (s *Server) Serve() error {
 func (s *Server) SendWebsocketUpdate() {
 	msg := websockets.Message{
 		Data: UpdateStateMessage{
-			Tournaments: s.DB.Tournaments,
+			Tournaments: s.DB.loaded(),
 		},
 	}
 
 	s.ws.SendAll(&msg)
 }
 
+// getMatch returns the match of the request, or nil if there is no such
+// match
+//
+// The runner-up rounds do not exist until the tryouts are done. Matches of
+// archived tournaments are found too, but cannot be changed.
 func (s *Server) getMatch(r *http.Request) *Match {
-	var m *Match
 	vars := mux.Vars(r)
 
-	tm := s.DB.tournamentRef[vars["id"]]
-	kind := vars["kind"]
-	index, _ := strconv.Atoi(vars["index"])
+	tm, err := s.DB.Get(vars["id"])
+	if err != nil {
+		return nil
+	}
+
+	index, err := strconv.Atoi(vars["index"])
+	if err != nil || index < 0 {
+		return nil
+	}
 
-	if kind == "tryout" {
-		m = tm.Tryouts[index]
-	} else if kind == "semi" {
-		m = tm.Semis[index]
-	} else if kind == "final" {
-		m = tm.Final
+	var ms []*Match
+	switch vars["kind"] {
+	case "tryout":
+		ms = tm.Tryouts
+	case "runnerup":
+		ms = tm.RunnerupRounds
+	case "semi":
+		ms = tm.Semis
+	case "final":
+		ms = []*Match{tm.Final}
 	}
 
-	return m
+	if index >= len(ms) {
+		return nil
+	}
+	return ms[index]
 }
 
+// getTournament returns the tournament of the request, or nil if there is
+// no such tournament
 func (s *Server) getTournament(r *http.Request) *Tournament {
 	vars := mux.Vars(r)
-	tm := s.DB.tournamentRef[vars["id"]]
+	tm, err := s.DB.Get(vars["id"])
+	if err != nil {
+		return nil
+	}
 	return tm
 }
 
+// admin runs a handler that only the organizers can use
+//
+// The request needs the admin token as a bearer token in the Authorization
+// header. If no token is set, the admin routes are turned off.
+func (s *Server) admin(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.adminToken == "" {
+			http.Error(w, "admin routes are turned off", 403)
+			return
+		}
+
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(s.adminToken)) != 1 {
+			http.Error(w, "need the admin token", 401)
+			return
+		}
+		h(w, r)
+	}
+}
+
+// locked runs a handler that changes a tournament while holding the lock of
+// that tournament
+//
+// With several stations, judges commit to the same tournament at the same
+// time. The handlers that change it take turns so that they do not trample
+// each other, and the ones that read the rounds wait for whole commits.
+func (s *Server) locked(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		l := s.tournamentLock(mux.Vars(r)["id"])
+		l.Lock()
+		defer l.Unlock()
+		h(w, r)
+	}
+}
+
+// tournamentLock returns the lock of a tournament
+func (s *Server) tournamentLock(id string) *sync.Mutex {
+	s.locksMu.Lock()
+	defer s.locksMu.Unlock()
+
+	if s.locks == nil {
+		s.locks = make(map[string]*sync.Mutex)
+	}
+	l, ok := s.locks[id]
+	if !ok {
+		l = &sync.Mutex{}
+		s.locks[id] = l
+	}
+	return l
+}
+
+// errorStatus returns the status to answer a failed change with
+//
+// A tournament that could not be saved is on the server. Anything else is a
+// change that the rules do not allow.
+func errorStatus(err error) int {
+	var pe *PersistError
+	if errors.As(err, &pe) {
+		return 500
+	}
+	return 400
+}
+
 // redirect creates a JSON redirect
 func (s *Server) redirect(w http.ResponseWriter, url string) {
 	data, err := json.Marshal(JSONMessage{
@@ -378,7 +1715,14 @@ func (s *Server) redirect(w http.ResponseWriter, url string) {
 }
 
 func main() {
-	db, err := NewDatabase("production.db")
+	if len(os.Args) > 1 {
+		if err := runCommand(os.Args[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	db, err := NewDatabase(DatabaseSpec())
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -391,6 +1735,13 @@ func main() {
 		log.Fatal(err)
 	}
 
+	if _, err := db.ArchiveEnded(DefaultArchiveAge); err != nil {
+		log.Printf("Archiving tournaments failed: %s", err)
+	}
+
+	s.backups = NewBackups(db, DefaultBackupDir)
+	go s.backups.Run(nil)
+
 	s.Serve()
 
 	if err != nil {
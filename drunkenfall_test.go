@@ -1,5 +1,15 @@
 package main
 
+import (
+	"errors"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
 // import (
 // 	"github.com/stretchr/testify/assert"
 // 	"io/ioutil"
@@ -27,3 +37,163 @@ package main
 // 	assert.Nil(err)
 // 	assert.True(strings.Contains(string(html), "<!doctype html>"))
 // }
+
+// serve runs a request through a handler routed on the pattern, and returns
+// the recorded response
+func serve(h http.HandlerFunc, pattern, method, path, body string) *httptest.ResponseRecorder {
+	r := mux.NewRouter()
+	r.HandleFunc(pattern, h)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(method, path, strings.NewReader(body)))
+	return w
+}
+
+func TestUnknownTournamentIsNotFound(t *testing.T) {
+	assert := assert.New(t)
+	s := testServer(MockDatabase())
+
+	for name, h := range map[string]http.HandlerFunc{
+		"join":            s.JoinHandler,
+		"team join":       s.TeamJoinHandler,
+		"start":           s.StartTournamentHandler,
+		"next":            s.NextHandler,
+		"schedule":        s.ScheduleHandler,
+		"stations":        s.StationCountHandler,
+		"station":         s.StationHandler,
+		"next on station": s.StationNextHandler,
+		"late join":       s.LateJoinHandler,
+		"withdraw":        s.WithdrawHandler,
+		"bracket":         s.BracketHandler,
+	} {
+		w := serve(h, "/{id}/", "POST", "/nope/", `{"name": "x", "color": "green"}`)
+		assert.Equal(404, w.Code, name)
+	}
+}
+
+func TestJoinWithBadBody(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTournament(8)
+	tm.db.list(tm)
+
+	w := serve(tm.server.JoinHandler, "/{id}/", "POST", "/"+tm.ID+"/", "{")
+	assert.Equal(400, w.Code)
+}
+
+func TestErrorStatus(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(400, errorStatus(errors.New("not allowed")))
+	assert.Equal(500, errorStatus(&PersistError{errors.New("disk full")}))
+}
+
+func TestTieHandlerRefusesBadRequests(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTournament(8)
+	tm.db.list(tm)
+	assert.Nil(tm.StartTournament())
+	s := tm.server
+	path := "/" + tm.ID + "/tryout/0/"
+
+	w := serve(s.MatchTieHandler, "/{id}/{kind}/{index}/", "POST", path, "{")
+	assert.Equal(400, w.Code)
+
+	// There is no tie to resolve
+	w = serve(s.MatchTieHandler, "/{id}/{kind}/{index}/", "POST", path, `{"winner": "x"}`)
+	assert.Equal(400, w.Code)
+}
+
+func TestOverrideHandlerRefusesBadRequests(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTournament(8)
+	tm.db.list(tm)
+	assert.Nil(tm.StartTournament())
+	s := tm.server
+	path := "/" + tm.ID + "/tryout/0/"
+
+	w := serve(s.MatchOverrideHandler, "/{id}/{kind}/{index}/", "POST", path, "{")
+	assert.Equal(400, w.Code)
+
+	// A match that has not been played cannot be ended
+	w = serve(s.MatchOverrideHandler, "/{id}/{kind}/{index}/", "POST", path, `{"state": "ended", "reason": "x"}`)
+	assert.Equal(400, w.Code)
+}
+
+func TestCheckInHandlersRefuseBadRequests(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTournament(8)
+	tm.db.list(tm)
+	tm.RequireCheckIn = true
+	assert.Nil(tm.StartTournament())
+	s := tm.server
+	path := "/" + tm.ID + "/tryout/0/"
+
+	for _, h := range []http.HandlerFunc{s.MatchCheckInHandler, s.MatchNoShowHandler} {
+		w := serve(h, "/{id}/{kind}/{index}/", "POST", path, "{")
+		assert.Equal(400, w.Code)
+
+		w = serve(h, "/{id}/{kind}/{index}/", "POST", path, `{"name": "nobody"}`)
+		assert.Equal(400, w.Code)
+	}
+}
+
+func TestStationCountHandlerRefusesBadBody(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTournament(8)
+	tm.db.list(tm)
+
+	w := serve(tm.server.StationCountHandler, "/{id}/", "POST", "/"+tm.ID+"/", "{")
+	assert.Equal(400, w.Code)
+	w = serve(tm.server.StationCountHandler, "/{id}/", "POST", "/"+tm.ID+"/", `{"count": 0}`)
+	assert.Equal(400, w.Code)
+}
+
+func TestLateJoinHandlerRefusesBadRequests(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTournament(8)
+	tm.db.list(tm)
+	s := tm.server
+	path := "/" + tm.ID + "/"
+
+	w := serve(s.LateJoinHandler, "/{id}/", "POST", path, "{")
+	assert.Equal(400, w.Code)
+	w = serve(s.LateJoinHandler, "/{id}/", "POST", path, `{"name": "late"}`)
+	assert.Equal(400, w.Code)
+
+	// The tournament is not running yet
+	w = serve(s.LateJoinHandler, "/{id}/", "POST", path, `{"name": "late", "color": "green"}`)
+	assert.Equal(400, w.Code)
+
+	w = serve(s.WithdrawHandler, "/{id}/", "POST", path, "{")
+	assert.Equal(400, w.Code)
+}
+
+func TestImportHandlerNeedsAdminToken(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTournament(8)
+	tm.db.list(tm)
+	s := tm.server
+	s.adminToken = "s3cret"
+
+	w := serve(s.admin(s.ImportHandler), "/import/", "POST", "/import/?overwrite=true", string(exportJSON(tm)))
+	assert.Equal(401, w.Code)
+	assert.Equal(1, len(s.DB.Tournaments))
+	assert.Equal(tm, s.DB.Tournaments[0])
+}
+
+func TestTrashHandlersNeedAdminToken(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTournament(8)
+	tm.db.list(tm)
+	s := tm.server
+	s.adminToken = "s3cret"
+
+	w := serve(s.admin(s.locked(s.DeleteHandler)), "/{id}/delete/", "POST", "/"+tm.ID+"/delete/", "")
+	assert.Equal(401, w.Code)
+	assert.False(tm.IsDeleted())
+
+	assert.Nil(s.DB.Trash(tm))
+	w = serve(s.admin(s.locked(s.TrashHandler)), "/trash/{id}/", "DELETE", "/trash/"+tm.ID+"/", "")
+	assert.Equal(401, w.Code)
+	_, ok := s.DB.trashed(tm.ID)
+	assert.True(ok)
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// ArchiveVersion is the version of the archive format written by exports
+const ArchiveVersion = 1
+
+// Export formats
+const (
+	// ExportJSON is a full archive of the tournament
+	ExportJSON = "json"
+	// ExportCSV is a table with a row per player per match
+	ExportCSV = "csv"
+	// ExportChallonge is the tournament in the shape of the Challonge API
+	ExportChallonge = "challonge"
+)
+
+// ExportFormats are all the formats that a tournament can be exported to
+var ExportFormats = []string{ExportJSON, ExportCSV, ExportChallonge}
+
+// Archive is a complete, standalone copy of a tournament
+type Archive struct {
+	Version    int         `json:"version"`
+	Exported   time.Time   `json:"exported"`
+	Tournament *Tournament `json:"tournament"`
+}
+
+// ChallongeTournament is a tournament as represented by Challonge
+//
+// Challonge only has two player matches, so only the participants and how
+// they placed are exported. That is enough to import the results.
+type ChallongeTournament struct {
+	Tournament struct {
+		Name              string                 `json:"name"`
+		URL               string                 `json:"url"`
+		TournamentType    string                 `json:"tournament_type"`
+		State             string                 `json:"state"`
+		StartedAt         *time.Time             `json:"started_at"`
+		CompletedAt       *time.Time             `json:"completed_at"`
+		ParticipantsCount int                    `json:"participants_count"`
+		Participants      []ChallongeParticipant `json:"participants"`
+	} `json:"tournament"`
+}
+
+// ChallongeParticipant is a player as represented by Challonge
+type ChallongeParticipant struct {
+	Participant struct {
+		ID        int    `json:"id"`
+		Name      string `json:"name"`
+		Seed      int    `json:"seed"`
+		FinalRank *int   `json:"final_rank"`
+	} `json:"participant"`
+}
+
+// Export writes the tournament out in the given format
+func (t *Tournament) Export(w io.Writer, format string) error {
+	switch format {
+	case ExportJSON:
+		return t.ExportJSON(w)
+	case ExportCSV:
+		return t.ExportCSV(w)
+	case ExportChallonge:
+		return t.ExportChallonge(w)
+	}
+	return fmt.Errorf("unknown export format '%s'", format)
+}
+
+// ExportJSON writes a full archive of the tournament
+func (t *Tournament) ExportJSON(w io.Writer) error {
+	a := Archive{
+		Version:    ArchiveVersion,
+		Exported:   time.Now(),
+		Tournament: t,
+	}
+
+	data, err := json.MarshalIndent(a, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// ExportCSV writes the stats of every player in every played match
+func (t *Tournament) ExportCSV(w io.Writer) error {
+	c := csv.NewWriter(w)
+	err := c.Write([]string{
+		"tournament", "match", "kind", "index", "state", "started", "ended",
+		"player", "team", "color", "placement",
+		"kills", "self", "sweeps", "explosions", "shots",
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, m := range t.Matches() {
+		if !m.IsStarted() {
+			continue
+		}
+
+		placement := make(map[string]int)
+		if m.IsEnded() {
+			for i, p := range m.Standings() {
+				placement[p.Name] = i + 1
+			}
+		}
+
+		for _, p := range m.Players {
+			if p.IsPrefill() {
+				continue
+			}
+
+			err := c.Write([]string{
+				t.ID,
+				m.Title(),
+				m.Kind,
+				strconv.Itoa(m.Index),
+				m.getState(),
+				formatTime(m.Started),
+				formatTime(m.Ended),
+				p.Name,
+				p.Team,
+				p.PreferredColor,
+				strconv.Itoa(placement[p.Name]),
+				strconv.Itoa(p.Kills),
+				strconv.Itoa(p.Self),
+				strconv.Itoa(p.Sweeps),
+				strconv.Itoa(p.Explosions),
+				strconv.Itoa(p.Shots),
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	c.Flush()
+	return c.Error()
+}
+
+// ExportChallonge writes the tournament in the format of the Challonge API
+func (t *Tournament) ExportChallonge(w io.Writer) error {
+	var ct ChallongeTournament
+	ct.Tournament.Name = t.Name
+	ct.Tournament.URL = t.ID
+	ct.Tournament.TournamentType = "free for all"
+	ct.Tournament.ParticipantsCount = len(t.Players)
+
+	ct.Tournament.State = "pending"
+	if !t.Started.IsZero() {
+		ct.Tournament.State = "underway"
+		ct.Tournament.StartedAt = &t.Started
+	}
+	if !t.Ended.IsZero() {
+		ct.Tournament.State = "complete"
+		ct.Tournament.CompletedAt = &t.Ended
+	}
+
+	ranks := make(map[string]int)
+	if !t.Ended.IsZero() {
+		for i, p := range t.Ranking() {
+			ranks[p.Name] = i + 1
+		}
+	}
+
+	for i, p := range t.Players {
+		var cp ChallongeParticipant
+		cp.Participant.ID = i + 1
+		cp.Participant.Name = p.Name
+		cp.Participant.Seed = i + 1
+		if r, ok := ranks[p.Name]; ok {
+			cp.Participant.FinalRank = &r
+		}
+		ct.Tournament.Participants = append(ct.Tournament.Participants, cp)
+	}
+
+	data, err := json.MarshalIndent(ct, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// Ranking returns all the players in the order they placed
+//
+// The finalists are ranked by the final, then the players that made it to the
+// semis, and then everyone else. Within those groups, the total score
+// decides. The scores are added up on copies of the players, so the
+// tournament is left as it is.
+func (t *Tournament) Ranking() []Player {
+	players := t.playerTotals()
+	totals := make(map[string]Player, len(players))
+	for _, p := range players {
+		totals[p.Name] = p
+	}
+
+	out := make([]Player, 0, len(players))
+	seen := make(map[string]bool)
+	add := func(ps []Player) {
+		for _, p := range SortByScore(ps) {
+			if _, ok := totals[p.Name]; ok && !seen[p.Name] {
+				seen[p.Name] = true
+				out = append(out, totals[p.Name])
+			}
+		}
+	}
+
+	if t.Final.IsEnded() {
+		if t.IsTeamMode() {
+			for _, ts := range t.Final.TeamStandings() {
+				for _, p := range ts.Players {
+					add([]Player{p})
+				}
+			}
+		} else {
+			for _, p := range t.Final.Standings() {
+				add([]Player{p})
+			}
+		}
+	}
+
+	semis := []Player{}
+	for _, m := range t.Semis {
+		for _, p := range m.Players {
+			if total, ok := totals[p.Name]; ok && !p.IsPrefill() {
+				semis = append(semis, total)
+			}
+		}
+	}
+	add(semis)
+	add(players)
+
+	return out
+}
+
+// ExportFilename returns the name that an export is saved as
+func (t *Tournament) ExportFilename(format string) string {
+	switch format {
+	case ExportCSV:
+		return t.ID + ".csv"
+	case ExportChallonge:
+		return t.ID + "-challonge.json"
+	}
+	return t.ID + ".json"
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func playTournament(tm *Tournament) {
+	tm.StartTournament()
+	endTryouts(tm)
+	endSemis(tm)
+	playMatch(tm.Final)
+}
+
+func TestExportJSONIsAFullArchive(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTournament(16)
+	tm.StartTournament()
+	playMatch(tm.Tryouts[0])
+
+	var buf bytes.Buffer
+	assert.Nil(tm.Export(&buf, ExportJSON))
+
+	var a struct {
+		Version    int             `json:"version"`
+		Tournament json.RawMessage `json:"tournament"`
+	}
+	assert.Nil(json.Unmarshal(buf.Bytes(), &a))
+	assert.Equal(ArchiveVersion, a.Version)
+
+	ct, err := LoadTournament(a.Tournament, tm.db)
+	assert.Nil(err)
+	assert.Equal(tm.ID, ct.ID)
+	assert.Equal(16, len(ct.Players))
+	assert.True(ct.Tryouts[0].IsEnded())
+	assert.Equal(tm.Runnerups, ct.Runnerups)
+}
+
+func TestExportCSVHasARowPerPlayerPerPlayedMatch(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTournament(16)
+	tm.StartTournament()
+	playMatch(tm.Tryouts[0])
+	playMatch(tm.Tryouts[1])
+
+	var buf bytes.Buffer
+	assert.Nil(tm.Export(&buf, ExportCSV))
+
+	rows, err := csv.NewReader(&buf).ReadAll()
+	assert.Nil(err)
+	assert.Equal(1+2*4, len(rows))
+	assert.Equal("kills", rows[0][11])
+
+	winner := tm.Tryouts[0].Standings()[0]
+	assert.Equal(tm.ID, rows[1][0])
+	assert.Equal("tryout", rows[1][2])
+	assert.Equal("ended", rows[1][4])
+	assert.Equal(winner.Name, rows[1][7])
+	assert.Equal("1", rows[1][10])
+	assert.Equal("10", rows[1][11])
+	assert.Equal("1", rows[1][15])
+}
+
+func TestExportChallonge(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTournament(16)
+	playTournament(tm)
+
+	var buf bytes.Buffer
+	assert.Nil(tm.Export(&buf, ExportChallonge))
+
+	var ct ChallongeTournament
+	assert.Nil(json.Unmarshal(buf.Bytes(), &ct))
+	assert.Equal("complete", ct.Tournament.State)
+	assert.Equal(16, len(ct.Tournament.Participants))
+
+	ranks := make(map[string]int)
+	for _, p := range ct.Tournament.Participants {
+		assert.NotNil(p.Participant.FinalRank)
+		ranks[p.Participant.Name] = *p.Participant.FinalRank
+	}
+	assert.Equal(1, ranks[tm.Winners[0].Name])
+	assert.Equal(2, ranks[tm.Winners[1].Name])
+	assert.Equal(3, ranks[tm.Winners[2].Name])
+}
+
+func TestExportChallongeWithoutRanksBeforeTheEnd(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTournament(8)
+
+	var buf bytes.Buffer
+	assert.Nil(tm.Export(&buf, ExportChallonge))
+
+	var ct ChallongeTournament
+	assert.Nil(json.Unmarshal(buf.Bytes(), &ct))
+	assert.Equal("pending", ct.Tournament.State)
+	assert.Nil(ct.Tournament.Participants[0].Participant.FinalRank)
+}
+
+func TestRankingPutsSemifinalistsBeforeTheRest(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTournament(16)
+	playTournament(tm)
+
+	rs := tm.Ranking()
+	assert.Equal(16, len(rs))
+	for _, p := range rs[:8] {
+		inSemis := tm.Semis[0].getPlayer(p.Name) != nil || tm.Semis[1].getPlayer(p.Name) != nil
+		assert.True(inSemis)
+	}
+}
+
+func TestRankingLeavesPlayersAlone(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTournament(16)
+	playTournament(tm)
+
+	for i := range tm.Players {
+		tm.Players[i].Reset()
+	}
+	// A player that only shows up in a match is skipped
+	tm.Tryouts[0].Players[3].Name = "ghost"
+
+	rs := tm.Ranking()
+	assert.Equal(16, len(rs))
+	assert.NotEqual(0, rs[0].Kills)
+	for _, p := range tm.Players {
+		assert.Equal(0, p.Kills)
+		assert.Equal(0, p.Matches)
+	}
+}
+
+func TestExportUnknownFormatFails(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTournament(8)
+
+	var buf bytes.Buffer
+	assert.NotNil(tm.Export(&buf, "xml"))
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+)
+
+// ImportOptions decide what happens when an imported tournament has the
+// same ID as one that already exists
+//
+// By default, the imported tournament gets a new ID. With Overwrite, it
+// replaces the existing one instead, while holding the lock of that
+// tournament so that no judge is changing it at the same time. ID imports the
+// tournament under another ID altogether.
+type ImportOptions struct {
+	ID        string
+	Overwrite bool
+}
+
+// ImportTournament loads a tournament archive into the database
+//
+// Both the archives written by ExportJSON and the plain tournament JSON that
+// is stored in the database are accepted.
+func (d *Database) ImportTournament(data []byte, opts ImportOptions) (*Tournament, error) {
+	raw, err := unwrapArchive(data)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err = Migrate(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	check := Tournament{}
+	if err := json.Unmarshal(raw, &check); err != nil {
+		return nil, fmt.Errorf("not a tournament: %s", err)
+	}
+	if err := check.validate(); err != nil {
+		return nil, fmt.Errorf("invalid tournament: %s", err)
+	}
+
+	t, err := LoadTournament(raw, d)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.ID != "" {
+		if err := ValidateID(opts.ID); err != nil {
+			return nil, err
+		}
+		t.ID = opts.ID
+	}
+	if d.exists(t.ID) && !opts.Overwrite {
+		t.ID = d.freeID(t.ID)
+	}
+
+	if d.Server != nil {
+		l := d.Server.tournamentLock(t.ID)
+		l.Lock()
+		defer l.Unlock()
+	}
+
+	if err := d.Persist(t); err != nil {
+		return nil, err
+	}
+
+	d.list(t)
+	d.fromTrash(t.ID)
+
+	if d.Server != nil {
+		go d.Server.SendWebsocketUpdate()
+	}
+	log.Printf("Imported tournament %s as %s", t.Name, t.ID)
+	return t, nil
+}
+
+// unwrapArchive returns the tournament JSON inside of an archive
+//
+// Data that is not an archive is assumed to be a tournament as it is stored
+// in the database.
+func unwrapArchive(data []byte) ([]byte, error) {
+	var a struct {
+		Version    int             `json:"version"`
+		Tournament json.RawMessage `json:"tournament"`
+	}
+	if err := json.Unmarshal(data, &a); err != nil {
+		return nil, fmt.Errorf("not JSON: %s", err)
+	}
+
+	if a.Tournament == nil {
+		return data, nil
+	}
+	if a.Version > ArchiveVersion {
+		return nil, fmt.Errorf("archive version %d is newer than the supported %d", a.Version, ArchiveVersion)
+	}
+	return a.Tournament, nil
+}
+
+// freeID returns the first of id-2, id-3 and so on that is not taken
+func (d *Database) freeID(id string) string {
+	for i := 2; ; i++ {
+		n := fmt.Sprintf("%s-%d", id, i)
+		if !d.exists(n) {
+			return n
+		}
+	}
+}
+
+// validate checks that a tournament is complete and refers to its players
+// consistently
+func (t *Tournament) validate() error {
+	if t.ID == "" {
+		return errors.New("no id")
+	}
+	if t.Name == "" {
+		return errors.New("no name")
+	}
+	if t.Mode != "" && t.Mode != SoloMode && t.Mode != TeamMode {
+		return fmt.Errorf("unknown mode '%s'", t.Mode)
+	}
+	if len(t.Tryouts) != 4 && len(t.Tryouts) != 8 {
+		return fmt.Errorf("expected 4 or 8 tryouts, got %d", len(t.Tryouts))
+	}
+	if len(t.Semis) != 2 {
+		return fmt.Errorf("expected 2 semis, got %d", len(t.Semis))
+	}
+	if t.Final == nil {
+		return errors.New("no final")
+	}
+
+	players := make(map[string]bool)
+	for _, p := range t.Players {
+		if p.Name == "" {
+			return errors.New("player without a name")
+		}
+		if players[p.Name] {
+			return fmt.Errorf("duplicate player '%s'", p.Name)
+		}
+		players[p.Name] = true
+	}
+
+	ms := append(append(append([]*Match{}, t.Tryouts...), t.RunnerupRounds...), t.Semis...)
+	ms = append(ms, t.Final)
+	for _, m := range ms {
+		if m == nil {
+			return errors.New("missing match")
+		}
+		if len(m.Players) > 4 {
+			return fmt.Errorf("%s %d has %d players", m.Kind, m.Index, len(m.Players))
+		}
+		for _, p := range m.Players {
+			if !p.IsPrefill() && !players[p.Name] {
+				return fmt.Errorf("%s %d has unknown player '%s'", m.Kind, m.Index, p.Name)
+			}
+		}
+	}
+
+	for _, r := range t.Runnerups {
+		if !players[r] {
+			return fmt.Errorf("unknown runnerup '%s'", r)
+		}
+	}
+	for _, team := range t.Teams {
+		for _, name := range team.Members {
+			if !players[name] {
+				return fmt.Errorf("team %s has unknown player '%s'", team.Name, name)
+			}
+		}
+	}
+	return nil
+}
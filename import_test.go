@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func exportJSON(tm *Tournament) []byte {
+	var buf bytes.Buffer
+	tm.ExportJSON(&buf)
+	return buf.Bytes()
+}
+
+func TestImportArchive(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTournament(16)
+	tm.StartTournament()
+	playMatch(tm.Tryouts[0])
+	data := exportJSON(tm)
+
+	db := MockDatabase()
+	testServer(db)
+	it, err := db.ImportTournament(data, ImportOptions{})
+	assert.Nil(err)
+
+	assert.Equal(tm.ID, it.ID)
+	assert.Equal(it, db.tournamentRef[tm.ID])
+	assert.Equal(1, len(db.Tournaments))
+
+	// The pointers are set up so the tournament can be played on
+	assert.Equal(it, it.Tryouts[1].Tournament)
+	assert.Equal(it.Tryouts[1], it.Tryouts[1].Players[0].Match)
+	assert.Nil(it.Tryouts[1].Start())
+}
+
+func TestImportLegacyBlob(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTournament(8)
+	data, _ := tm.JSON()
+
+	db := MockDatabase()
+	it, err := db.ImportTournament(data, ImportOptions{})
+	assert.Nil(err)
+	assert.Equal(8, len(it.Players))
+}
+
+func TestImportConflictingIDGetsNewID(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTournament(8)
+	tm.db.list(tm)
+	data := exportJSON(tm)
+
+	it, err := tm.db.ImportTournament(data, ImportOptions{})
+	assert.Nil(err)
+	assert.Equal(tm.ID+"-2", it.ID)
+
+	it, err = tm.db.ImportTournament(data, ImportOptions{})
+	assert.Nil(err)
+	assert.Equal(tm.ID+"-3", it.ID)
+}
+
+func TestImportOverwrite(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTournament(8)
+	tm.db.list(tm)
+	data := exportJSON(tm)
+
+	it, err := tm.db.ImportTournament(data, ImportOptions{Overwrite: true})
+	assert.Nil(err)
+	assert.Equal(tm.ID, it.ID)
+	assert.Equal(1, len(tm.db.Tournaments))
+	assert.Equal(it, tm.db.Tournaments[0])
+}
+
+func TestImportOverwriteWaitsForTournamentLock(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTournament(8)
+	tm.db.list(tm)
+	data := exportJSON(tm)
+
+	// A judge is busy with the tournament
+	l := tm.server.tournamentLock(tm.ID)
+	l.Lock()
+
+	done := make(chan *Tournament)
+	go func() {
+		it, _ := tm.db.ImportTournament(data, ImportOptions{Overwrite: true})
+		done <- it
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("import overwrote a tournament that was being changed")
+	case <-time.After(50 * time.Millisecond):
+	}
+	assert.Equal(tm, tm.db.Tournaments[0])
+
+	l.Unlock()
+	it := <-done
+	assert.Equal(it, tm.db.Tournaments[0])
+}
+
+func TestImportUnderAnotherID(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTournament(8)
+
+	it, err := tm.db.ImportTournament(exportJSON(tm), ImportOptions{ID: "staging"})
+	assert.Nil(err)
+	assert.Equal("staging", it.ID)
+}
+
+func TestImportInvalidFails(t *testing.T) {
+	assert := assert.New(t)
+	db := MockDatabase()
+
+	for _, data := range []string{
+		`nope`,
+		`{"version": 99, "tournament": {}}`,
+		`{"name": "x", "id": "x"}`,
+		`{"version": 1, "tournament": {"name": "x"}}`,
+	} {
+		_, err := db.ImportTournament([]byte(data), ImportOptions{})
+		assert.NotNil(err, data)
+	}
+	assert.Equal(0, len(db.Tournaments))
+}
+
+func TestImportUnknownPlayerInMatchFails(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTournament(8)
+	tm.Tryouts[0].Players[0].Name = "ghost"
+
+	_, err := tm.db.ImportTournament(exportJSON(tm), ImportOptions{})
+	assert.NotNil(err)
+}
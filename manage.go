@@ -0,0 +1,236 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// Rename changes the name of the tournament
+func (t *Tournament) Rename(name string) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return errors.New("need a name")
+	}
+
+	old := t.Name
+	t.Name = name
+	if err := t.Persist(); err != nil {
+		t.Name = old
+		return err
+	}
+	return nil
+}
+
+// IsDeleted returns boolean whether the tournament is in the trash
+func (t *Tournament) IsDeleted() bool {
+	return !t.Deleted.IsZero()
+}
+
+// Trash soft deletes a tournament
+//
+// The tournament is taken out of the list and can no longer be found by ID,
+// but it is kept in the trash until it is restored or purged. Archived
+// tournaments are taken out of the archive.
+func (d *Database) Trash(t *Tournament) error {
+	if t.IsDeleted() {
+		return fmt.Errorf("%s is already deleted", t.ID)
+	}
+
+	archived := t.archived
+	t.archived = false
+	t.Deleted = time.Now()
+	if err := t.Persist(); err != nil {
+		t.archived = archived
+		t.Deleted = time.Time{}
+		return err
+	}
+
+	d.toTrash(t)
+	d.unlist(t)
+	log.Printf("Moved tournament %s to the trash", t.ID)
+	return nil
+}
+
+// Restore takes a tournament out of the trash
+func (d *Database) Restore(id string) (*Tournament, error) {
+	t, ok := d.trashed(id)
+	if !ok {
+		return nil, fmt.Errorf("%s is not in the trash", id)
+	}
+
+	t.Deleted = time.Time{}
+	if err := t.Persist(); err != nil {
+		t.Deleted = time.Now()
+		return nil, err
+	}
+
+	d.list(t)
+	d.fromTrash(id)
+	log.Printf("Restored tournament %s from the trash", t.ID)
+	return t, nil
+}
+
+// Purge removes a tournament in the trash for good
+func (d *Database) Purge(id string) error {
+	if _, ok := d.trashed(id); !ok {
+		return fmt.Errorf("%s is not in the trash", id)
+	}
+	if err := d.Store.Delete(id); err != nil {
+		return err
+	}
+
+	d.fromTrash(id)
+	log.Printf("Purged tournament %s", id)
+	return nil
+}
+
+// Clone makes a fresh tournament with the settings and players of another
+//
+// Players that have withdrawn are left out. Without a name, the clone is
+// named after the original, and without an ID, it gets the first free one
+// after the ID of the original.
+func (d *Database) Clone(t *Tournament, name, id string) (*Tournament, error) {
+	if d.Server == nil {
+		return nil, errors.New("tournaments can only be cloned by a server")
+	}
+	if name == "" {
+		name = t.Name + " (copy)"
+	}
+	if id == "" {
+		id = d.freeID(t.ID)
+	} else {
+		var err error
+		if id, err = d.TournamentID(name, id); err != nil {
+			return nil, err
+		}
+	}
+
+	c, _ := NewTournament(name, id, d.Server)
+	if err := d.copySettings(c, t); err != nil {
+		// NewTournament has already stored it
+		d.Store.Delete(c.ID)
+		return nil, err
+	}
+
+	d.list(c)
+	log.Printf("Cloned tournament %s into %s", t.ID, c.ID)
+	return c, nil
+}
+
+// copySettings sets up a new tournament like another one, players included
+func (d *Database) copySettings(c, t *Tournament) error {
+	if err := c.SetMode(t.Mode); err != nil {
+		return err
+	}
+	c.RequireCheckIn = t.RequireCheckIn
+	c.Judges = append([]Judge{}, t.Judges...)
+
+	if t.StationCount != 0 {
+		if err := c.SetStationCount(t.StationCount); err != nil {
+			return err
+		}
+		for i, st := range t.Stations {
+			if i < len(c.Stations) {
+				c.Stations[i].Name = st.Name
+			}
+		}
+	}
+
+	if t.IsTeamMode() {
+		for _, team := range t.Teams {
+			members := []Player{}
+			for _, n := range team.Members {
+				if p := t.getPlayer(n); p != nil && !p.Withdrawn {
+					members = append(members, Player{Name: p.Name, PreferredColor: p.PreferredColor})
+				}
+			}
+			if len(members) != TeamSize {
+				continue
+			}
+			if err := c.AddTeam(team.Name, team.Color, members); err != nil {
+				return err
+			}
+		}
+	} else {
+		ps := make([]Player, 0, len(t.Players)+len(t.Waitlist))
+		ps = append(ps, t.Players...)
+		ps = append(ps, t.Waitlist...)
+		for _, p := range ps {
+			if p.Withdrawn {
+				continue
+			}
+			if _, err := c.Join(p.Name, p.PreferredColor); err != nil {
+				return err
+			}
+		}
+	}
+
+	return c.Persist()
+}
+
+// list adds a tournament to the loaded ones, in the place of any loaded
+// tournament with the same ID
+func (d *Database) list(t *Tournament) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	replaced := false
+	ts := make([]*Tournament, 0, len(d.Tournaments)+1)
+	for _, o := range d.Tournaments {
+		if o.ID == t.ID {
+			o, replaced = t, true
+		}
+		ts = append(ts, o)
+	}
+	if !replaced {
+		ts = append(ts, t)
+	}
+	d.Tournaments = ts
+	d.tournamentRef[t.ID] = t
+}
+
+// unlist takes a tournament out of the loaded ones
+//
+// The slice is built anew, since a websocket update might be sending the
+// old one.
+func (d *Database) unlist(t *Tournament) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	ts := make([]*Tournament, 0, len(d.Tournaments))
+	for _, o := range d.Tournaments {
+		if o != t {
+			ts = append(ts, o)
+		}
+	}
+	d.Tournaments = ts
+	delete(d.tournamentRef, t.ID)
+}
+
+// trashed returns the tournament in the trash with the ID
+func (d *Database) trashed(id string) (*Tournament, bool) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	t, ok := d.trash[id]
+	return t, ok
+}
+
+// toTrash puts a tournament in the trash
+//
+// It is put there before it is unlisted, so that its ID is never free in
+// between.
+func (d *Database) toTrash(t *Tournament) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.trash[t.ID] = t
+}
+
+// fromTrash takes a tournament out of the trash
+func (d *Database) fromTrash(id string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.trash, id)
+}
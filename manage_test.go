@@ -0,0 +1,147 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// registered returns a tournament that is loaded into its database
+func registered(tm *Tournament) *Tournament {
+	tm.db.list(tm)
+	return tm
+}
+
+func TestRename(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTournament(8)
+
+	assert.Nil(tm.Rename("  Drunkenfall 2017 "))
+	assert.Equal("Drunkenfall 2017", tm.Name)
+
+	db := NewStoreDatabase(tm.db.Store)
+	db.LoadTournaments()
+	assert.Equal("Drunkenfall 2017", db.tournamentRef[tm.ID].Name)
+
+	assert.NotNil(tm.Rename(" "))
+	assert.Equal("Drunkenfall 2017", tm.Name)
+}
+
+func TestTrashAndRestore(t *testing.T) {
+	assert := assert.New(t)
+	tm := registered(testTournament(8))
+	db := tm.db
+
+	assert.Nil(db.Trash(tm))
+	assert.True(tm.IsDeleted())
+	assert.Equal(0, len(db.Tournaments))
+	_, err := db.Get(tm.ID)
+	assert.NotNil(err)
+	assert.NotNil(db.Trash(tm))
+
+	page, _ := db.List(ListFilter{})
+	assert.Equal(0, page.Total)
+	page, _ = db.List(ListFilter{Trash: true})
+	assert.Equal(1, page.Total)
+
+	// The trash survives a restart
+	loaded := NewStoreDatabase(db.Store)
+	loaded.LoadTournaments()
+	assert.Equal(0, len(loaded.Tournaments))
+	assert.NotNil(loaded.trash[tm.ID])
+
+	rt, err := db.Restore(tm.ID)
+	assert.Nil(err)
+	assert.False(rt.IsDeleted())
+	assert.Equal(1, len(db.Tournaments))
+	_, err = db.Get(tm.ID)
+	assert.Nil(err)
+
+	_, err = db.Restore(tm.ID)
+	assert.NotNil(err)
+}
+
+func TestTrashArchivedTournament(t *testing.T) {
+	assert := assert.New(t)
+	tm := endedTournament(8)
+	db := tm.db
+	db.Archive(tm)
+
+	at, _ := db.Get(tm.ID)
+	assert.Nil(db.Trash(at))
+
+	summaries, _ := db.Store.Summaries()
+	assert.Equal(0, len(summaries))
+
+	rt, err := db.Restore(tm.ID)
+	assert.Nil(err)
+	assert.Nil(rt.Persist())
+}
+
+func TestPurge(t *testing.T) {
+	assert := assert.New(t)
+	tm := registered(testTournament(8))
+	db := tm.db
+
+	assert.NotNil(db.Purge(tm.ID))
+	db.Trash(tm)
+	assert.Nil(db.Purge(tm.ID))
+
+	ts, _ := db.Store.Tournaments()
+	assert.Equal(0, len(ts))
+	assert.False(db.exists(tm.ID))
+}
+
+func TestClone(t *testing.T) {
+	assert := assert.New(t)
+	tm := registered(testTournament(10))
+	tm.RequireCheckIn = true
+	tm.SetStationCount(2)
+	tm.Stations[1].Name = "Couch"
+	tm.WithdrawPlayer("4")
+
+	c, err := tm.db.Clone(tm, "", "")
+	assert.Nil(err)
+	assert.Equal(tm.ID+"-2", c.ID)
+	assert.Equal(tm.Name+" (copy)", c.Name)
+	assert.True(c.RequireCheckIn)
+	assert.Equal(2, len(c.Stations))
+	assert.Equal("Couch", c.Stations[1].Name)
+	assert.Equal(9, len(c.Players))
+	assert.Nil(c.getPlayer("4"))
+	assert.Equal(tm.getPlayer("1").PreferredColor, c.getPlayer("1").PreferredColor)
+	assert.Equal(c, tm.db.tournamentRef[c.ID])
+
+	// The original is left alone
+	assert.Equal(9, len(tm.Players))
+	assert.True(c.Started.IsZero())
+}
+
+func TestCloneStartedTournamentIsFresh(t *testing.T) {
+	assert := assert.New(t)
+	tm := registered(testTournament(8))
+	tm.StartTournament()
+	playMatch(tm.Tryouts[0])
+
+	c, err := tm.db.Clone(tm, "Rematch", "rematch")
+	assert.Nil(err)
+	assert.Equal("Rematch", c.Name)
+	assert.True(c.Started.IsZero())
+	assert.Equal(8, len(c.Players))
+	for _, p := range c.Players {
+		assert.Equal(0, p.Kills)
+	}
+
+	_, err = tm.db.Clone(tm, "Again", "rematch")
+	assert.NotNil(err)
+}
+
+func TestCloneTeamTournament(t *testing.T) {
+	assert := assert.New(t)
+	tm := registered(testTeamTournament(5))
+
+	c, err := tm.db.Clone(tm, "", "")
+	assert.Nil(err)
+	assert.Equal(TeamMode, c.Mode)
+	assert.Equal(5, len(c.Teams))
+	assert.Equal(10, len(c.Players))
+}
@@ -10,13 +10,23 @@ import (
 
 // Match represents a game being played
 type Match struct {
-	Players    []Player    `json:"players"`
-	Judges     []Judge     `json:"judges"`
-	Kind       string      `json:"kind"`
-	Index      int         `json:"index"`
-	Started    time.Time   `json:"started"`
-	Ended      time.Time   `json:"ended"`
-	Tournament *Tournament `json:"-"`
+	Players     []Player     `json:"players"`
+	Judges      []Judge      `json:"judges"`
+	Kind        string       `json:"kind"`
+	Index       int          `json:"index"`
+	Started     time.Time    `json:"started"`
+	Ended       time.Time    `json:"ended"`
+	State       string       `json:"state"`
+	Station     int          `json:"station"`
+	CheckedIn   []string     `json:"checked_in,omitempty"`
+	Forfeits    []string     `json:"forfeits,omitempty"`
+	Backfilled  []string     `json:"backfilled,omitempty"`
+	Overrides   []Override   `json:"overrides,omitempty"`
+	Tied        []string     `json:"tied,omitempty"`
+	SuddenDeath []string     `json:"sudden_death,omitempty"`
+	Rounds      []Round      `json:"rounds,omitempty"`
+	Predictions []Prediction `json:"predictions,omitempty"`
+	Tournament  *Tournament  `json:"-"`
 }
 
 // NewMatch creates a new Match for usage!
@@ -24,6 +34,7 @@ func NewMatch(t *Tournament, index int, kind string) *Match {
 	m := Match{
 		Index:      index,
 		Kind:       kind,
+		State:      MatchScheduled,
 		Tournament: t,
 	}
 	m.Prefill()
@@ -68,6 +79,8 @@ func (m *Match) Title() string {
 		return "Final"
 	} else if m.Kind == "tryout" {
 		l = len(m.Tournament.Tryouts)
+	} else if m.Kind == "runnerup" {
+		l = len(m.Tournament.RunnerupRounds)
 	}
 
 	out := fmt.Sprintf(
@@ -98,8 +111,6 @@ func (m *Match) AddPlayer(p Player) error {
 	}
 
 	p.Reset()
-	// TODO: Does not do anything right now...
-	m.CorrectColorConflicts()
 	p.Match = m
 
 	if len(m.Players) == 4 {
@@ -115,6 +126,8 @@ func (m *Match) AddPlayer(p Player) error {
 		m.Players = append(m.Players, p)
 	}
 
+	m.DraftColors()
+	m.updateReadiness()
 	return nil
 }
 
@@ -152,35 +165,19 @@ func (m *Match) ActualPlayers() int {
 	return ret
 }
 
-// CorrectColorConflicts sets colors for players who have chosen the same
-// archer.
-func (m *Match) CorrectColorConflicts() error {
-	// Get any conflicting sets
-	for i, p := range m.Players {
-		for j, p2 := range m.Players {
-			// Same player. Skip.
-			if i == j {
-				continue
-			}
-
-			if p.PreferredColor == p2.PreferredColor {
-				// If the score is the same, prefer player one.
-				if p.Score() >= p2.Score() {
-					p2.RandomizeColor(m)
-					_ = m.UpdatePlayer(p2)
-				} else {
-					p.RandomizeColor(m)
-					_ = m.UpdatePlayer(p)
-				}
-			}
-		}
+// Commit adds a state of the players
+//
+// Commits are only accepted while the match is being played. When a commit
+// brings a player to the kill target, the match awaits confirmation from the
+// judge before it can be ended.
+func (m *Match) Commit(scores [][]int, shots []bool) error {
+	if m.getState() != MatchPlaying {
+		return fmt.Errorf("cannot commit to %s match", m.getState())
+	}
+	if len(scores) != len(m.Players) || len(shots) != len(m.Players) {
+		return fmt.Errorf("commit needs state for %d players", len(m.Players))
 	}
 
-	return nil
-}
-
-// Commit adds a state of the players
-func (m *Match) Commit(scores [][]int, shots []bool) {
 	for i, score := range scores {
 		ups := score[0]
 		downs := score[1]
@@ -212,8 +209,17 @@ func (m *Match) Commit(scores [][]int, shots []bool) {
 			m.Players[i].AddShot()
 		}
 	}
+	m.recordRound(scores)
+	m.checkAchievements()
+
+	if m.CanEnd() {
+		_ = m.transition(MatchAwaitingConfirmation)
+	}
 
-	_ = m.Tournament.Persist()
+	if m.Tournament != nil {
+		_ = m.Tournament.Persist()
+	}
+	return nil
 }
 
 // Start starts the match
@@ -222,24 +228,54 @@ func (m *Match) Start() error {
 		return errors.New("match already started")
 	}
 
-	// If there are not four players in the match, we need to populate
-	// the match with runnerups from the tournament
-	if m.ActualPlayers() != 4 {
-		m.Tournament.PopulateRunnerups(m)
+	// Players that have not checked in are replaced by runnerups. Whether
+	// the match can start is only known after that, so if it cannot, the
+	// players are put back the way they were.
+	undo := m.snapshot()
+	if m.Tournament != nil && m.Tournament.RequireCheckIn {
+		if err := m.replaceNoShows(); err != nil {
+			undo()
+			return err
+		}
 	}
 
-	err := m.CorrectColorConflicts()
-	if err != nil {
-		log.Fatal(err)
+	m.updateReadiness()
+	if m.getState() != MatchReady {
+		undo()
+		return fmt.Errorf("cannot start %s match", m.getState())
 	}
 
+	// If there are not four players in the match, we need to populate
+	// the match with runnerups from the tournament. Runner-up rounds are
+	// played with whoever is in them.
+	if m.ActualPlayers() != 4 && m.Kind != "runnerup" {
+		err := m.Tournament.PopulateRunnerups(m)
+		if err != nil {
+			undo()
+			return err
+		}
+
+		// The runnerups are called in by the judge, so they are present
+		if m.Tournament.RequireCheckIn {
+			for _, p := range m.Players {
+				if !m.IsCheckedIn(p.Name) {
+					m.CheckedIn = append(m.CheckedIn, p.Name)
+				}
+			}
+		}
+	}
+
+	m.DraftColors()
+
 	for i := range m.Players {
 		m.Players[i].Reset()
 		m.Players[i].Match = m
 	}
 
 	m.Started = time.Now()
+	_ = m.transition(MatchPlaying)
 	if m.Tournament != nil {
+		m.Tournament.event("match_started", m, "")
 		m.Tournament.Persist()
 	}
 	return nil
@@ -254,8 +290,27 @@ func (m *Match) End() error {
 		return errors.New("match already ended")
 	}
 
+	// Reaching the kill target is what allows a match to end. The toggle from
+	// the judge is the confirmation.
+	if m.getState() == MatchPlaying && m.CanEnd() {
+		_ = m.transition(MatchAwaitingConfirmation)
+	}
+	if !m.CanTransition(MatchEnded) {
+		return fmt.Errorf("cannot end %s match before the kill target is reached", m.getState())
+	}
+
+	// If the players that matter cannot be separated, the judge needs to
+	// have them play a sudden death round before we can end.
+	m.Tied = m.unresolvedTies()
+	if m.IsTiePending() {
+		if m.Tournament != nil {
+			m.Tournament.Persist()
+		}
+		return errors.New(m.tieMessage())
+	}
+
 	// Give the winner one last shot
-	ps := ByScore(m.Players)
+	ps := m.Standings()
 	winner := ps[0].Name
 	for i, p := range m.Players {
 		if p.Name == winner {
@@ -265,14 +320,19 @@ func (m *Match) End() error {
 	}
 
 	m.Ended = time.Now()
+	_ = m.transition(MatchEnded)
+	m.checkAchievements()
+	m.scorePredictions()
 	// TODO: This is for the tests not to break. Fix by setting up better tests.
 	if m.Tournament != nil {
+		m.Tournament.event("match_ended", m, winner)
 		if m.Kind == "final" {
 			m.Tournament.AwardMedals(m)
 		} else {
 			m.Tournament.MovePlayers(m)
 		}
 
+		m.Tournament.updateReadiness()
 		m.Tournament.Persist()
 	}
 	return nil
@@ -298,6 +358,14 @@ func (m *Match) CanEnd() bool {
 	if !m.IsOpen() {
 		return false
 	}
+	if m.Tournament != nil && m.Tournament.IsTeamMode() {
+		for _, ts := range m.TeamStandings() {
+			if ts.Kills >= m.Length() {
+				return true
+			}
+		}
+		return false
+	}
 	for _, p := range m.Players {
 		if p.Kills >= m.Length() {
 			return true
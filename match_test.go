@@ -82,7 +82,7 @@ func TestEndGivesShotToWinner(t *testing.T) {
 
 	err = m.End()
 	assert.Nil(err)
-	assert.Equal(1, m.Players[0].Shots)
+	assert.Equal(1, m.Players[2].Shots)
 }
 
 func TestEndAlreadyEndedMatch(t *testing.T) {
@@ -97,8 +97,18 @@ func TestEndAlreadyEndedMatch(t *testing.T) {
 func TestEnd(t *testing.T) {
 	assert := assert.New(t)
 	m := NewMatch(tm, 1, "test")
+	m.Players = []Player{
+		{Name: "1"},
+		{Name: "2"},
+		{Name: "3"},
+		{Name: "4"},
+	}
 
-	err := m.End()
+	err := m.Start()
+	assert.Nil(err)
+	m.Players[0].AddKill(10)
+
+	err = m.End()
 	assert.Nil(err)
 	assert.Equal(false, m.Ended.IsZero())
 }
@@ -134,6 +144,7 @@ func TestCommitSweepPlayer1(t *testing.T) {
 	_ = m.AddPlayer(Player{Name: "2"})
 	_ = m.AddPlayer(Player{Name: "3"})
 	_ = m.AddPlayer(Player{Name: "4"})
+	_ = m.Start()
 
 	scores := [][]int{
 		[]int{3, 0},
@@ -148,7 +159,8 @@ func TestCommitSweepPlayer1(t *testing.T) {
 		false,
 	}
 
-	m.Commit(scores, shots)
+	err := m.Commit(scores, shots)
+	assert.Nil(err)
 	assert.Equal(1, m.Players[0].Sweeps)
 }
 
@@ -160,6 +172,7 @@ func TestCommitDoubleKillPlayer2(t *testing.T) {
 	_ = m.AddPlayer(Player{Name: "2"})
 	_ = m.AddPlayer(Player{Name: "3"})
 	_ = m.AddPlayer(Player{Name: "4"})
+	_ = m.Start()
 
 	scores := [][]int{
 		[]int{0, 0},
@@ -174,7 +187,8 @@ func TestCommitDoubleKillPlayer2(t *testing.T) {
 		false,
 	}
 
-	m.Commit(scores, shots)
+	err := m.Commit(scores, shots)
+	assert.Nil(err)
 	assert.Equal(2, m.Players[1].Kills)
 }
 
@@ -186,6 +200,7 @@ func TestCommitSweepAndSuicidePlayer3(t *testing.T) {
 	_ = m.AddPlayer(Player{Name: "2"})
 	_ = m.AddPlayer(Player{Name: "3"})
 	_ = m.AddPlayer(Player{Name: "4"})
+	_ = m.Start()
 
 	scores := [][]int{
 		[]int{0, 0},
@@ -200,7 +215,8 @@ func TestCommitSweepAndSuicidePlayer3(t *testing.T) {
 		false,
 	}
 
-	m.Commit(scores, shots)
+	err := m.Commit(scores, shots)
+	assert.Nil(err)
 	assert.Equal(1, m.Players[2].Sweeps)
 	assert.Equal(2, m.Players[2].Kills)
 	assert.Equal(1, m.Players[2].Shots)
@@ -214,6 +230,7 @@ func TestCommitSuicidePlayer4(t *testing.T) {
 	_ = m.AddPlayer(Player{Name: "2"})
 	_ = m.AddPlayer(Player{Name: "3"})
 	_ = m.AddPlayer(Player{Name: "4"})
+	_ = m.Start()
 
 	scores := [][]int{
 		[]int{0, 0},
@@ -228,7 +245,8 @@ func TestCommitSuicidePlayer4(t *testing.T) {
 		false,
 	}
 
-	m.Commit(scores, shots)
+	err := m.Commit(scores, shots)
+	assert.Nil(err)
 	assert.Equal(1, m.Players[3].Self)
 	assert.Equal(1, m.Players[3].Shots)
 }
@@ -241,6 +259,7 @@ func TestCommitShotsForPlayer2and3(t *testing.T) {
 	_ = m.AddPlayer(Player{Name: "2"})
 	_ = m.AddPlayer(Player{Name: "3"})
 	_ = m.AddPlayer(Player{Name: "4"})
+	_ = m.Start()
 
 	scores := [][]int{
 		[]int{0, 0},
@@ -255,7 +274,8 @@ func TestCommitShotsForPlayer2and3(t *testing.T) {
 		false,
 	}
 
-	m.Commit(scores, shots)
+	err := m.Commit(scores, shots)
+	assert.Nil(err)
 	assert.Equal(1, m.Players[1].Shots)
 	assert.Equal(1, m.Players[2].Shots)
 }
@@ -269,8 +289,7 @@ func TestCorrectColorConflictsNoScores(t *testing.T) {
 	_ = m.AddPlayer(Player{Name: "c", PreferredColor: "blue"})
 	_ = m.AddPlayer(Player{Name: "d", PreferredColor: "pink"})
 
-	err := m.CorrectColorConflicts()
-	assert.Nil(err)
+	m.DraftColors()
 
 	assert.Equal("green", m.Players[0].PreferredColor)
 	assert.NotEqual("green", m.Players[1].PreferredColor)
@@ -285,8 +304,7 @@ func TestCorrectColorConflictsNoScoresDoubleConflict(t *testing.T) {
 	_ = m.AddPlayer(Player{Name: "c", PreferredColor: "blue"})
 	_ = m.AddPlayer(Player{Name: "d", PreferredColor: "blue"})
 
-	err := m.CorrectColorConflicts()
-	assert.Nil(err)
+	m.DraftColors()
 
 	assert.Equal("green", m.Players[0].PreferredColor)
 	assert.NotEqual("green", m.Players[1].PreferredColor)
@@ -306,12 +324,10 @@ func TestCorrectColorConflictPlayerTwoHasHigherScore(t *testing.T) {
 	// Add some score to player 2 so that it has preference over green.
 	m.Players[1].AddKill(3)
 
-	err := m.CorrectColorConflicts()
-	assert.Nil(err)
+	m.DraftColors()
 
-	// TODO(thiderman): Fix this
-	// assert.NotEqual("green", m.Players[0].PreferredColor)
-	// assert.Equal("green", m.Players[1].PreferredColor)
-	// assert.Equal("blue", m.Players[2].PreferredColor)
-	// assert.Equal("cyan", m.Players[3].PreferredColor)
+	assert.NotEqual("green", m.Players[0].PreferredColor)
+	assert.Equal("green", m.Players[1].PreferredColor)
+	assert.Equal("blue", m.Players[2].PreferredColor)
+	assert.Equal("cyan", m.Players[3].PreferredColor)
 }
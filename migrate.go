@@ -0,0 +1,163 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// SchemaVersion is the version of the tournament records written by this
+// version of the code
+//
+// Whenever a change to Tournament, Match or Player means that old records
+// would not load correctly, bump this and add a migration that upgrades the
+// records from the previous version.
+const SchemaVersion = 1
+
+// migration upgrades a stored tournament by one schema version
+//
+// Migrations work on the decoded JSON rather than on the structs, since the
+// structs only know how to read the current version.
+type migration struct {
+	description string
+	run         func(doc map[string]interface{}) error
+}
+
+// migrations are the upgrades between the schema versions. The migration at
+// index n upgrades a record from version n to version n+1.
+var migrations = []migration{
+	{"set the mode and the match states of unversioned records", migrateUnversioned},
+}
+
+// Migrate upgrades a stored tournament to the current schema version
+//
+// Records that are already current are returned as they are.
+func Migrate(data []byte) ([]byte, error) {
+	doc := make(map[string]interface{})
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+
+	v, err := schemaVersion(doc)
+	if err != nil {
+		return nil, err
+	}
+	if v == SchemaVersion {
+		return data, nil
+	}
+
+	for ; v < SchemaVersion; v++ {
+		m := migrations[v]
+		if err := m.run(doc); err != nil {
+			return nil, fmt.Errorf("migration to version %d (%s) failed: %s", v+1, m.description, err)
+		}
+	}
+
+	doc["version"] = SchemaVersion
+	return json.Marshal(doc)
+}
+
+// schemaVersion returns the version of a decoded record
+//
+// Records from before the versioning have no version and are version 0.
+func schemaVersion(doc map[string]interface{}) (int, error) {
+	raw, ok := doc["version"]
+	if !ok || raw == nil {
+		return 0, nil
+	}
+
+	f, ok := raw.(float64)
+	if !ok || f < 0 || f != float64(int(f)) {
+		return 0, fmt.Errorf("invalid schema version %v", raw)
+	}
+
+	v := int(f)
+	if v > SchemaVersion {
+		return 0, fmt.Errorf("schema version %d is newer than the supported %d", v, SchemaVersion)
+	}
+	return v, nil
+}
+
+// migrateUnversioned upgrades records from before the versioning
+//
+// Those were all solo tournaments, and the matches had no state apart from
+// their start and end times. Started matches where the kill target has been
+// reached are awaiting confirmation, like deriveState would have it. The
+// readiness of the matches that have not started is worked out when the
+// tournament is loaded.
+func migrateUnversioned(doc map[string]interface{}) error {
+	if mode, _ := doc["mode"].(string); mode == "" {
+		doc["mode"] = SoloMode
+	}
+
+	for _, m := range docMatches(doc) {
+		if state, _ := m["state"].(string); state != "" {
+			continue
+		}
+
+		ended, err := docTime(m, "ended")
+		if err != nil {
+			return err
+		}
+		started, err := docTime(m, "started")
+		if err != nil {
+			return err
+		}
+
+		if !ended.IsZero() {
+			m["state"] = MatchEnded
+		} else if !started.IsZero() {
+			m["state"] = MatchPlaying
+			done, err := docCanEnd(m)
+			if err != nil {
+				return err
+			}
+			if done {
+				m["state"] = MatchAwaitingConfirmation
+			}
+		} else {
+			m["state"] = MatchScheduled
+		}
+	}
+	return nil
+}
+
+// docMatches returns all the matches of a decoded record
+func docMatches(doc map[string]interface{}) []map[string]interface{} {
+	ms := []map[string]interface{}{}
+	for _, key := range []string{"tryouts", "runnerup_rounds", "semis"} {
+		list, _ := doc[key].([]interface{})
+		for _, x := range list {
+			if m, ok := x.(map[string]interface{}); ok {
+				ms = append(ms, m)
+			}
+		}
+	}
+	if m, ok := doc["final"].(map[string]interface{}); ok {
+		ms = append(ms, m)
+	}
+	return ms
+}
+
+// docCanEnd returns if the kill target of a decoded match has been reached
+func docCanEnd(doc map[string]interface{}) (bool, error) {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return false, err
+	}
+
+	var m Match
+	if err := json.Unmarshal(data, &m); err != nil {
+		return false, err
+	}
+	return m.CanEnd(), nil
+}
+
+// docTime returns a time from a decoded record. Missing times are zero.
+func docTime(doc map[string]interface{}, key string) (time.Time, error) {
+	s, _ := doc[key].(string)
+	if s == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339Nano, s)
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"testing"
+)
+
+func legacyFixture(t *testing.T) []byte {
+	data, err := ioutil.ReadFile("testdata/tournament-v0.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestLegacyFixtureHasTheOldShape(t *testing.T) {
+	assert := assert.New(t)
+
+	var doc map[string]interface{}
+	assert.Nil(json.Unmarshal(legacyFixture(t), &doc))
+	for _, key := range []string{"version", "mode", "teams", "runnerup_rounds", "stations"} {
+		_, ok := doc[key]
+		assert.False(ok, key)
+	}
+	for _, m := range docMatches(doc) {
+		_, ok := m["state"]
+		assert.False(ok)
+	}
+}
+
+func TestLoadUnversionedTournament(t *testing.T) {
+	assert := assert.New(t)
+	db := MockDatabase()
+	testServer(db)
+
+	tm, err := LoadTournament(legacyFixture(t), db)
+	assert.Nil(err)
+
+	assert.Equal(SchemaVersion, tm.Version)
+	assert.Equal(SoloMode, tm.Mode)
+	assert.Equal(12, len(tm.Players))
+	assert.Equal(MatchEnded, tm.Tryouts[0].State)
+	assert.Equal(MatchPlaying, tm.Tryouts[1].State)
+	assert.Equal(MatchAwaitingConfirmation, tm.Tryouts[2].State)
+	assert.Equal(MatchScheduled, tm.Tryouts[3].State)
+	assert.Equal(MatchScheduled, tm.Final.State)
+	assert.Equal("Friday Night Fall", tm.Name)
+	assert.Equal(10, tm.Tryouts[0].Players[0].Kills)
+	assert.Equal(1, len(tm.Stations))
+
+	// The migrated tournament can be played on
+	m := tm.Tryouts[1]
+	for i := range m.Players {
+		m.Players[i].AddKill(m.Length() - i)
+	}
+	assert.Nil(m.End())
+	assert.Equal(4, tm.Semis[0].ActualPlayers()+tm.Semis[1].ActualPlayers())
+}
+
+func TestMigrateStampsVersion(t *testing.T) {
+	assert := assert.New(t)
+
+	data, err := Migrate(legacyFixture(t))
+	assert.Nil(err)
+
+	var doc map[string]interface{}
+	assert.Nil(json.Unmarshal(data, &doc))
+	assert.Equal(float64(SchemaVersion), doc["version"])
+	assert.Equal(SoloMode, doc["mode"])
+}
+
+func TestMigrateCurrentRecordIsUntouched(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTournament(8)
+	data, _ := tm.JSON()
+
+	out, err := Migrate(data)
+	assert.Nil(err)
+	assert.Equal(data, out)
+}
+
+func TestMigrateNewerVersionFails(t *testing.T) {
+	assert := assert.New(t)
+
+	_, err := Migrate([]byte(`{"version": 99, "name": "future"}`))
+	assert.NotNil(err)
+
+	db := MockDatabase()
+	_, err = LoadTournament([]byte(`{"version": 99, "name": "future"}`), db)
+	assert.NotNil(err)
+}
+
+func TestPersistStampsVersion(t *testing.T) {
+	assert := assert.New(t)
+	tm := Tournament{Name: "hehe", ID: "hehe"}
+
+	data, err := tm.JSON()
+	assert.Nil(err)
+	assert.Contains(string(data), `"version":1`)
+
+	// Writing it out does not change it
+	assert.Equal(0, tm.Version)
+}
+
+func TestDatabaseMigrate(t *testing.T) {
+	assert := assert.New(t)
+	db := MockDatabase()
+	legacy := legacyFixture(t)
+
+	db.Store.Save("legacy", legacy)
+
+	ids, err := db.Migrate(true)
+	assert.Nil(err)
+	assert.Equal([]string{"legacy"}, ids)
+
+	stored := func() []byte {
+		ts, _ := db.Store.Tournaments()
+		return ts["legacy"]
+	}
+	assert.Equal(legacy, stored())
+
+	ids, err = db.Migrate(false)
+	assert.Nil(err)
+	assert.Equal([]string{"legacy"}, ids)
+	assert.Contains(string(stored()), `"version":1`)
+
+	ids, err = db.Migrate(false)
+	assert.Nil(err)
+	assert.Equal(0, len(ids))
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/thiderman/drunkenfall/render"
+)
+
+// overlayCache holds the latest rendered overlay images of the tournaments
+type overlayCache struct {
+	sync.RWMutex
+	images map[string][]byte
+}
+
+// overlayQueue holds the tournaments waiting to have their overlays rendered
+//
+// The tournaments are kept as the JSON they were persisted as, so that the
+// rendering does not read them while they are being changed. Rendering is
+// slow, so a tournament that is persisted again while it waits is only
+// rendered once, from the latest JSON.
+type overlayQueue struct {
+	sync.Mutex
+	pending map[string][]byte
+	running bool
+}
+
+// RenderOverlays draws the bracket and scoreboard images of a tournament
+//
+// This is done whenever the tournament is persisted, so that the stream
+// overlays can just keep fetching the same URLs.
+func (s *Server) RenderOverlays(t *Tournament) error {
+	if s.renderer == nil {
+		return errors.New("no renderer loaded")
+	}
+
+	b := t.renderBracket()
+	images := map[string][]byte{
+		"bracket.svg": s.renderer.BracketSVG(b),
+	}
+
+	data, err := s.renderer.BracketPNG(b)
+	if err != nil {
+		return err
+	}
+	images["bracket.png"] = data
+
+	if m := t.overlayMatch(); m != nil {
+		rm := m.renderMatch()
+		images["scoreboard.svg"] = s.renderer.ScoreboardSVG(rm)
+
+		data, err := s.renderer.ScoreboardPNG(rm)
+		if err != nil {
+			return err
+		}
+		images["scoreboard.png"] = data
+	}
+
+	s.overlays.Lock()
+	defer s.overlays.Unlock()
+	if s.overlays.images == nil {
+		s.overlays.images = make(map[string][]byte)
+	}
+	for name, data := range images {
+		s.overlays.images[t.ID+"/"+name] = data
+	}
+	return nil
+}
+
+// overlay returns a rendered overlay image, rendering it if needed
+func (s *Server) overlay(t *Tournament, name string) ([]byte, error) {
+	s.overlays.RLock()
+	data, ok := s.overlays.images[t.ID+"/"+name]
+	s.overlays.RUnlock()
+	if ok {
+		return data, nil
+	}
+
+	if err := s.RenderOverlays(t); err != nil {
+		return nil, err
+	}
+
+	s.overlays.RLock()
+	defer s.overlays.RUnlock()
+	data, ok = s.overlays.images[t.ID+"/"+name]
+	if !ok {
+		return nil, fmt.Errorf("%s has not been rendered", name)
+	}
+	return data, nil
+}
+
+// queueOverlays has the overlays of a persisted tournament rendered in the
+// background
+//
+// There is only ever one worker rendering, which keeps going until the queue
+// is empty.
+func (s *Server) queueOverlays(id string, data []byte) {
+	if s == nil || s.renderer == nil || s.DB == nil {
+		return
+	}
+
+	q := &s.overlayQueue
+	q.Lock()
+	defer q.Unlock()
+	if q.pending == nil {
+		q.pending = make(map[string][]byte)
+	}
+	q.pending[id] = data
+
+	if !q.running {
+		q.running = true
+		go s.renderQueued()
+	}
+}
+
+// renderQueued renders the queued overlays and logs failures
+func (s *Server) renderQueued() {
+	q := &s.overlayQueue
+	for {
+		q.Lock()
+		pending := q.pending
+		q.pending = nil
+		if len(pending) == 0 {
+			q.running = false
+			q.Unlock()
+			return
+		}
+		q.Unlock()
+
+		for _, data := range pending {
+			t, err := LoadTournament(data, s.DB)
+			if err != nil {
+				continue
+			}
+			if err := s.RenderOverlays(t); err != nil {
+				log.Print(err)
+			}
+		}
+	}
+}
+
+// renderBracket converts the bracket into what the renderer draws
+//
+// The tryouts, semis and final are drawn as the rounds of the tree, with the
+// runner-up rounds on the side.
+func (t *Tournament) renderBracket() render.Bracket {
+	columns := map[string]int{
+		"tryout":   0,
+		"semi":     1,
+		"final":    2,
+		"runnerup": 3,
+	}
+
+	gb := t.Bracket()
+	b := render.Bracket{
+		Title:   t.Name,
+		Matches: make([]render.Match, 0, len(gb.Nodes)),
+		Edges:   make([]render.Edge, 0, len(gb.Edges)),
+	}
+
+	for _, m := range t.Matches() {
+		rm := m.renderMatch()
+		rm.Column = columns[m.Kind]
+		b.Matches = append(b.Matches, rm)
+	}
+	for _, e := range gb.Edges {
+		b.Edges = append(b.Edges, render.Edge{From: e.From, To: e.To})
+	}
+	return b
+}
+
+// renderMatch converts a match into what the renderer draws
+func (m *Match) renderMatch() render.Match {
+	n := m.bracketNode()
+	rm := render.Match{
+		ID:    n.ID,
+		Title: n.Title,
+		State: n.State,
+		Slots: make([]render.Slot, 0, len(m.Players)),
+	}
+
+	for i, s := range n.Slots {
+		rs := render.Slot{
+			Name:      s.Name,
+			Color:     s.Color,
+			Kills:     s.Kills,
+			Placement: s.Placement,
+		}
+		if s.Name != "" {
+			rs.Shots = m.Players[i].Shots
+		}
+		rm.Slots = append(rm.Slots, rs)
+	}
+	return rm
+}
+
+// overlayMatch returns the match to show on the scoreboard overlay
+//
+// That is the match being played, or the one that is up next. Once the
+// tournament is over, the final stays up.
+func (t *Tournament) overlayMatch() *Match {
+	for _, m := range t.Matches() {
+		if m.IsStarted() && !m.IsDone() {
+			return m
+		}
+	}
+
+	m, err := t.NextMatch()
+	if err != nil {
+		return t.Final
+	}
+	return m
+}
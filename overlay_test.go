@@ -0,0 +1,103 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+
+	"github.com/thiderman/drunkenfall/render"
+)
+
+func TestRenderBracketColumns(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTournament(16)
+
+	b := tm.renderBracket()
+	assert.Equal(7, len(b.Matches))
+	assert.Equal(0, b.Matches[0].Column)
+	assert.Equal(1, b.Matches[4].Column)
+	assert.Equal(2, b.Matches[6].Column)
+	assert.Equal(12, len(b.Edges))
+}
+
+func TestRenderMatchHasScores(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTournament(16)
+	tm.StartTournament()
+	m := tm.Tryouts[0]
+	m.Start()
+	m.Players[1].AddKill(3)
+	m.Players[1].AddShot()
+
+	rm := m.renderMatch()
+	assert.Equal(m.Players[1].Name, rm.Slots[1].Name)
+	assert.Equal(3, rm.Slots[1].Kills)
+	assert.Equal(1, rm.Slots[1].Shots)
+}
+
+func TestOverlayMatchIsTheOneBeingPlayed(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTournament(16)
+	tm.StartTournament()
+	assert.Equal(tm.Tryouts[0], tm.overlayMatch())
+
+	tm.Tryouts[2].Start()
+	assert.Equal(tm.Tryouts[2], tm.overlayMatch())
+}
+
+func TestOverlayWithoutRendererFails(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTournament(16)
+
+	_, err := tm.server.overlay(tm, "bracket.svg")
+	assert.NotNil(err)
+}
+
+func TestOverlaysAreRendered(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTournament(16)
+	s := tm.server
+
+	r, err := render.NewRenderer("static", renderArchers())
+	assert.Nil(err)
+	s.renderer = r
+
+	for _, name := range []string{"bracket.svg", "bracket.png", "scoreboard.svg", "scoreboard.png"} {
+		data, err := s.overlay(tm, name)
+		assert.Nil(err)
+		assert.NotEmpty(data)
+	}
+
+	_, err = s.overlay(tm, "nope.svg")
+	assert.NotNil(err)
+}
+
+func TestPersistRendersOverlaysFromSnapshot(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTournament(16)
+	s := tm.server
+
+	r, err := render.NewRenderer("static", renderArchers())
+	assert.Nil(err)
+	s.renderer = r
+
+	for i := 0; i < 5; i++ {
+		assert.Nil(tm.Persist())
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		s.overlayQueue.Lock()
+		done := !s.overlayQueue.running
+		s.overlayQueue.Unlock()
+		if done || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	s.overlays.RLock()
+	defer s.overlays.RUnlock()
+	assert.NotEmpty(s.overlays.images[tm.ID+"/bracket.svg"])
+	assert.NotEmpty(s.overlays.images[tm.ID+"/scoreboard.png"])
+}
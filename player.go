@@ -2,24 +2,9 @@ package main
 
 import (
 	"fmt"
-	"math/rand"
 	"sort"
-	"time"
 )
 
-// Colors is a list of the available player colors
-var Colors = []string{
-	"green",
-	"blue",
-	"pink",
-	"orange",
-	"white",
-	"yellow",
-	"cyan",
-	"purple",
-	"red",
-}
-
 // Participant someone having a role in the tournament
 type Participant interface {
 	ID() string
@@ -33,17 +18,26 @@ type ScoreData struct {
 }
 
 // Player is a Participant that is actively participating in battles.
+//
+// In a match, PreferredColor is the archer the player was drafted, and
+// Colors are the archers the player wants, most wanted first. Achievements
+// are counted by their ID.
 type Player struct {
-	Name           string `json:"name"`
-	PreferredColor string `json:"preferred_color"`
-	Shots          int    `json:"shots"`
-	Sweeps         int    `json:"sweeps"`
-	Kills          int    `json:"kills"`
-	Self           int    `json:"self"`
-	Explosions     int    `json:"explosions"`
-	Matches        int    `json:"matches"`
-	TotalScore     int    `json:"score"`
-	Match          *Match `json:"-"`
+	Name           string         `json:"name"`
+	PreferredColor string         `json:"preferred_color"`
+	Colors         []string       `json:"colors,omitempty"`
+	Team           string         `json:"team,omitempty"`
+	Shots          int            `json:"shots"`
+	Sweeps         int            `json:"sweeps"`
+	Kills          int            `json:"kills"`
+	Self           int            `json:"self"`
+	Explosions     int            `json:"explosions"`
+	Matches        int            `json:"matches"`
+	Forfeits       int            `json:"forfeits"`
+	Achievements   map[string]int `json:"achievements,omitempty"`
+	Withdrawn      bool           `json:"withdrawn"`
+	TotalScore     int            `json:"score"`
+	Match          *Match         `json:"-"`
 }
 
 // NewPlayer returns a new instance of a player
@@ -116,13 +110,14 @@ func (p *Player) Classes() string {
 	}
 
 	if p.Match != nil && p.Match.IsEnded() {
-		ps := ByScore(p.Match.Players)
+		ps := p.Match.Standings()
 		if ps[0].Name == p.Name {
 			// Always gold for the winner
 			return "gold"
 		} else if ps[1].Name == p.Name {
-			// Silver for the second, unless there is a short amount of tryouts
-			if p.Match.Kind != "tryout" || len(p.Match.Tournament.Tryouts) <= 4 {
+			// Silver for the second, unless there is a short amount of tryouts or
+			// nothing rides on the match
+			if p.Match.Kind == "semi" || p.Match.Kind == "final" || (p.Match.Kind == "tryout" && len(p.Match.Tournament.Tryouts) <= 4) {
 				return "silver"
 			}
 		} else if ps[2].Name == p.Name && p.Match.Kind == "final" {
@@ -134,42 +129,6 @@ func (p *Player) Classes() string {
 	return p.PreferredColor
 }
 
-// RandomizeColor sets the color of this player to an unused one
-func (p *Player) RandomizeColor(m *Match) {
-	// Grab all the colors so we have a reference of what we cannot use
-	colors := make([]string, 4)
-	for _, p := range m.Players {
-		colors = append(colors, p.PreferredColor)
-	}
-
-	// Loop and try to find a new random color that is not already taken
-	// by another player in the match.
-	var color string
-	found := false
-	for {
-		color = getRandomPlayerColor()
-		found = false
-		for _, c := range colors {
-			if c == color {
-				found = true
-				break
-			}
-		}
-
-		if found == false {
-			p.PreferredColor = color
-			return
-		}
-	}
-}
-
-func getRandomPlayerColor() string {
-	rand.Seed(time.Now().UTC().UnixNano())
-	offset := rand.Int() % len(Colors)
-	color := Colors[offset]
-	return color
-}
-
 // Index returns the index in the current match
 func (p *Player) Index() int {
 	if p.Match != nil {
@@ -327,6 +286,8 @@ func (p *Player) Reset() {
 	p.Self = 0
 	p.Explosions = 0
 	p.Matches = 0
+	p.Forfeits = 0
+	p.Achievements = nil
 }
 
 // Update updates a player with the scores of another
@@ -338,6 +299,11 @@ func (p *Player) Update(other Player) {
 	p.Kills += other.Kills
 	p.Self += other.Self
 	p.Explosions += other.Explosions
+	for id, n := range other.Achievements {
+		for i := 0; i < n; i++ {
+			p.addAchievement(id)
+		}
+	}
 	p.TotalScore = p.Score()
 
 	// Every call to this method is per match. Count every call
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/thiderman/drunkenfall/websockets"
+)
+
+// PredictionPoints is what a spectator gets for predicting the winner of a
+// match
+const PredictionPoints = 10
+
+// Prediction is a spectator picking the winner of a match
+//
+// The points are given when the match ends. In team mode, the winner is the
+// name of a team.
+type Prediction struct {
+	Spectator string    `json:"spectator"`
+	Winner    string    `json:"winner"`
+	Time      time.Time `json:"time"`
+	Points    int       `json:"points"`
+}
+
+// SpectatorScore is how well a spectator has predicted the matches of a
+// tournament
+type SpectatorScore struct {
+	Spectator   string `json:"spectator"`
+	Points      int    `json:"points"`
+	Correct     int    `json:"correct"`
+	Predictions int    `json:"predictions"`
+}
+
+// LeaderboardMessage is the spectator leaderboard of a tournament, pushed
+// over the websocket
+type LeaderboardMessage struct {
+	Leaderboard Leaderboard `json:"leaderboard"`
+}
+
+// Leaderboard is the spectator leaderboard of a tournament
+type Leaderboard struct {
+	Tournament string           `json:"tournament"`
+	Scores     []SpectatorScore `json:"scores"`
+}
+
+// Predict sets the winner a spectator thinks the match will have
+//
+// Predictions can be changed until the match starts, and are locked after
+// that.
+func (m *Match) Predict(spectator, winner string) error {
+	spectator = strings.TrimSpace(spectator)
+	if spectator == "" {
+		return errors.New("need a spectator name")
+	}
+	if m.IsStarted() {
+		return errors.New("predictions are locked once the match has started")
+	}
+	if !m.canWin(winner) {
+		return fmt.Errorf("%s is not in %s", winner, m.String())
+	}
+
+	p := Prediction{
+		Spectator: spectator,
+		Winner:    winner,
+		Time:      time.Now(),
+	}
+
+	found := false
+	for i, o := range m.Predictions {
+		if o.Spectator == spectator {
+			m.Predictions[i] = p
+			found = true
+		}
+	}
+	if !found {
+		m.Predictions = append(m.Predictions, p)
+	}
+
+	if m.Tournament != nil {
+		return m.Tournament.Persist()
+	}
+	return nil
+}
+
+// canWin returns boolean whether the player, or the team in team mode, is
+// in the match
+func (m *Match) canWin(name string) bool {
+	if name == "" {
+		return false
+	}
+
+	for _, p := range m.Players {
+		if p.IsPrefill() {
+			continue
+		}
+		if m.Tournament != nil && m.Tournament.IsTeamMode() {
+			if p.Team == name {
+				return true
+			}
+		} else if p.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// winner returns the name of the winner of the match, or the winning team in
+// team mode
+func (m *Match) winner() string {
+	if m.Tournament != nil && m.Tournament.IsTeamMode() {
+		ts := m.TeamStandings()
+		if len(ts) == 0 {
+			return ""
+		}
+		return ts[0].Name
+	}
+
+	ps := m.Standings()
+	if len(ps) == 0 {
+		return ""
+	}
+	return ps[0].Name
+}
+
+// scorePredictions gives points to the spectators that predicted the winner
+func (m *Match) scorePredictions() {
+	if len(m.Predictions) == 0 {
+		return
+	}
+
+	winner := m.winner()
+	for i, p := range m.Predictions {
+		m.Predictions[i].Points = 0
+		if p.Winner == winner {
+			m.Predictions[i].Points = PredictionPoints
+		}
+	}
+
+	if m.Tournament != nil {
+		m.Tournament.sendLeaderboard()
+	}
+}
+
+// Leaderboard returns the spectators of the tournament, best first
+//
+// Spectators with the same points are ordered by how many predictions they
+// got right, and then by name.
+func (t *Tournament) Leaderboard() Leaderboard {
+	index := make(map[string]int)
+	scores := []SpectatorScore{}
+	for _, m := range t.Matches() {
+		for _, p := range m.Predictions {
+			i, ok := index[p.Spectator]
+			if !ok {
+				i = len(scores)
+				index[p.Spectator] = i
+				scores = append(scores, SpectatorScore{Spectator: p.Spectator})
+			}
+
+			s := &scores[i]
+			s.Predictions++
+			s.Points += p.Points
+			if p.Points > 0 {
+				s.Correct++
+			}
+		}
+	}
+
+	sort.SliceStable(scores, func(i, j int) bool {
+		a, b := scores[i], scores[j]
+		if a.Points != b.Points {
+			return a.Points > b.Points
+		}
+		if a.Correct != b.Correct {
+			return a.Correct > b.Correct
+		}
+		return a.Spectator < b.Spectator
+	})
+
+	return Leaderboard{Tournament: t.ID, Scores: scores}
+}
+
+// sendLeaderboard pushes the spectator leaderboard to the websocket
+func (t *Tournament) sendLeaderboard() {
+	if t.server == nil || t.server.ws == nil {
+		return
+	}
+
+	msg := websockets.Message{
+		Data:      LeaderboardMessage{Leaderboard: t.Leaderboard()},
+		Transient: true,
+	}
+	go t.server.ws.SendAll(&msg)
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestPredict(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTournament(8)
+	assert.Nil(tm.StartTournament())
+	m := tm.Tryouts[0]
+
+	assert.Nil(m.Predict("alice", m.Players[0].Name))
+	assert.Nil(m.Predict(" bob ", m.Players[1].Name))
+	assert.Equal(2, len(m.Predictions))
+	assert.Equal("bob", m.Predictions[1].Spectator)
+
+	// Changing your mind replaces the prediction
+	assert.Nil(m.Predict("alice", m.Players[2].Name))
+	assert.Equal(2, len(m.Predictions))
+	assert.Equal(m.Players[2].Name, m.Predictions[0].Winner)
+}
+
+func TestPredictNeedsPlayerInMatch(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTournament(8)
+	assert.Nil(tm.StartTournament())
+
+	assert.NotNil(tm.Tryouts[0].Predict("alice", tm.Tryouts[1].Players[0].Name))
+	assert.NotNil(tm.Tryouts[0].Predict("alice", ""))
+	assert.NotNil(tm.Tryouts[0].Predict(" ", tm.Tryouts[0].Players[0].Name))
+
+	// Nobody is in the semis yet
+	assert.NotNil(tm.Semis[0].Predict("alice", ""))
+	assert.Equal(0, len(tm.Tryouts[0].Predictions))
+}
+
+func TestPredictionsLockAtStart(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTournament(8)
+	assert.Nil(tm.StartTournament())
+	m := tm.Tryouts[0]
+
+	assert.Nil(m.Predict("alice", m.Players[0].Name))
+	assert.Nil(m.Start())
+	assert.NotNil(m.Predict("alice", m.Players[1].Name))
+	assert.NotNil(m.Predict("bob", m.Players[1].Name))
+	assert.Equal(m.Players[0].Name, m.Predictions[0].Winner)
+}
+
+func TestPredictionsAreScoredAtEnd(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTournament(8)
+	assert.Nil(tm.StartTournament())
+
+	// playMatch has the first player of the match win
+	m := tm.Tryouts[0]
+	assert.Nil(m.Predict("alice", m.Players[0].Name))
+	assert.Nil(m.Predict("bob", m.Players[1].Name))
+	playMatch(m)
+
+	assert.Equal(PredictionPoints, m.Predictions[0].Points)
+	assert.Equal(0, m.Predictions[1].Points)
+
+	m = tm.Tryouts[1]
+	assert.Nil(m.Predict("bob", m.Players[0].Name))
+	assert.Nil(m.Predict("carol", m.Players[0].Name))
+	assert.Nil(m.Predict("dave", m.Players[3].Name))
+	playMatch(m)
+
+	lb := tm.Leaderboard()
+	assert.Equal(tm.ID, lb.Tournament)
+	assert.Equal(4, len(lb.Scores))
+	assert.Equal(SpectatorScore{Spectator: "alice", Points: 10, Correct: 1, Predictions: 1}, lb.Scores[0])
+	assert.Equal(SpectatorScore{Spectator: "bob", Points: 10, Correct: 1, Predictions: 2}, lb.Scores[1])
+	assert.Equal("carol", lb.Scores[2].Spectator)
+	assert.Equal(SpectatorScore{Spectator: "dave", Points: 0, Correct: 0, Predictions: 1}, lb.Scores[3])
+}
+
+func TestPredictTeams(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTeamTournament(4)
+	m := NewMatch(tm, 0, "final")
+	for _, p := range tm.Players {
+		_ = m.AddPlayer(p)
+	}
+
+	team := m.Players[0].Team
+	assert.Nil(m.Predict("alice", team))
+	assert.NotNil(m.Predict("alice", m.Players[0].Name))
+}
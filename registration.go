@@ -0,0 +1,195 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+)
+
+// WithdrawPlayer removes a player from the tournament
+//
+// Before the tournament has started, the player is simply removed and the
+// first player on the waitlist takes their place. After it
+// has started, the player is kept for their stats but forfeits all the
+// matches they have not played, is removed from the runnerups and cannot
+// advance from a match they are currently playing. In team mode, the whole
+// team withdraws.
+func (t *Tournament) WithdrawPlayer(name string) error {
+	if t.WaitlistPosition(name) != 0 {
+		return t.LeaveWaitlist(name)
+	}
+
+	p := t.getPlayer(name)
+	if name == "" || p == nil {
+		return fmt.Errorf("no player named %s", name)
+	}
+	if p.Withdrawn {
+		return fmt.Errorf("%s has already withdrawn", name)
+	}
+
+	names := []string{name}
+	if team := t.getTeam(p.Team); team != nil {
+		names = team.Members
+	}
+
+	if t.Started.IsZero() {
+		t.removePlayers(names)
+		t.ShufflePlayers()
+		t.promoteWaitlist()
+		return t.Persist()
+	}
+
+	for _, n := range names {
+		t.getPlayer(n).Withdrawn = true
+		t.removeRunnerups([]Player{{Name: n}})
+
+		for _, m := range t.Matches() {
+			if m.IsStarted() || m.IsDone() || m.getPlayer(n) == nil {
+				continue
+			}
+			m.removePlayer(n)
+			m.Forfeits = append(m.Forfeits, n)
+			m.updateReadiness()
+		}
+	}
+
+	return t.Persist()
+}
+
+// LateRegister adds a player into a tournament that has already started
+//
+// The player is shuffled together with the players of the tryouts that have
+// not yet started, as long as they have room. If there is no room, the player
+// is put into the runnerup pool and will be backfilled into a match later.
+func (t *Tournament) LateRegister(name, color string) error {
+	if !t.IsRunning() {
+		return errors.New("late registration is only for running tournaments")
+	}
+	if t.IsTeamMode() {
+		return errors.New("team tournaments cannot be joined late")
+	}
+	if name == "" || !t.CanJoin(name) {
+		return fmt.Errorf("%s cannot join", name)
+	}
+
+	t.Players = append(t.Players, Player{Name: name, PreferredColor: color})
+
+	open := []*Match{}
+	free := 0
+	for _, m := range t.Tryouts {
+		if !m.IsStarted() && !m.IsDone() {
+			open = append(open, m)
+			free += 4 - m.ActualPlayers()
+		}
+	}
+
+	if free == 0 {
+		t.Runnerups = append(t.Runnerups, name)
+		return t.Persist()
+	}
+
+	// Reshuffle the unstarted tryouts, with the new player in the mix
+	ps := []Player{*t.getPlayer(name)}
+	for _, m := range open {
+		for _, p := range m.Players {
+			if !p.IsPrefill() {
+				ps = append(ps, p)
+			}
+		}
+		m.Players = []Player{}
+	}
+
+	for i := range ps {
+		j := rand.Intn(i + 1)
+		ps[i], ps[j] = ps[j], ps[i]
+	}
+
+	for i, p := range ps {
+		open[i/4].AddPlayer(p)
+	}
+
+	for _, m := range open {
+		m.Prefill()
+
+		// Check-ins only count for the match they were made for
+		checked := []string{}
+		for _, n := range m.CheckedIn {
+			if m.getPlayer(n) != nil {
+				checked = append(checked, n)
+			}
+		}
+		m.CheckedIn = checked
+	}
+
+	t.updateReadiness()
+	return t.Persist()
+}
+
+// IsWithdrawn returns boolean whether the named player has withdrawn
+func (t *Tournament) IsWithdrawn(name string) bool {
+	for _, p := range t.Players {
+		if p.Name == name {
+			return p.Withdrawn
+		}
+	}
+	return false
+}
+
+// removePlayers removes players from the tournament before it has started
+func (t *Tournament) removePlayers(names []string) {
+	ps := make([]Player, 0, len(t.Players))
+	for _, p := range t.Players {
+		keep := true
+		for _, n := range names {
+			if p.Name == n {
+				keep = false
+				break
+			}
+		}
+		if keep {
+			ps = append(ps, p)
+		}
+	}
+	t.Players = ps
+
+	for i, team := range t.Teams {
+		if team.HasMember(names[0]) {
+			t.Teams = append(t.Teams[:i], t.Teams[i+1:]...)
+			break
+		}
+	}
+
+	// Go back to four tryouts if we are no longer over 16 players
+	if len(t.Tryouts) == 8 && len(t.Players) <= 16 {
+		t.Tryouts = t.Tryouts[:4]
+	}
+}
+
+// withoutWithdrawn filters out the players that have withdrawn
+func (t *Tournament) withoutWithdrawn(ps []Player) []Player {
+	out := make([]Player, 0, len(ps))
+	for _, p := range ps {
+		if !t.IsWithdrawn(p.Name) {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// withoutWithdrawnTeams filters out the teams that have withdrawn
+func (t *Tournament) withoutWithdrawnTeams(ts []TeamScore) []TeamScore {
+	out := make([]TeamScore, 0, len(ts))
+	for _, s := range ts {
+		withdrawn := false
+		for _, p := range s.Players {
+			if t.IsWithdrawn(p.Name) {
+				withdrawn = true
+				break
+			}
+		}
+		if !withdrawn {
+			out = append(out, s)
+		}
+	}
+	return out
+}
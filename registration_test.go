@@ -0,0 +1,155 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestWithdrawBeforeStartRemovesPlayer(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTournament(10)
+
+	assert.Nil(tm.WithdrawPlayer("3"))
+	assert.Equal(9, len(tm.Players))
+	assert.Nil(tm.getPlayer("3"))
+
+	total := 0
+	for _, m := range tm.Tryouts {
+		total += m.ActualPlayers()
+	}
+	assert.Equal(9, total)
+}
+
+func TestWithdrawBeforeStartShrinksTryouts(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTournament(17)
+	assert.Equal(8, len(tm.Tryouts))
+
+	assert.Nil(tm.WithdrawPlayer("17"))
+	assert.Equal(4, len(tm.Tryouts))
+}
+
+func TestWithdrawUnknownPlayerFails(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTournament(8)
+
+	assert.NotNil(tm.WithdrawPlayer("nope"))
+}
+
+func TestWithdrawAfterStartForfeitsUnplayedMatch(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTournament(16)
+	tm.StartTournament()
+
+	m := tm.Tryouts[2]
+	name := m.Players[1].Name
+	assert.Nil(tm.WithdrawPlayer(name))
+
+	assert.True(tm.IsWithdrawn(name))
+	assert.Equal(16, len(tm.Players))
+	assert.Nil(m.getPlayer(name))
+	assert.True(m.HasForfeited(name))
+}
+
+func TestWithdrawnPlayerDoesNotAdvance(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTournament(16)
+	tm.StartTournament()
+
+	m := tm.Tryouts[0]
+	m.Start()
+	m.Players[0].AddKill(10)
+	m.Players[1].AddKill(8)
+	m.Players[2].AddKill(6)
+	m.Players[3].AddKill(4)
+
+	gone := m.Players[0].Name
+	assert.Nil(tm.WithdrawPlayer(gone))
+	assert.Nil(m.End())
+
+	// Second and third place advance instead
+	assert.Equal(m.Players[1].Name, tm.Semis[0].Players[0].Name)
+	assert.Equal(m.Players[2].Name, tm.Semis[1].Players[0].Name)
+	assert.NotContains(tm.Runnerups, gone)
+	assert.Equal(1, len(tm.Runnerups))
+}
+
+func TestWithdrawnRunnerupIsRemoved(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTournament(16)
+	tm.StartTournament()
+	playMatch(tm.Tryouts[0])
+
+	r := tm.Runnerups[0]
+	assert.Nil(tm.WithdrawPlayer(r))
+	assert.NotContains(tm.Runnerups, r)
+}
+
+func TestWithdrawTeam(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTeamTournament(6)
+
+	assert.Nil(tm.WithdrawPlayer("2a"))
+	assert.Equal(5, len(tm.Teams))
+	assert.Equal(10, len(tm.Players))
+	assert.Nil(tm.getTeam("team 2"))
+}
+
+func TestLateRegisterIntoUnstartedTryout(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTournament(10)
+	tm.StartTournament()
+
+	started := tm.Tryouts[0]
+	started.Start()
+	before := make([]string, 0, 4)
+	for _, p := range started.Players {
+		before = append(before, p.Name)
+	}
+
+	assert.Nil(tm.LateRegister("late", "green"))
+	assert.Equal(11, len(tm.Players))
+
+	found := false
+	for _, m := range tm.Tryouts[1:] {
+		if m.getPlayer("late") != nil {
+			found = true
+		}
+	}
+	assert.True(found)
+
+	// The started tryout is left alone
+	for i, p := range started.Players {
+		assert.Equal(before[i], p.Name)
+	}
+
+	total := 0
+	for _, m := range tm.Tryouts {
+		total += m.ActualPlayers()
+	}
+	assert.Equal(11, total)
+}
+
+func TestLateRegisterWithoutRoomGoesToRunnerups(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTournament(16)
+	tm.StartTournament()
+
+	assert.Nil(tm.LateRegister("late", "green"))
+	assert.Contains(tm.Runnerups, "late")
+}
+
+func TestLateRegisterBeforeStartFails(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTournament(8)
+
+	assert.NotNil(tm.LateRegister("late", "green"))
+}
+
+func TestAddPlayerAfterStartFails(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTournament(8)
+	tm.StartTournament()
+
+	assert.NotNil(tm.AddPlayer("late", "green"))
+}
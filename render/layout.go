@@ -0,0 +1,130 @@
+package render
+
+import (
+	"image"
+)
+
+const (
+	margin       = 20
+	titleHeight  = 40
+	boxWidth     = 220
+	headerHeight = 26
+	rowHeight    = 24
+	columnGap    = 60
+	rowGap       = 24
+	scoreWidth   = 360
+	scoreRow     = 40
+)
+
+// box is a match placed on the canvas
+type box struct {
+	Match
+	Rect image.Rectangle
+	Row  int
+
+	// Scoreboards have more room and also show the shots
+	Detailed bool
+}
+
+// layout is where everything goes on the canvas
+type layout struct {
+	Title  string
+	Width  int
+	Height int
+	Boxes  []box
+	Lines  [][]image.Point
+}
+
+// bracketLayout places the matches in their columns and draws the lines
+// between them
+//
+// The matches of a column are spread evenly over the height of the tallest
+// column, which makes each round line up between the matches feeding it.
+func bracketLayout(b Bracket) layout {
+	columns := [][]Match{}
+	for _, m := range b.Matches {
+		for len(columns) <= m.Column {
+			columns = append(columns, []Match{})
+		}
+		columns[m.Column] = append(columns[m.Column], m)
+	}
+
+	height := 0
+	for _, c := range columns {
+		h := 0
+		for _, m := range c {
+			h += boxHeight(m, rowHeight) + rowGap
+		}
+		if h > height {
+			height = h
+		}
+	}
+
+	l := layout{
+		Title:  b.Title,
+		Width:  2*margin + len(columns)*(boxWidth+columnGap) - columnGap,
+		Height: 2*margin + titleHeight + height,
+	}
+	if len(columns) == 0 {
+		l.Width = 2*margin + boxWidth
+	}
+
+	pos := make(map[string]image.Rectangle)
+	for i, c := range columns {
+		x := margin + i*(boxWidth+columnGap)
+		for j, m := range c {
+			h := boxHeight(m, rowHeight)
+			mid := margin + titleHeight + (2*j+1)*height/(2*len(c))
+			r := image.Rect(x, mid-h/2, x+boxWidth, mid-h/2+h)
+
+			l.Boxes = append(l.Boxes, box{Match: m, Rect: r, Row: rowHeight})
+			pos[m.ID] = r
+		}
+	}
+
+	for _, e := range b.Edges {
+		from, ok := pos[e.From]
+		if !ok {
+			continue
+		}
+		to, ok := pos[e.To]
+		if !ok {
+			continue
+		}
+
+		y1 := (from.Min.Y + from.Max.Y) / 2
+		y2 := (to.Min.Y + to.Max.Y) / 2
+		x := (from.Max.X + to.Min.X) / 2
+		l.Lines = append(l.Lines, []image.Point{
+			{from.Max.X, y1},
+			{x, y1},
+			{x, y2},
+			{to.Min.X, y2},
+		})
+	}
+
+	return l
+}
+
+// scoreboardLayout places a single match on its own canvas
+func scoreboardLayout(m Match) layout {
+	h := boxHeight(m, scoreRow)
+	r := image.Rect(margin, margin, margin+scoreWidth, margin+h)
+	return layout{
+		Width:  2*margin + scoreWidth,
+		Height: 2*margin + h,
+		Boxes:  []box{{Match: m, Rect: r, Row: scoreRow, Detailed: true}},
+	}
+}
+
+func boxHeight(m Match, row int) int {
+	return headerHeight + len(m.Slots)*row
+}
+
+// archerColor returns the hex code of an archer
+func (r *Renderer) archerColor(id string) string {
+	if c, ok := r.hex[id]; ok {
+		return c
+	}
+	return "#777777"
+}
@@ -0,0 +1,137 @@
+package render
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"strconv"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+// BracketPNG draws the bracket as a PNG
+func (r *Renderer) BracketPNG(b Bracket) ([]byte, error) {
+	return r.png(bracketLayout(b))
+}
+
+// ScoreboardPNG draws the scoreboard of a match as a PNG
+func (r *Renderer) ScoreboardPNG(m Match) ([]byte, error) {
+	return r.png(scoreboardLayout(m))
+}
+
+// png rasterizes a layout the same way as it is drawn in the SVG
+func (r *Renderer) png(l layout) ([]byte, error) {
+	img := image.NewRGBA(image.Rect(0, 0, l.Width, l.Height))
+	fill(img, img.Bounds(), background)
+
+	if l.Title != "" {
+		if err := r.text(img, l.Title, margin, margin+titleHeight/2+8, 24, textColor, false); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, line := range l.Lines {
+		for i := 1; i < len(line); i++ {
+			a, b := line[i-1], line[i]
+			if a.X > b.X || a.Y > b.Y {
+				a, b = b, a
+			}
+			fill(img, image.Rect(a.X-1, a.Y-1, b.X+1, b.Y+1), lineColor)
+		}
+	}
+
+	for _, x := range l.Boxes {
+		if err := r.pngBox(img, x); err != nil {
+			return nil, err
+		}
+	}
+
+	var out bytes.Buffer
+	if err := png.Encode(&out, img); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+func (r *Renderer) pngBox(img *image.RGBA, x box) error {
+	rect := x.Rect
+	fill(img, rect.Inset(-1), stateColor(x.State))
+	fill(img, rect.Inset(1), boxColor)
+
+	err := r.text(img, x.Title, rect.Min.X+8, rect.Min.Y+headerHeight-8, 14, textColor, false)
+	if err != nil {
+		return err
+	}
+
+	for i, s := range x.Slots {
+		top := rect.Min.Y + headerHeight + i*x.Row
+		if s.Name == "" {
+			continue
+		}
+
+		icon := archerRect(rect.Min.X+8, top, x.Row)
+		if a, ok := r.archers[s.Color]; ok {
+			draw.ApproxBiLinear.Scale(img, icon, a, a.Bounds(), draw.Over, nil)
+		}
+
+		fg := textColor
+		if s.Placement == 1 {
+			fg = gold
+		}
+		size := x.Row * 2 / 3
+		base := top + x.Row/2 + size/3
+
+		err := r.text(img, s.Name, icon.Max.X+8, base, size, fg, false)
+		if err != nil {
+			return err
+		}
+		err = r.text(img, scoreText(s, x.Detailed), rect.Max.X-8, base, size, r.archerColor(s.Color), true)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// text draws a string with its baseline at y. Right aligned text ends at x.
+func (r *Renderer) text(img *image.RGBA, s string, x, y, size int, hex string, right bool) error {
+	face, err := opentype.NewFace(r.font, &opentype.FaceOptions{
+		Size:    float64(size),
+		DPI:     72,
+		Hinting: font.HintingFull,
+	})
+	if err != nil {
+		return err
+	}
+	defer face.Close()
+
+	d := font.Drawer{
+		Dst:  img,
+		Src:  image.NewUniform(parseHex(hex)),
+		Face: face,
+	}
+	s = label(s)
+	if right {
+		x -= d.MeasureString(s).Round()
+	}
+	d.Dot = fixed.P(x, y)
+	d.DrawString(s)
+	return nil
+}
+
+func fill(img *image.RGBA, r image.Rectangle, hex string) {
+	draw.Draw(img, r, image.NewUniform(parseHex(hex)), image.Point{}, draw.Src)
+}
+
+// parseHex turns a #rrggbb code into a color
+func parseHex(hex string) color.Color {
+	v, err := strconv.ParseUint(hex[1:], 16, 32)
+	if err != nil {
+		return color.Black
+	}
+	return color.RGBA{uint8(v >> 16), uint8(v >> 8), uint8(v), 0xff}
+}
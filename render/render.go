@@ -0,0 +1,107 @@
+// Package render draws the tournament bracket and match scoreboards as images
+//
+// The images are meant for the stream overlays, so they are drawn on the
+// server without any browser involved. Everything is rendered both as SVG and
+// as PNG from the same layout.
+package render
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"io/ioutil"
+	"path/filepath"
+
+	"golang.org/x/image/font/opentype"
+)
+
+// Archer is an archer skin that can be drawn in a slot
+//
+// The image is relative to the static directory.
+type Archer struct {
+	ID    string
+	Hex   string
+	Image string
+}
+
+// Bracket is what gets drawn as the bracket
+type Bracket struct {
+	Title   string  `json:"title"`
+	Matches []Match `json:"matches"`
+	Edges   []Edge  `json:"edges"`
+}
+
+// Match is a match box in the bracket, or a whole scoreboard
+//
+// The column is what round of the bracket the match is drawn in.
+type Match struct {
+	ID     string `json:"id"`
+	Title  string `json:"title"`
+	State  string `json:"state"`
+	Column int    `json:"column"`
+	Slots  []Slot `json:"slots"`
+}
+
+// Slot is a player in a match. Empty slots have no name.
+type Slot struct {
+	Name      string `json:"name"`
+	Color     string `json:"color"`
+	Kills     int    `json:"kills"`
+	Shots     int    `json:"shots"`
+	Placement int    `json:"placement"`
+}
+
+// Edge is a line from one match to the match it feeds into
+type Edge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// Renderer holds the assets that the images are drawn with
+type Renderer struct {
+	fontData []byte
+	font     *opentype.Font
+	archers  map[string]image.Image
+	pngs     map[string][]byte
+	hex      map[string]string
+}
+
+// NewRenderer loads the font and the images of the archers from the static
+// directory
+func NewRenderer(static string, archers []Archer) (*Renderer, error) {
+	data, err := ioutil.ReadFile(filepath.Join(static, "Archer.ttf"))
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := opentype.Parse(data)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Renderer{
+		fontData: data,
+		font:     f,
+		archers:  make(map[string]image.Image),
+		pngs:     make(map[string][]byte),
+		hex:      make(map[string]string),
+	}
+
+	for _, a := range archers {
+		data, err := ioutil.ReadFile(filepath.Join(static, a.Image))
+		if err != nil {
+			return nil, err
+		}
+
+		img, err := png.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+
+		r.archers[a.ID] = img
+		r.pngs[a.ID] = data
+		r.hex[a.ID] = a.Hex
+	}
+
+	return r, nil
+}
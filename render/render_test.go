@@ -0,0 +1,97 @@
+package render
+
+import (
+	"bytes"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testArchers = []Archer{
+	{ID: "green", Hex: "#4fb84a", Image: "img/green-selected.png"},
+	{ID: "blue-alt", Hex: "#224e7c", Image: "img/blue-alt-selected.png"},
+}
+
+func testBracket() Bracket {
+	slots := []Slot{
+		{Name: "Alice", Color: "green", Kills: 10, Placement: 1},
+		{Name: "Bob & Co", Color: "blue-alt", Kills: 7, Placement: 2},
+		{Name: "Carol", Color: "nope", Kills: 3},
+		{},
+	}
+	return Bracket{
+		Title: "TEST TOURNAMENT",
+		Matches: []Match{
+			{ID: "tryout-0", Title: "Tryout 1/2", State: "ended", Slots: slots},
+			{ID: "tryout-1", Title: "Tryout 2/2", State: "playing", Slots: slots},
+			{ID: "final-0", Title: "Final", State: "scheduled", Column: 1, Slots: make([]Slot, 4)},
+		},
+		Edges: []Edge{
+			{From: "tryout-0", To: "final-0"},
+			{From: "tryout-1", To: "final-0"},
+			{From: "tryout-1", To: "nowhere"},
+		},
+	}
+}
+
+func TestBracketLayout(t *testing.T) {
+	assert := assert.New(t)
+	l := bracketLayout(testBracket())
+
+	assert.Equal(3, len(l.Boxes))
+	assert.Equal(2, len(l.Lines))
+	assert.Equal(2*margin+2*boxWidth+columnGap, l.Width)
+
+	// The final is centered between the tryouts feeding it
+	t0, t1, f := l.Boxes[0].Rect, l.Boxes[1].Rect, l.Boxes[2].Rect
+	assert.True(t0.Max.Y <= t1.Min.Y)
+	assert.Equal((t0.Min.Y+t1.Max.Y)/2, (f.Min.Y+f.Max.Y)/2)
+}
+
+func TestBracketSVG(t *testing.T) {
+	assert := assert.New(t)
+	r, err := NewRenderer("../static", testArchers)
+	assert.Nil(err)
+
+	svg := string(r.BracketSVG(testBracket()))
+	assert.True(strings.HasPrefix(svg, "<svg"))
+	assert.Contains(svg, "TEST TOURNAMENT")
+	assert.Contains(svg, "BOB &amp; CO")
+	assert.Contains(svg, `xlink:href="#archer-green"`)
+	assert.Contains(svg, `xlink:href="#archer-blue-alt"`)
+	assert.NotContains(svg, "#archer-nope")
+}
+
+func TestBracketPNG(t *testing.T) {
+	assert := assert.New(t)
+	r, err := NewRenderer("../static", testArchers)
+	assert.Nil(err)
+
+	data, err := r.BracketPNG(testBracket())
+	assert.Nil(err)
+
+	img, err := png.Decode(bytes.NewReader(data))
+	assert.Nil(err)
+	l := bracketLayout(testBracket())
+	assert.Equal(l.Width, img.Bounds().Dx())
+	assert.Equal(l.Height, img.Bounds().Dy())
+}
+
+func TestScoreboardPNG(t *testing.T) {
+	assert := assert.New(t)
+	r, err := NewRenderer("../static", testArchers)
+	assert.Nil(err)
+
+	data, err := r.ScoreboardPNG(testBracket().Matches[0])
+	assert.Nil(err)
+	_, err = png.Decode(bytes.NewReader(data))
+	assert.Nil(err)
+	assert.Contains(string(r.ScoreboardSVG(testBracket().Matches[0])), "10 KILLS")
+}
+
+func TestNewRendererWithoutAssetsFails(t *testing.T) {
+	_, err := NewRenderer("nope", testArchers)
+	assert.NotNil(t, err)
+}
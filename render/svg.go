@@ -0,0 +1,141 @@
+package render
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"image"
+	"sort"
+	"strings"
+)
+
+const (
+	background = "#1d1d1d"
+	boxColor   = "#2b2b2b"
+	textColor  = "#f2f2f2"
+	lineColor  = "#777777"
+	gold       = "#e5c34b"
+)
+
+// BracketSVG draws the bracket as an SVG
+func (r *Renderer) BracketSVG(b Bracket) []byte {
+	return r.svg(bracketLayout(b))
+}
+
+// ScoreboardSVG draws the scoreboard of a match as an SVG
+func (r *Renderer) ScoreboardSVG(m Match) []byte {
+	return r.svg(scoreboardLayout(m))
+}
+
+// svg writes out a layout as a standalone SVG document
+//
+// The font and the archers are embedded so that the overlay does not need to
+// fetch anything else.
+func (r *Renderer) svg(l layout) []byte {
+	var b bytes.Buffer
+
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink" width="%d" height="%d" viewBox="0 0 %d %d">`, l.Width, l.Height, l.Width, l.Height)
+	b.WriteString("<defs><style>")
+	fmt.Fprintf(&b, `@font-face{font-family:Archer;src:url(data:font/ttf;base64,%s)}`, base64.StdEncoding.EncodeToString(r.fontData))
+	fmt.Fprintf(&b, `text{font-family:Archer;fill:%s}`, textColor)
+	b.WriteString("</style>")
+
+	colors := make([]string, 0, len(r.pngs))
+	for c := range r.pngs {
+		colors = append(colors, c)
+	}
+	sort.Strings(colors)
+	for _, c := range colors {
+		fmt.Fprintf(&b, `<image id="archer-%s" width="60" height="120" xlink:href="data:image/png;base64,%s"/>`, c, base64.StdEncoding.EncodeToString(r.pngs[c]))
+	}
+	b.WriteString("</defs>")
+
+	fmt.Fprintf(&b, `<rect width="100%%" height="100%%" fill="%s"/>`, background)
+	if l.Title != "" {
+		fmt.Fprintf(&b, `<text x="%d" y="%d" font-size="24">%s</text>`, margin, margin+titleHeight/2+8, escape(label(l.Title)))
+	}
+
+	for _, line := range l.Lines {
+		ps := make([]string, 0, len(line))
+		for _, p := range line {
+			ps = append(ps, fmt.Sprintf("%d,%d", p.X, p.Y))
+		}
+		fmt.Fprintf(&b, `<polyline points="%s" fill="none" stroke="%s" stroke-width="2"/>`, strings.Join(ps, " "), lineColor)
+	}
+
+	for _, x := range l.Boxes {
+		r.svgBox(&b, x)
+	}
+
+	b.WriteString("</svg>")
+	return b.Bytes()
+}
+
+func (r *Renderer) svgBox(b *bytes.Buffer, x box) {
+	rect := x.Rect
+	fmt.Fprintf(b, `<rect x="%d" y="%d" width="%d" height="%d" fill="%s" stroke="%s" stroke-width="2"/>`, rect.Min.X, rect.Min.Y, rect.Dx(), rect.Dy(), boxColor, stateColor(x.State))
+	fmt.Fprintf(b, `<text x="%d" y="%d" font-size="14">%s</text>`, rect.Min.X+8, rect.Min.Y+headerHeight-8, escape(label(x.Title)))
+
+	for i, s := range x.Slots {
+		top := rect.Min.Y + headerHeight + i*x.Row
+		if s.Name == "" {
+			continue
+		}
+
+		icon := archerRect(rect.Min.X+8, top, x.Row)
+		if _, ok := r.pngs[s.Color]; ok {
+			fmt.Fprintf(b, `<use xlink:href="#archer-%s" transform="translate(%d %d) scale(%g)"/>`, s.Color, icon.Min.X, icon.Min.Y, float64(icon.Dy())/120)
+		}
+
+		fill := textColor
+		if s.Placement == 1 {
+			fill = gold
+		}
+		size := x.Row * 2 / 3
+		base := top + x.Row/2 + size/3
+		fmt.Fprintf(b, `<text x="%d" y="%d" font-size="%d" style="fill:%s">%s</text>`, icon.Max.X+8, base, size, fill, escape(label(s.Name)))
+		fmt.Fprintf(b, `<text x="%d" y="%d" font-size="%d" text-anchor="end" style="fill:%s">%s</text>`, rect.Max.X-8, base, size, r.archerColor(s.Color), escape(label(scoreText(s, x.Detailed))))
+	}
+}
+
+// archerRect returns where the archer icon of a row is drawn
+//
+// The archer images are twice as tall as they are wide.
+func archerRect(x, top, row int) image.Rectangle {
+	h := row - 4
+	return image.Rect(x, top+2, x+h/2, top+2+h)
+}
+
+// scoreText returns the score to show for a player
+func scoreText(s Slot, detailed bool) string {
+	if detailed {
+		return fmt.Sprintf("%d kills  %d shots", s.Kills, s.Shots)
+	}
+	return fmt.Sprintf("%d", s.Kills)
+}
+
+// stateColor returns the border color of a match in a given state
+func stateColor(state string) string {
+	switch state {
+	case "playing", "awaiting-confirmation":
+		return gold
+	case "ended":
+		return lineColor
+	}
+	return boxColor
+}
+
+// label returns how a text is written out
+//
+// The lowercase letters of the archer font are not all readable, so
+// everything is in capitals like on the score screen of the game.
+func label(s string) string {
+	return strings.ToUpper(s)
+}
+
+func escape(s string) string {
+	var b bytes.Buffer
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
@@ -0,0 +1,85 @@
+package main
+
+// addRunnerupRounds sets up the runner-up rounds once all tryouts are done
+//
+// Everyone in the runnerup roster gets to play another match while the semis
+// are running. The roster is sorted by score, so dealing the players out
+// round-robin spreads the strong ones over the matches. Nobody advances from
+// a runner-up round, but the scores count towards who is first in line when
+// a later match needs backfilling.
+func (t *Tournament) addRunnerupRounds() {
+	if len(t.RunnerupRounds) != 0 {
+		return
+	}
+	for _, m := range t.Tryouts {
+		if !m.IsDone() {
+			return
+		}
+	}
+
+	// The runner-up teams of the smallest team tournaments are all needed
+	// in the semis
+	if t.IsTeamMode() && len(t.Teams) == MinTeams {
+		return
+	}
+
+	groups := t.runnerupGroups()
+	if len(groups) < 2 {
+		return
+	}
+
+	// Solo players fill up to four per match. Teams play two against two,
+	// so with an odd number of teams the lowest scoring one sits out.
+	count := (len(groups) + 3) / 4
+	if t.IsTeamMode() {
+		count = len(groups) / 2
+		groups = groups[:count*2]
+	}
+
+	for i := 0; i < count; i++ {
+		t.RunnerupRounds = append(t.RunnerupRounds, NewMatch(t, i, "runnerup"))
+	}
+	for i, g := range groups {
+		m := t.RunnerupRounds[i%count]
+		for _, name := range g {
+			m.AddPlayer(*t.getPlayer(name))
+		}
+	}
+}
+
+// runnerupGroups returns the runnerups in the groups they are seated by
+//
+// In team mode, a group is a team. Otherwise every player is their own.
+func (t *Tournament) runnerupGroups() [][]string {
+	groups := [][]string{}
+	seen := make(map[string]bool)
+	for _, name := range t.Runnerups {
+		if seen[name] {
+			continue
+		}
+
+		g := []string{name}
+		p := t.getPlayer(name)
+		if p != nil && p.Team != "" {
+			if team := t.getTeam(p.Team); team != nil {
+				g = team.Members
+			}
+		}
+
+		for _, n := range g {
+			seen[n] = true
+		}
+		groups = append(groups, g)
+	}
+	return groups
+}
+
+// isPlaying returns boolean whether the player is in a match being played
+func (t *Tournament) isPlaying(name string) bool {
+	for _, m := range t.Matches() {
+		if m.IsStarted() && !m.IsDone() && m.getPlayer(name) != nil {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// requestMatch returns what getMatch finds for a path like "/id/kind/index/"
+func requestMatch(s *Server, path string) *Match {
+	var m *Match
+	r := mux.NewRouter()
+	r.HandleFunc("/{id}/{kind}/{index:[0-9]+}/", func(w http.ResponseWriter, r *http.Request) {
+		m = s.getMatch(r)
+	})
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	return m
+}
+
+func TestRunnerupRoundsAreAddedWhenTryoutsEnd(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTournament(16)
+	tm.StartTournament()
+
+	for _, m := range tm.Tryouts[:3] {
+		playMatch(m)
+	}
+	assert.Equal(0, len(tm.RunnerupRounds))
+
+	playMatch(tm.Tryouts[3])
+	assert.Equal(2, len(tm.RunnerupRounds))
+	for i, m := range tm.RunnerupRounds {
+		assert.Equal("runnerup", m.Kind)
+		assert.Equal(i, m.Index)
+		assert.Equal(4, m.ActualPlayers())
+		assert.Equal(MatchReady, m.State)
+	}
+}
+
+func TestRunnerupRoundsAreSpreadEvenly(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTournament(17)
+	tm.StartTournament()
+
+	for _, m := range tm.Tryouts {
+		playMatch(m)
+	}
+
+	assert.Equal(9, len(tm.Runnerups))
+	assert.Equal(3, len(tm.RunnerupRounds))
+	for _, m := range tm.RunnerupRounds {
+		assert.Equal(3, m.ActualPlayers())
+	}
+}
+
+func TestRunnerupRoundsArePlayedAlongsideSemis(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTournament(16)
+	tm.StartTournament()
+	endTryouts(tm)
+
+	ps := tm.Playable()
+	assert.Equal(4, len(ps))
+	assert.Contains(ps, tm.RunnerupRounds[0])
+	assert.Contains(ps, tm.Semis[0])
+
+	// A short runner-up round starts without backfilling
+	m := tm.RunnerupRounds[1]
+	m.NoShow(m.Players[0].Name)
+	assert.Nil(m.Start())
+	assert.Equal(3, m.ActualPlayers())
+}
+
+func TestRunnerupRoundDoesNotAdvanceAnyone(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTournament(16)
+	tm.StartTournament()
+	endTryouts(tm)
+
+	semis := [][]Player{tm.Semis[0].Players, tm.Semis[1].Players}
+	playMatch(tm.RunnerupRounds[0])
+
+	assert.True(tm.RunnerupRounds[0].IsEnded())
+	assert.Equal(semis[0], tm.Semis[0].Players)
+	assert.Equal(semis[1], tm.Semis[1].Players)
+	assert.Equal(8, len(tm.Runnerups))
+}
+
+func TestRunnerupRoundScoresAreCounted(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTournament(16)
+	tm.StartTournament()
+	endTryouts(tm)
+
+	m := tm.RunnerupRounds[0]
+	winner := m.Players[0].Name
+	before := tm.getPlayer(winner).Kills
+
+	playMatch(m)
+	tm.UpdatePlayers()
+	assert.Equal(before+m.Players[0].Kills, tm.getPlayer(winner).Kills)
+}
+
+func TestBackfilledPlayersAreRecorded(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTournament(10)
+	tm.StartTournament()
+
+	total := 0
+	for _, m := range tm.Tryouts {
+		missing := 4 - m.ActualPlayers()
+		playMatch(m)
+		assert.Equal(missing, len(m.Backfilled))
+		for _, name := range m.Backfilled {
+			assert.NotNil(m.getPlayer(name))
+		}
+		total += len(m.Backfilled)
+	}
+	assert.Equal(6, total)
+}
+
+func TestRunnerupRoundsInTeamMode(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTeamTournament(8)
+	tm.StartTournament()
+
+	for _, m := range tm.Tryouts {
+		playMatch(m)
+	}
+
+	assert.Equal(2, len(tm.RunnerupRounds))
+	for _, m := range tm.RunnerupRounds {
+		assert.Equal(2, len(m.TeamStandings()))
+	}
+}
+
+func TestGetMatchBeforeRunnerupRounds(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTournament(16)
+	tm.StartTournament()
+	tm.db.list(tm)
+	s := tm.server
+
+	assert.Equal(tm.Tryouts[1], requestMatch(s, "/16/tryout/1/"))
+	assert.Equal(tm.Final, requestMatch(s, "/16/final/0/"))
+	assert.Nil(requestMatch(s, "/16/runnerup/0/"))
+	assert.Nil(requestMatch(s, "/16/tryout/4/"))
+	assert.Nil(requestMatch(s, "/16/final/1/"))
+	assert.Nil(requestMatch(s, "/nope/tryout/0/"))
+
+	endTryouts(tm)
+	assert.Equal(tm.RunnerupRounds[0], requestMatch(s, "/16/runnerup/0/"))
+}
+
+func TestVoidedLastTryoutAddsRunnerupRounds(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTournament(16)
+	tm.StartTournament()
+
+	for _, m := range tm.Tryouts[:3] {
+		playMatch(m)
+	}
+	assert.Nil(tm.Tryouts[3].Override(MatchVoided, "the TV broke"))
+
+	// Everyone in the voided tryout is a runnerup, ten in all
+	assert.Equal(10, len(tm.Runnerups))
+	assert.Equal(3, len(tm.RunnerupRounds))
+}
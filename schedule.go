@@ -0,0 +1,209 @@
+package main
+
+import (
+	"sort"
+	"time"
+)
+
+// DefaultMatchDuration is the assumed length of a match before any match in
+// the tournament has been played
+const DefaultMatchDuration = 12 * time.Minute
+
+// ScheduledMatch is a match that has yet to be played, with its predicted
+// start time
+type ScheduledMatch struct {
+	Match   *Match    `json:"match"`
+	Title   string    `json:"title"`
+	Station int       `json:"station"`
+	Start   time.Time `json:"start"`
+	End     time.Time `json:"end"`
+}
+
+// Schedule is the predicted order and timing of the remaining matches
+type Schedule struct {
+	Average  int              `json:"average"`
+	Stations int              `json:"stations"`
+	Matches  []ScheduledMatch `json:"matches"`
+	UpNext   []ScheduledMatch `json:"up_next"`
+	OnDeck   []ScheduledMatch `json:"on_deck"`
+}
+
+// AverageMatchDuration returns how long the ended matches of the tournament
+// took on average
+func (t *Tournament) AverageMatchDuration() time.Duration {
+	var total time.Duration
+	count := 0
+	for _, m := range t.Matches() {
+		if !m.IsStarted() || !m.IsEnded() {
+			continue
+		}
+		total += m.Ended.Sub(m.Started)
+		count++
+	}
+
+	if count == 0 {
+		return DefaultMatchDuration
+	}
+	return total / time.Duration(count)
+}
+
+// stationCount returns how many matches can be played at the same time
+func (t *Tournament) stationCount() int {
+	if len(t.Stations) != 0 {
+		return len(t.Stations)
+	}
+	if t.StationCount < 1 {
+		return 1
+	}
+	return t.StationCount
+}
+
+// Schedule predicts when the remaining matches will be played
+//
+// Matches are assumed to be started in order on whichever station frees up
+// first, but never before the matches they depend on are predicted to end.
+// A match that is assigned to a station is the next one played there, like
+// the judge at the station is told. The stations are numbered from 1.
+func (t *Tournament) Schedule(now time.Time) Schedule {
+	avg := t.AverageMatchDuration()
+	stations := make([]time.Time, t.stationCount())
+	for i := range stations {
+		stations[i] = now
+	}
+
+	ends := make(map[*Match]time.Time)
+	s := Schedule{
+		Average:  int(avg.Seconds()),
+		Stations: len(stations),
+	}
+
+	// Matches being played are occupying stations already
+	for _, m := range t.Matches() {
+		if !m.IsStarted() || m.IsDone() {
+			continue
+		}
+
+		end := m.Started.Add(avg)
+		if end.Before(now) {
+			end = now
+		}
+		ends[m] = end
+
+		i := earliestStation(stations, nil)
+		if m.Station > 0 && m.Station <= len(stations) {
+			i = m.Station - 1
+		}
+		stations[i] = end
+	}
+
+	// Stations with a match assigned are kept for it
+	reserved := make(map[int]bool)
+	for _, m := range t.Matches() {
+		if !m.IsStarted() && !m.IsDone() && m.Station > 0 && m.Station <= len(stations) {
+			reserved[m.Station-1] = true
+		}
+	}
+
+	for _, m := range t.Matches() {
+		if m.IsDone() {
+			ends[m] = m.Ended
+			continue
+		}
+		if m.IsStarted() {
+			continue
+		}
+
+		i := earliestStation(stations, reserved)
+		if m.Station > 0 && m.Station <= len(stations) {
+			i = m.Station - 1
+			delete(reserved, i)
+		}
+		start := stations[i]
+		for _, d := range t.dependencies(m) {
+			if e, ok := ends[d]; ok && e.After(start) {
+				start = e
+			}
+		}
+
+		end := start.Add(avg)
+		ends[m] = end
+		stations[i] = end
+
+		s.Matches = append(s.Matches, ScheduledMatch{
+			Match:   m,
+			Title:   m.Title(),
+			Station: i + 1,
+			Start:   start,
+			End:     end,
+		})
+	}
+
+	sort.SliceStable(s.Matches, func(i, j int) bool {
+		return s.Matches[i].Start.Before(s.Matches[j].Start)
+	})
+
+	for i, sm := range s.Matches {
+		if i < len(stations) {
+			s.UpNext = append(s.UpNext, sm)
+		} else if i < len(stations)*2 {
+			s.OnDeck = append(s.OnDeck, sm)
+		}
+	}
+
+	return s
+}
+
+// dependencies returns the matches that need to be done before a match can
+// be played
+func (t *Tournament) dependencies(m *Match) []*Match {
+	switch m.Kind {
+	case "tryout":
+		// Full tryouts can be played whenever. The ones that are backfilled
+		// with runnerups need to wait for the matches before them.
+		if m.ActualPlayers() == 4 {
+			return nil
+		}
+		for i, o := range t.Tryouts {
+			if o == m {
+				return t.Tryouts[:i]
+			}
+		}
+	case "semi":
+		// With more than four tryouts (or in team mode), only the winners
+		// advance and they are spread so that every other tryout feeds the
+		// same semi.
+		if len(t.Tryouts) > 4 || t.IsTeamMode() {
+			ds := []*Match{}
+			for _, o := range t.Tryouts {
+				if o.Index%2 == m.Index {
+					ds = append(ds, o)
+				}
+			}
+			return ds
+		}
+		return t.Tryouts
+	case "runnerup":
+		return t.Tryouts
+	case "final":
+		return t.Semis
+	}
+	return nil
+}
+
+// earliestStation returns the index of the station that frees up first,
+// leaving out the skipped ones unless all of them are
+func earliestStation(stations []time.Time, skip map[int]bool) int {
+	x := -1
+	for i, s := range stations {
+		if skip[i] {
+			continue
+		}
+		if x == -1 || s.Before(stations[x]) {
+			x = i
+		}
+	}
+	if x == -1 {
+		return earliestStation(stations, nil)
+	}
+	return x
+}
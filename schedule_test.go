@@ -0,0 +1,143 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestAverageMatchDurationDefault(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTournament(16)
+
+	assert.Equal(DefaultMatchDuration, tm.AverageMatchDuration())
+}
+
+func TestAverageMatchDuration(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTournament(16)
+	tm.StartTournament()
+	endTryouts(tm)
+
+	now := time.Now()
+	tm.Tryouts[0].Started = now.Add(-10 * time.Minute)
+	tm.Tryouts[0].Ended = now
+	tm.Tryouts[1].Started = now.Add(-20 * time.Minute)
+	tm.Tryouts[1].Ended = now
+	tm.Tryouts[2].Started = now.Add(-10 * time.Minute)
+	tm.Tryouts[2].Ended = now
+	tm.Tryouts[3].Started = now.Add(-20 * time.Minute)
+	tm.Tryouts[3].Ended = now
+
+	assert.Equal(15*time.Minute, tm.AverageMatchDuration())
+}
+
+func TestScheduleOneStation(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTournament(16)
+	tm.StartTournament()
+
+	now := time.Now()
+	s := tm.Schedule(now)
+
+	// Four tryouts, two semis and the final
+	assert.Equal(7, len(s.Matches))
+	for i, sm := range s.Matches {
+		assert.Equal(1, sm.Station)
+		assert.Equal(now.Add(time.Duration(i)*DefaultMatchDuration), sm.Start)
+	}
+
+	assert.Equal(1, len(s.UpNext))
+	assert.Equal(tm.Tryouts[0], s.UpNext[0].Match)
+	assert.Equal(1, len(s.OnDeck))
+	assert.Equal(tm.Tryouts[1], s.OnDeck[0].Match)
+}
+
+func TestScheduleTwoStations(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTournament(16)
+	tm.StartTournament()
+	tm.SetStationCount(2)
+
+	now := time.Now()
+	s := tm.Schedule(now)
+	d := DefaultMatchDuration
+
+	// The tryouts are played two at a time
+	assert.Equal(now, s.Matches[0].Start)
+	assert.Equal(now, s.Matches[1].Start)
+	assert.Equal(now.Add(d), s.Matches[2].Start)
+	assert.Equal(now.Add(d), s.Matches[3].Start)
+
+	// The semis need to wait for all the tryouts
+	assert.Equal(now.Add(2*d), s.Matches[4].Start)
+	assert.Equal(now.Add(2*d), s.Matches[5].Start)
+
+	// ...and the final for the semis
+	assert.Equal(now.Add(3*d), s.Matches[6].Start)
+
+	assert.Equal(2, len(s.UpNext))
+	assert.Equal(2, len(s.OnDeck))
+}
+
+func TestScheduleCountsPlayingMatch(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTournament(16)
+	tm.StartTournament()
+
+	now := time.Now()
+	tm.Tryouts[0].Start()
+	tm.Tryouts[0].Started = now.Add(-2 * time.Minute)
+
+	s := tm.Schedule(now)
+	assert.Equal(6, len(s.Matches))
+	assert.Equal(tm.Tryouts[1], s.Matches[0].Match)
+	assert.Equal(now.Add(DefaultMatchDuration-2*time.Minute), s.Matches[0].Start)
+}
+
+func TestScheduleWaitsForBackfilledTryouts(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTournament(10)
+	tm.StartTournament()
+	tm.SetStationCount(2)
+
+	now := time.Now()
+	s := tm.Schedule(now)
+	d := DefaultMatchDuration
+
+	// The two full tryouts are played in parallel, but the ones needing
+	// runnerups have to wait for them.
+	assert.Equal(now, s.Matches[1].Start)
+	assert.Equal(now.Add(d), s.Matches[2].Start)
+	assert.Equal(now.Add(2*d), s.Matches[3].Start)
+}
+
+func TestScheduleAgreesWithStations(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTournament(16)
+	tm.StartTournament()
+	tm.SetStationCount(2)
+	assert.Nil(tm.AssignMatch(tm.Tryouts[2], 2))
+
+	s := tm.Schedule(time.Now())
+	for _, sm := range s.Matches {
+		if sm.Match == tm.Tryouts[2] {
+			assert.Equal(2, sm.Station)
+		}
+	}
+
+	// The matches up next are what the judges at the stations are given
+	assert.Equal(2, len(s.UpNext))
+	for _, sm := range s.UpNext {
+		m, err := tm.NextStationMatch(sm.Station)
+		assert.Nil(err)
+		assert.Equal(sm.Match, m, "station %d", sm.Station)
+	}
+}
+
+func TestSetStationCountNeedsOneStation(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTournament(8)
+
+	assert.NotNil(tm.SetStationCount(0))
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// MaxIDLength is how long a tournament ID can be
+const MaxIDLength = 64
+
+// ErrIDTaken is returned when a tournament ID is already in use
+var ErrIDTaken = errors.New("there is already a tournament with that id")
+
+// ReservedIDs are the IDs that would clash with the routes of the API and
+// the frontend
+var ReservedIDs = []string{
+	"achievements",
+	"admin",
+	"archers",
+	"auto-updater",
+	"import",
+	"new",
+	"players",
+	"tournament",
+	"trash",
+}
+
+var slugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// These letters don't lose anything to normalization, so they are spelled
+// out instead
+var slugLetters = strings.NewReplacer(
+	"æ", "ae",
+	"ø", "o",
+	"ß", "ss",
+	"đ", "d",
+	"ł", "l",
+	"þ", "th",
+)
+
+// Slugify makes an ID out of a tournament name
+//
+// The ID is lowercase letters and numbers separated by single dashes.
+// Accents are dropped, and everything else turns into a dash.
+func Slugify(name string) string {
+	name = slugLetters.Replace(strings.ToLower(name))
+
+	var b strings.Builder
+	dash := false
+	for _, r := range norm.NFD.String(name) {
+		switch {
+		case unicode.Is(unicode.Mn, r):
+			// The accent of a letter that was decomposed
+			continue
+		case r >= 'a' && r <= 'z' || r >= '0' && r <= '9':
+			if dash && b.Len() != 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+		default:
+			dash = true
+		}
+	}
+
+	slug := b.String()
+	if len(slug) > MaxIDLength {
+		slug = strings.TrimRight(slug[:MaxIDLength], "-")
+	}
+	return slug
+}
+
+// ValidateID checks that an ID can be used in the URLs of a tournament
+func ValidateID(id string) error {
+	if id == "" {
+		return errors.New("need an id")
+	}
+	if len(id) > MaxIDLength {
+		return fmt.Errorf("id can be at most %d characters", MaxIDLength)
+	}
+	if !slugPattern.MatchString(id) {
+		return fmt.Errorf("id '%s' can only have lowercase letters, numbers and single dashes between them", id)
+	}
+	for _, r := range ReservedIDs {
+		if id == r {
+			return fmt.Errorf("id '%s' is reserved", id)
+		}
+	}
+	return nil
+}
+
+// TournamentID returns the ID for a new tournament
+//
+// Without an ID, one is made from the name, and if it is taken, a number is
+// added to it. A given ID has to be valid and free, or ErrIDTaken is
+// returned.
+func (d *Database) TournamentID(name, id string) (string, error) {
+	if id != "" {
+		if err := ValidateID(id); err != nil {
+			return "", err
+		}
+		if d.exists(id) {
+			return "", ErrIDTaken
+		}
+		return id, nil
+	}
+
+	id = Slugify(name)
+	if id == "" {
+		id = "untitled"
+	}
+	if ValidateID(id) != nil || d.exists(id) {
+		// Leave room for the number
+		if len(id) > MaxIDLength-4 {
+			id = strings.TrimRight(id[:MaxIDLength-4], "-")
+		}
+		id = d.freeID(id)
+	}
+	return id, nil
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestSlugify(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("drunkenfall-2017", Slugify("DrunkenFall 2017"))
+	assert.Equal("drunkenfall-2017", Slugify("  Drunkenfall -- 2017!  "))
+	assert.Equal("smorgasbord-fest", Slugify("Smörgåsbord/Fest"))
+	assert.Equal("aero-strasse", Slugify("Ærø Straße"))
+	assert.Equal("", Slugify("!!!"))
+	assert.Equal(MaxIDLength, len(Slugify(strings.Repeat("a", 100))))
+}
+
+func TestValidateID(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Nil(ValidateID("drunkenfall-2017"))
+	assert.Nil(ValidateID("8"))
+	assert.NotNil(ValidateID(""))
+	assert.NotNil(ValidateID("with/slash"))
+	assert.NotNil(ValidateID("Upper"))
+	assert.NotNil(ValidateID("double--dash"))
+	assert.NotNil(ValidateID("-leading"))
+	assert.NotNil(ValidateID("new"))
+	assert.NotNil(ValidateID("tournament"))
+	assert.NotNil(ValidateID(strings.Repeat("a", MaxIDLength+1)))
+}
+
+func TestTournamentIDFromName(t *testing.T) {
+	assert := assert.New(t)
+	tm := registered(testTournament(8))
+	db := tm.db
+
+	id, err := db.TournamentID("Drunkenfall: Spring", "")
+	assert.Nil(err)
+	assert.Equal("drunkenfall-spring", id)
+
+	// Taken and reserved slugs get a number
+	id, _ = db.TournamentID("8", "")
+	assert.Equal("8-2", id)
+	id, _ = db.TournamentID("New", "")
+	assert.Equal("new-2", id)
+	id, _ = db.TournamentID("???", "")
+	assert.Equal("untitled", id)
+
+	id, _ = db.TournamentID(strings.Repeat("n", 100), "")
+	assert.Equal(MaxIDLength, len(id))
+	assert.Nil(ValidateID(id))
+}
+
+func TestTournamentIDGiven(t *testing.T) {
+	assert := assert.New(t)
+	tm := registered(testTournament(8))
+	db := tm.db
+
+	id, err := db.TournamentID("Whatever", "custom")
+	assert.Nil(err)
+	assert.Equal("custom", id)
+
+	_, err = db.TournamentID("Whatever", "8")
+	assert.Equal(ErrIDTaken, err)
+	_, err = db.TournamentID("Whatever", "a/b")
+	assert.NotNil(err)
+
+	// Trashed tournaments keep their IDs
+	db.Trash(tm)
+	_, err = db.TournamentID("Whatever", "8")
+	assert.Equal(ErrIDTaken, err)
+}
@@ -0,0 +1,598 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	// Registers the sqlite3 driver
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// sqliteSchema sets up the tables of the SQLite store
+//
+// The full tournament records are kept in the data column and are what the
+// server loads. Archived tournaments are moved from the tournaments table
+// into the archive table. The other tables are flattened out of the records
+// every time a tournament is saved, and are kept when it is archived, so
+// that stats can be queried with plain SQL.
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS tournaments (
+	id      TEXT PRIMARY KEY,
+	name    TEXT NOT NULL,
+	mode    TEXT NOT NULL,
+	version INTEGER NOT NULL,
+	opened  TIMESTAMP,
+	started TIMESTAMP,
+	ended   TIMESTAMP,
+	data    BLOB NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS archive (
+	id      TEXT PRIMARY KEY,
+	name    TEXT NOT NULL,
+	mode    TEXT NOT NULL,
+	status  TEXT NOT NULL,
+	players INTEGER NOT NULL,
+	winner  TEXT NOT NULL,
+	opened  TIMESTAMP,
+	started TIMESTAMP,
+	ended   TIMESTAMP,
+	data    BLOB NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS players (
+	tournament TEXT NOT NULL,
+	name       TEXT NOT NULL,
+	color      TEXT NOT NULL,
+	team       TEXT NOT NULL,
+	shots      INTEGER NOT NULL,
+	sweeps     INTEGER NOT NULL,
+	kills      INTEGER NOT NULL,
+	self       INTEGER NOT NULL,
+	explosions INTEGER NOT NULL,
+	matches    INTEGER NOT NULL,
+	forfeits   INTEGER NOT NULL,
+	withdrawn  BOOLEAN NOT NULL,
+	score      INTEGER NOT NULL,
+	PRIMARY KEY (tournament, name)
+);
+
+CREATE TABLE IF NOT EXISTS match_players (
+	tournament TEXT NOT NULL,
+	kind       TEXT NOT NULL,
+	idx        INTEGER NOT NULL,
+	state      TEXT NOT NULL,
+	started    TIMESTAMP,
+	ended      TIMESTAMP,
+	player     TEXT NOT NULL,
+	color      TEXT NOT NULL,
+	team       TEXT NOT NULL,
+	shots      INTEGER NOT NULL,
+	sweeps     INTEGER NOT NULL,
+	kills      INTEGER NOT NULL,
+	self       INTEGER NOT NULL,
+	explosions INTEGER NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS achievements (
+	tournament  TEXT NOT NULL,
+	kind        TEXT NOT NULL,
+	idx         INTEGER NOT NULL,
+	player      TEXT NOT NULL,
+	achievement TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS webhooks (
+	tournament TEXT NOT NULL,
+	id         TEXT NOT NULL,
+	url        TEXT NOT NULL,
+	secret     TEXT NOT NULL,
+	events     TEXT NOT NULL,
+	created    TIMESTAMP NOT NULL,
+	PRIMARY KEY (tournament, id)
+);
+
+CREATE TABLE IF NOT EXISTS deliveries (
+	seq        INTEGER PRIMARY KEY AUTOINCREMENT,
+	tournament TEXT NOT NULL,
+	webhook    TEXT NOT NULL,
+	id         TEXT NOT NULL,
+	event      TEXT NOT NULL,
+	attempt    INTEGER NOT NULL,
+	status     INTEGER NOT NULL,
+	error      TEXT NOT NULL,
+	time       TIMESTAMP NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS events (
+	id         INTEGER PRIMARY KEY AUTOINCREMENT,
+	tournament TEXT NOT NULL,
+	match      TEXT NOT NULL,
+	kind       TEXT NOT NULL,
+	player     TEXT NOT NULL,
+	time       TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS match_players_player ON match_players(player);
+CREATE INDEX IF NOT EXISTS achievements_player ON achievements(player);
+CREATE INDEX IF NOT EXISTS events_tournament ON events(tournament);
+CREATE INDEX IF NOT EXISTS deliveries_tournament ON deliveries(tournament);
+`
+
+// SQLiteStore keeps the tournaments in an embedded SQLite database
+//
+// Next to the tournament records, the players and matches are kept in tables
+// of their own, which can be queried for stats.
+type SQLiteStore struct {
+	DB *sql.DB
+
+	// fn is the file of the database, which the ad-hoc queries open on
+	// their own
+	fn string
+}
+
+// NewSQLiteStore opens an SQLite database, creating it if needed
+func NewSQLiteStore(fn string) (*SQLiteStore, error) {
+	db, err := sql.Open("sqlite3", fn+"?_busy_timeout=5000")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &SQLiteStore{DB: db, fn: fn}, nil
+}
+
+// Tournaments returns the stored records of all the tournaments, by ID
+func (s *SQLiteStore) Tournaments() (map[string][]byte, error) {
+	rows, err := s.DB.Query("SELECT id, data FROM tournaments")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make(map[string][]byte)
+	for rows.Next() {
+		var id string
+		var data []byte
+		if err := rows.Scan(&id, &data); err != nil {
+			return nil, err
+		}
+		out[id] = data
+	}
+	return out, rows.Err()
+}
+
+// Save stores the record of a tournament and rewrites its stats rows
+func (s *SQLiteStore) Save(id string, data []byte) error {
+	var t Tournament
+	if err := json.Unmarshal(data, &t); err != nil {
+		return err
+	}
+
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`INSERT OR REPLACE INTO tournaments
+		(id, name, mode, version, opened, started, ended, data)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		id, t.Name, t.Mode, t.Version,
+		nullTime(t.Opened), nullTime(t.Started), nullTime(t.Ended), data)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM archive WHERE id = ?", id); err != nil {
+		return err
+	}
+
+	for _, table := range []string{"players", "match_players", "achievements"} {
+		if _, err := tx.Exec("DELETE FROM "+table+" WHERE tournament = ?", id); err != nil {
+			return err
+		}
+	}
+
+	for _, p := range t.Players {
+		_, err := tx.Exec(`INSERT INTO players
+			(tournament, name, color, team, shots, sweeps, kills, self, explosions, matches, forfeits, withdrawn, score)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			id, p.Name, p.PreferredColor, p.Team, p.Shots, p.Sweeps, p.Kills,
+			p.Self, p.Explosions, p.Matches, p.Forfeits, p.Withdrawn, p.TotalScore)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, m := range t.Matches() {
+		if m == nil || !m.IsStarted() {
+			continue
+		}
+
+		for _, p := range m.Players {
+			if p.IsPrefill() {
+				continue
+			}
+
+			_, err := tx.Exec(`INSERT INTO match_players
+				(tournament, kind, idx, state, started, ended, player, color, team, shots, sweeps, kills, self, explosions)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+				id, m.Kind, m.Index, m.State, nullTime(m.Started), nullTime(m.Ended),
+				p.Name, p.PreferredColor, p.Team, p.Shots, p.Sweeps, p.Kills, p.Self, p.Explosions)
+			if err != nil {
+				return err
+			}
+
+			for a, n := range p.Achievements {
+				for i := 0; i < n; i++ {
+					_, err := tx.Exec(`INSERT INTO achievements
+						(tournament, kind, idx, player, achievement) VALUES (?, ?, ?, ?, ?)`,
+						id, m.Kind, m.Index, p.Name, a)
+					if err != nil {
+						return err
+					}
+				}
+			}
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Delete removes a tournament and its events
+func (s *SQLiteStore) Delete(id string) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, table := range []string{"events", "match_players", "players", "achievements", "webhooks", "deliveries"} {
+		if _, err := tx.Exec("DELETE FROM "+table+" WHERE tournament = ?", id); err != nil {
+			return err
+		}
+	}
+	for _, table := range []string{"tournaments", "archive"} {
+		if _, err := tx.Exec("DELETE FROM "+table+" WHERE id = ?", id); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Archive moves the record of a tournament into the archive
+//
+// The stats rows of the tournament stay where they are.
+func (s *SQLiteStore) Archive(id string, data []byte, summary Summary) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`INSERT OR REPLACE INTO archive
+		(id, name, mode, status, players, winner, opened, started, ended, data)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		id, summary.Name, summary.Mode, summary.Status, summary.Players, summary.Winner,
+		nullTime(summary.Opened), nullTime(summary.Started), nullTime(summary.Ended), data)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM tournaments WHERE id = ?", id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Archived returns the record of an archived tournament
+func (s *SQLiteStore) Archived(id string) ([]byte, error) {
+	var data []byte
+	err := s.DB.QueryRow("SELECT data FROM archive WHERE id = ?", id).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, ErrNoTournament
+	}
+	return data, err
+}
+
+// Summaries returns the summaries of the archived tournaments
+func (s *SQLiteStore) Summaries() ([]Summary, error) {
+	rows, err := s.DB.Query(`SELECT id, name, mode, status, players, winner, opened, started, ended
+		FROM archive`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []Summary{}
+	for rows.Next() {
+		summary := Summary{Archived: true}
+		var opened, started, ended sql.NullTime
+		err := rows.Scan(&summary.ID, &summary.Name, &summary.Mode, &summary.Status,
+			&summary.Players, &summary.Winner, &opened, &started, &ended)
+		if err != nil {
+			return nil, err
+		}
+
+		summary.Opened = opened.Time
+		summary.Started = started.Time
+		summary.Ended = ended.Time
+		out = append(out, summary)
+	}
+	return out, rows.Err()
+}
+
+// Players returns the stats of every player over all the tournaments
+func (s *SQLiteStore) Players() ([]Player, error) {
+	rows, err := s.DB.Query(`SELECT name,
+		(SELECT color FROM players c WHERE c.name = p.name ORDER BY tournament LIMIT 1),
+		SUM(shots), SUM(sweeps), SUM(kills), SUM(self), SUM(explosions), SUM(matches), SUM(forfeits)
+		FROM players p GROUP BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []Player{}
+	for rows.Next() {
+		p := Player{}
+		err := rows.Scan(&p.Name, &p.PreferredColor, &p.Shots, &p.Sweeps, &p.Kills,
+			&p.Self, &p.Explosions, &p.Matches, &p.Forfeits)
+		if err != nil {
+			return nil, err
+		}
+		p.TotalScore = p.Score()
+		out = append(out, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := s.achievements(out); err != nil {
+		return nil, err
+	}
+	sortCareer(out)
+	return out, nil
+}
+
+// achievements counts the achievements the players have earned
+func (s *SQLiteStore) achievements(ps []Player) error {
+	index := make(map[string]int)
+	for i, p := range ps {
+		index[p.Name] = i
+	}
+
+	rows, err := s.DB.Query(
+		"SELECT player, achievement, COUNT(*) FROM achievements GROUP BY player, achievement")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name, a string
+		var n int
+		if err := rows.Scan(&name, &a, &n); err != nil {
+			return err
+		}
+
+		i, ok := index[name]
+		if !ok {
+			continue
+		}
+		if ps[i].Achievements == nil {
+			ps[i].Achievements = make(map[string]int)
+		}
+		ps[i].Achievements[a] = n
+	}
+	return rows.Err()
+}
+
+// AddEvent records something that happened in a tournament
+func (s *SQLiteStore) AddEvent(e Event) error {
+	_, err := s.DB.Exec(
+		"INSERT INTO events (tournament, match, kind, player, time) VALUES (?, ?, ?, ?, ?)",
+		e.Tournament, e.Match, e.Kind, e.Player, e.Time.UTC())
+	return err
+}
+
+// Events returns the events of a tournament, oldest first
+func (s *SQLiteStore) Events(id string) ([]Event, error) {
+	rows, err := s.DB.Query(
+		"SELECT tournament, match, kind, player, time FROM events WHERE tournament = ? ORDER BY id", id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []Event{}
+	for rows.Next() {
+		var e Event
+		if err := rows.Scan(&e.Tournament, &e.Match, &e.Kind, &e.Player, &e.Time); err != nil {
+			return nil, err
+		}
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
+
+// Webhooks returns the webhooks of a tournament
+func (s *SQLiteStore) Webhooks(id string) ([]Webhook, error) {
+	rows, err := s.DB.Query(
+		"SELECT id, url, secret, events, created FROM webhooks WHERE tournament = ? ORDER BY created, id", id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []Webhook{}
+	for rows.Next() {
+		var h Webhook
+		var events string
+		if err := rows.Scan(&h.ID, &h.URL, &h.Secret, &events, &h.Created); err != nil {
+			return nil, err
+		}
+		h.Events = []string{}
+		if events != "" {
+			h.Events = strings.Split(events, ",")
+		}
+		out = append(out, h)
+	}
+	return out, rows.Err()
+}
+
+// SaveWebhooks replaces the webhooks of a tournament
+func (s *SQLiteStore) SaveWebhooks(id string, hooks []Webhook) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM webhooks WHERE tournament = ?", id); err != nil {
+		return err
+	}
+	for _, h := range hooks {
+		_, err := tx.Exec(
+			"INSERT INTO webhooks (tournament, id, url, secret, events, created) VALUES (?, ?, ?, ?, ?, ?)",
+			id, h.ID, h.URL, h.Secret, strings.Join(h.Events, ","), h.Created.UTC())
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// AddDelivery logs an attempt to deliver an event to a webhook
+func (s *SQLiteStore) AddDelivery(d Delivery) error {
+	_, err := s.DB.Exec(`INSERT INTO deliveries
+		(tournament, webhook, id, event, attempt, status, error, time)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		d.Tournament, d.Webhook, d.ID, d.Event, d.Attempt, d.Status, d.Error, d.Time.UTC())
+	return err
+}
+
+// Deliveries returns the delivery log of a tournament, oldest first
+func (s *SQLiteStore) Deliveries(id string) ([]Delivery, error) {
+	rows, err := s.DB.Query(`SELECT tournament, webhook, id, event, attempt, status, error, time
+		FROM deliveries WHERE tournament = ? ORDER BY seq`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []Delivery{}
+	for rows.Next() {
+		var d Delivery
+		err := rows.Scan(&d.Tournament, &d.Webhook, &d.ID, &d.Event, &d.Attempt,
+			&d.Status, &d.Error, &d.Time)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, d)
+	}
+	return out, rows.Err()
+}
+
+// Query runs an ad-hoc query and returns the rows as column to value maps
+//
+// Only a single SELECT, or WITH ... SELECT, is allowed. On top of that,
+// every query gets a connection of its own that opens the file read only, so
+// nothing a query does can last beyond it or change the database.
+func (s *SQLiteStore) Query(q string, args ...interface{}) ([]map[string]interface{}, error) {
+	if err := checkQuery(q); err != nil {
+		return nil, err
+	}
+
+	db, err := sql.Open("sqlite3", "file:"+s.fn+"?mode=ro&_query_only=true&_busy_timeout=5000")
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	rows, err := db.Query(q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	out := []map[string]interface{}{}
+	for rows.Next() {
+		values := make([]interface{}, len(cols))
+		ptrs := make([]interface{}, len(cols))
+		for i := range values {
+			ptrs[i] = &values[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, err
+		}
+
+		row := make(map[string]interface{}, len(cols))
+		for i, col := range cols {
+			// Text comes back as bytes, which would be base64 in the JSON
+			if b, ok := values[i].([]byte); ok {
+				values[i] = string(b)
+			}
+			row[col] = values[i]
+		}
+		out = append(out, row)
+	}
+	return out, rows.Err()
+}
+
+// Backup writes a consistent copy of the database to a new file
+func (s *SQLiteStore) Backup(fn string) error {
+	_, err := s.DB.Exec("VACUUM INTO ?", fn)
+	return err
+}
+
+// Close closes the database
+func (s *SQLiteStore) Close() error {
+	return s.DB.Close()
+}
+
+// checkQuery returns an error unless the query is a single SELECT statement
+//
+// Comments are not allowed, since they could hide what the query does, and
+// neither are semicolons other than at the end.
+func checkQuery(q string) error {
+	q = strings.TrimRight(strings.TrimSpace(q), "; \t\n")
+	if strings.Contains(q, "--") || strings.Contains(q, "/*") {
+		return errors.New("queries cannot have comments")
+	}
+	if strings.Contains(q, ";") {
+		return errors.New("only one statement can be queried")
+	}
+
+	fields := strings.Fields(strings.ToUpper(q))
+	if len(fields) == 0 || (fields[0] != "SELECT" && fields[0] != "WITH") {
+		return errors.New("only SELECT statements can be queried")
+	}
+	for _, f := range fields {
+		switch f {
+		case "PRAGMA", "ATTACH", "DETACH", "INSERT", "UPDATE", "DELETE", "REPLACE", "DROP", "CREATE", "ALTER", "VACUUM":
+			return fmt.Errorf("%s is not allowed in queries", f)
+		}
+	}
+	return nil
+}
+
+// nullTime stores unset times as NULL rather than as year one
+func nullTime(t time.Time) interface{} {
+	if t.IsZero() {
+		return nil
+	}
+	return t.UTC()
+}
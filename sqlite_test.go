@@ -0,0 +1,123 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func testSQLiteStore() *SQLiteStore {
+	os.Mkdir("test/", 0700)
+	os.Remove("test/query.sqlite")
+
+	s, err := NewSQLiteStore("test/query.sqlite")
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+func TestSQLiteQueryMatchStats(t *testing.T) {
+	assert := assert.New(t)
+	s := testSQLiteStore()
+	defer s.Close()
+
+	tm := testTournament(8)
+	tm.StartTournament()
+	playMatch(tm.Tryouts[0])
+	data, _ := tm.JSON()
+	assert.Nil(s.Save(tm.ID, data))
+
+	rows, err := s.Query(`SELECT player, kills FROM match_players
+		WHERE tournament = ? AND kind = 'tryout' ORDER BY kills DESC`, tm.ID)
+	assert.Nil(err)
+	assert.Equal(4, len(rows))
+	assert.Equal(tm.Tryouts[0].Players[0].Name, rows[0]["player"])
+	assert.Equal(int64(tm.Tryouts[0].Players[0].Kills), rows[0]["kills"])
+
+	rows, err = s.Query("SELECT name, mode FROM tournaments")
+	assert.Nil(err)
+	assert.Equal([]map[string]interface{}{{"name": tm.Name, "mode": SoloMode}}, rows)
+}
+
+func TestSQLiteSaveReplacesStats(t *testing.T) {
+	assert := assert.New(t)
+	s := testSQLiteStore()
+	defer s.Close()
+
+	tm := testTournament(8)
+	data, _ := tm.JSON()
+	s.Save(tm.ID, data)
+	tm.AddPlayer("9", "red")
+	data, _ = tm.JSON()
+	s.Save(tm.ID, data)
+
+	rows, err := s.Query("SELECT COUNT(*) AS players FROM players")
+	assert.Nil(err)
+	assert.Equal(int64(9), rows[0]["players"])
+}
+
+func TestSQLiteQueryCannotWrite(t *testing.T) {
+	assert := assert.New(t)
+	s := testSQLiteStore()
+	defer s.Close()
+
+	_, err := s.Query("DELETE FROM tournaments")
+	assert.NotNil(err)
+	_, err = s.Query("SELECT nope FROM tournaments")
+	assert.NotNil(err)
+}
+
+func TestSQLiteQueryCannotTurnOffReadOnly(t *testing.T) {
+	assert := assert.New(t)
+	s := testSQLiteStore()
+	defer s.Close()
+
+	tm := testTournament(8)
+	data, _ := tm.JSON()
+	assert.Nil(s.Save(tm.ID, data))
+
+	for _, q := range []string{
+		"PRAGMA query_only=0",
+		"SELECT 1; DELETE FROM tournaments",
+		"WITH x AS (SELECT 1) DELETE FROM tournaments",
+		"ATTACH DATABASE 'test/other.sqlite' AS other",
+		"SELECT 1 -- hidden",
+	} {
+		_, err := s.Query(q)
+		assert.NotNil(err, q)
+	}
+
+	rows, err := s.Query("SELECT COUNT(*) AS n FROM tournaments;")
+	assert.Nil(err)
+	assert.Equal(int64(1), rows[0]["n"])
+}
+
+func TestQueryHandlerNeedsAdminToken(t *testing.T) {
+	assert := assert.New(t)
+	db := MockDatabase()
+	s := testServer(db)
+
+	query := func(token string) int {
+		body := strings.NewReader(`{"query": "SELECT 1"}`)
+		req := httptest.NewRequest("POST", "/api/towerfall/admin/query/", body)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		s.admin(s.QueryHandler)(w, req)
+		return w.Code
+	}
+
+	// Turned off without a token
+	assert.Equal(403, query("s3cret"))
+
+	s.adminToken = "s3cret"
+	assert.Equal(401, query(""))
+	assert.Equal(401, query("guess"))
+
+	// The memory store cannot be queried, but the request got through
+	assert.Equal(501, query("s3cret"))
+}
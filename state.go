@@ -0,0 +1,225 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Match states
+const (
+	// MatchScheduled is a match that is still waiting for its players
+	MatchScheduled = "scheduled"
+	// MatchReady is a match that can be started
+	MatchReady = "ready"
+	// MatchPlaying is a match that is being played and accepts commits
+	MatchPlaying = "playing"
+	// MatchAwaitingConfirmation is a match where the kill target has been
+	// reached and the judge needs to confirm the end
+	MatchAwaitingConfirmation = "awaiting-confirmation"
+	// MatchEnded is a match that has ended and whose players have moved on
+	MatchEnded = "ended"
+	// MatchVoided is a match that has been cancelled by an organizer
+	MatchVoided = "voided"
+)
+
+// matchTransitions are the allowed state transitions for a match
+var matchTransitions = map[string][]string{
+	MatchScheduled:            {MatchReady, MatchVoided},
+	MatchReady:                {MatchScheduled, MatchPlaying, MatchVoided},
+	MatchPlaying:              {MatchAwaitingConfirmation, MatchVoided},
+	MatchAwaitingConfirmation: {MatchPlaying, MatchEnded, MatchVoided},
+}
+
+// Override is a record of an organizer forcing the state of a match
+type Override struct {
+	From   string    `json:"from"`
+	To     string    `json:"to"`
+	Reason string    `json:"reason"`
+	Time   time.Time `json:"time"`
+}
+
+// CanTransition returns boolean whether the match can move into a state
+func (m *Match) CanTransition(to string) bool {
+	for _, s := range matchTransitions[m.getState()] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Override forces the match into a state, bypassing the normal rules
+//
+// Organizers can end a match before the kill target is reached, void a match
+// that cannot be played, or put a match awaiting confirmation back into play.
+// Ended and voided matches cannot be overridden. The override is only
+// recorded if it went through.
+//
+// Nobody advances from a voided tryout. Its players go to the runnerups
+// instead, and the slots it would have filled are backfilled. Semis decide
+// the final and cannot be voided.
+func (m *Match) Override(to, reason string) error {
+	if reason == "" {
+		return errors.New("override needs a reason")
+	}
+
+	from := m.getState()
+	if from == MatchEnded || from == MatchVoided {
+		return fmt.Errorf("cannot override %s match", from)
+	}
+
+	switch to {
+	case MatchEnded:
+		if from != MatchPlaying && from != MatchAwaitingConfirmation {
+			return fmt.Errorf("cannot end %s match", from)
+		}
+	case MatchPlaying:
+		if from != MatchAwaitingConfirmation {
+			return fmt.Errorf("cannot resume %s match", from)
+		}
+	case MatchVoided:
+		if m.Kind == "semi" {
+			return errors.New("cannot void a semi, since it decides the final")
+		}
+	default:
+		return fmt.Errorf("cannot override match into %s", to)
+	}
+
+	override := Override{
+		From:   from,
+		To:     to,
+		Reason: reason,
+		Time:   time.Now(),
+	}
+
+	if to == MatchEnded {
+		// End does the rest of the checks, e.g. for pending ties. If it
+		// fails, the match stays as it was.
+		m.State = MatchAwaitingConfirmation
+		if err := m.End(); err != nil {
+			m.State = from
+			return err
+		}
+
+		m.Overrides = append(m.Overrides, override)
+		if m.Tournament != nil {
+			return m.Tournament.Persist()
+		}
+		return nil
+	}
+
+	m.State = to
+	m.Overrides = append(m.Overrides, override)
+	if m.Tournament != nil {
+		if to == MatchVoided && m.Kind == "tryout" {
+			m.Tournament.byeTryout(m)
+		}
+		m.Tournament.updateReadiness()
+		return m.Tournament.Persist()
+	}
+	return nil
+}
+
+// IsVoided returns boolean whether the match has been voided or not
+func (m *Match) IsVoided() bool {
+	return m.State == MatchVoided
+}
+
+// IsDone returns boolean whether the match will not be played anymore
+func (m *Match) IsDone() bool {
+	return m.IsEnded() || m.IsVoided()
+}
+
+// getState returns the state, treating matches without one as scheduled
+func (m *Match) getState() string {
+	if m.State == "" {
+		return MatchScheduled
+	}
+	return m.State
+}
+
+// transition moves the match into a new state if allowed
+func (m *Match) transition(to string) error {
+	if !m.CanTransition(to) {
+		return fmt.Errorf("%s: cannot go from %s to %s", m.String(), m.getState(), to)
+	}
+
+	m.State = to
+	return nil
+}
+
+// updateReadiness moves a match between scheduled and ready depending on
+// whether it has everything needed to start
+func (m *Match) updateReadiness() {
+	s := m.getState()
+	if s != MatchScheduled && s != MatchReady {
+		return
+	}
+
+	if m.isReady() {
+		m.State = MatchReady
+	} else {
+		m.State = MatchScheduled
+	}
+}
+
+// isReady returns boolean whether the match has its players
+//
+// Matches that are not full are ready once all matches before them are done,
+// since that is when the runnerups that will backfill them are known. Matches
+// that lost players to no-shows can be backfilled as soon as there are enough
+// runnerups.
+func (m *Match) isReady() bool {
+	if m.ActualPlayers() == 4 {
+		return true
+	}
+
+	t := m.Tournament
+	if t == nil || !t.IsRunning() {
+		return false
+	}
+
+	// Runner-up rounds are never backfilled
+	if m.Kind == "runnerup" {
+		return m.ActualPlayers() >= 2
+	}
+
+	if t.availableRunnerups(m) < 4-m.ActualPlayers() {
+		return false
+	}
+	if len(m.Forfeits) != 0 {
+		return true
+	}
+
+	for _, o := range t.Matches() {
+		if o == m {
+			return true
+		}
+		if o.Kind == "runnerup" {
+			continue
+		}
+		if !o.IsDone() {
+			return false
+		}
+	}
+	return false
+}
+
+// deriveState sets the state of matches persisted before states existed
+func (m *Match) deriveState() {
+	if m.State != "" {
+		return
+	}
+
+	if m.IsEnded() {
+		m.State = MatchEnded
+	} else if m.IsStarted() {
+		m.State = MatchPlaying
+		if m.CanEnd() {
+			m.State = MatchAwaitingConfirmation
+		}
+	} else {
+		m.updateReadiness()
+	}
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func testStateMatch() *Match {
+	m := NewMatch(tm, 0, "tryout")
+	_ = m.AddPlayer(Player{Name: "1"})
+	_ = m.AddPlayer(Player{Name: "2"})
+	_ = m.AddPlayer(Player{Name: "3"})
+	_ = m.AddPlayer(Player{Name: "4"})
+	return m
+}
+
+func testCommit(m *Match, kills int) error {
+	scores := [][]int{
+		[]int{kills, 0},
+		[]int{0, 0},
+		[]int{0, 0},
+		[]int{0, 0},
+	}
+	shots := []bool{false, false, false, false}
+	return m.Commit(scores, shots)
+}
+
+func TestNewMatchIsScheduled(t *testing.T) {
+	assert := assert.New(t)
+	m := NewMatch(tm, 0, "tryout")
+
+	assert.Equal(MatchScheduled, m.State)
+}
+
+func TestFullMatchIsReady(t *testing.T) {
+	assert := assert.New(t)
+	m := testStateMatch()
+
+	assert.Equal(MatchReady, m.State)
+}
+
+func TestStartScheduledMatchFails(t *testing.T) {
+	assert := assert.New(t)
+	m := NewMatch(tm, 0, "tryout")
+	_ = m.AddPlayer(Player{Name: "1"})
+
+	err := m.Start()
+	assert.NotNil(err)
+	assert.False(m.IsStarted())
+}
+
+func TestStartedMatchIsPlaying(t *testing.T) {
+	assert := assert.New(t)
+	m := testStateMatch()
+
+	assert.Nil(m.Start())
+	assert.Equal(MatchPlaying, m.State)
+}
+
+func TestCommitToNotStartedMatchFails(t *testing.T) {
+	assert := assert.New(t)
+	m := testStateMatch()
+
+	err := testCommit(m, 2)
+	assert.NotNil(err)
+	assert.Equal(0, m.Players[0].Kills)
+}
+
+func TestCommitToEndedMatchFails(t *testing.T) {
+	assert := assert.New(t)
+	m := testStateMatch()
+	_ = m.Start()
+	m.Players[0].AddKill(10)
+	assert.Nil(m.End())
+
+	err := testCommit(m, 2)
+	assert.NotNil(err)
+	assert.Equal(10, m.Players[0].Kills)
+}
+
+func TestCommitReachingKillTargetAwaitsConfirmation(t *testing.T) {
+	assert := assert.New(t)
+	m := testStateMatch()
+	_ = m.Start()
+	m.Players[0].AddKill(8)
+
+	assert.Nil(testCommit(m, 2))
+	assert.Equal(MatchAwaitingConfirmation, m.State)
+
+	// No more rounds can be committed until the judge resumes the match
+	assert.NotNil(testCommit(m, 1))
+}
+
+func TestEndBeforeKillTargetFails(t *testing.T) {
+	assert := assert.New(t)
+	m := testStateMatch()
+	_ = m.Start()
+	m.Players[0].AddKill(9)
+
+	err := m.End()
+	assert.NotNil(err)
+	assert.False(m.IsEnded())
+	assert.Equal(MatchPlaying, m.State)
+}
+
+func TestEndNotStartedMatchFails(t *testing.T) {
+	assert := assert.New(t)
+	m := testStateMatch()
+
+	err := m.End()
+	assert.NotNil(err)
+	assert.False(m.IsEnded())
+}
+
+func TestEndAwaitingConfirmationEnds(t *testing.T) {
+	assert := assert.New(t)
+	m := testStateMatch()
+	_ = m.Start()
+	m.Players[0].AddKill(9)
+	_ = testCommit(m, 1)
+
+	assert.Nil(m.End())
+	assert.Equal(MatchEnded, m.State)
+}
+
+func TestOverrideNeedsReason(t *testing.T) {
+	assert := assert.New(t)
+	m := testStateMatch()
+	_ = m.Start()
+
+	err := m.Override(MatchEnded, "")
+	assert.NotNil(err)
+	assert.Equal(MatchPlaying, m.State)
+}
+
+func TestOverrideEndsMatchBeforeKillTarget(t *testing.T) {
+	assert := assert.New(t)
+	m := testStateMatch()
+	_ = m.Start()
+	m.Players[0].AddKill(4)
+
+	err := m.Override(MatchEnded, "the bar is closing")
+	assert.Nil(err)
+	assert.True(m.IsEnded())
+	assert.Equal(MatchEnded, m.State)
+	assert.Equal(1, len(m.Overrides))
+	assert.Equal(MatchPlaying, m.Overrides[0].From)
+	assert.Equal("the bar is closing", m.Overrides[0].Reason)
+}
+
+func TestOverrideResumesAwaitingMatch(t *testing.T) {
+	assert := assert.New(t)
+	m := testStateMatch()
+	_ = m.Start()
+	m.Players[0].AddKill(9)
+	_ = testCommit(m, 1)
+
+	assert.Nil(m.Override(MatchPlaying, "the last round was misjudged"))
+	assert.Equal(MatchPlaying, m.State)
+	assert.Nil(testCommit(m, 0))
+}
+
+func TestOverrideVoidsMatch(t *testing.T) {
+	assert := assert.New(t)
+	m := testStateMatch()
+
+	assert.Nil(m.Override(MatchVoided, "controller broke"))
+	assert.True(m.IsVoided())
+	assert.NotNil(m.Start())
+}
+
+func TestOverrideEndedMatchFails(t *testing.T) {
+	assert := assert.New(t)
+	m := testStateMatch()
+	_ = m.Start()
+	m.Players[0].AddKill(10)
+	_ = m.End()
+
+	err := m.Override(MatchVoided, "changed my mind")
+	assert.NotNil(err)
+	assert.Equal(MatchEnded, m.State)
+}
+
+func TestNextMatchSkipsVoidedMatch(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTournament(16)
+	tm.StartTournament()
+
+	assert.Nil(tm.Tryouts[0].Override(MatchVoided, "no show"))
+
+	m, err := tm.NextMatch()
+	assert.Nil(err)
+	assert.Equal(1, m.Index)
+}
+
+func TestDeriveStateForLegacyMatches(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTournament(16)
+	tm.StartTournament()
+	playMatch(tm.Tryouts[0])
+	tm.Tryouts[1].Start()
+
+	for _, m := range tm.Matches() {
+		m.State = ""
+	}
+	tm.SetMatchPointers()
+
+	assert.Equal(MatchEnded, tm.Tryouts[0].State)
+	assert.Equal(MatchPlaying, tm.Tryouts[1].State)
+	assert.Equal(MatchReady, tm.Tryouts[2].State)
+	assert.Equal(MatchScheduled, tm.Semis[0].State)
+}
+
+func TestOverrideEndWithTieIsNotRecorded(t *testing.T) {
+	assert := assert.New(t)
+	m := testStateMatch()
+	_ = m.Start()
+	m.Players[0].AddKill(4)
+	m.Players[1].AddKill(4)
+
+	assert.NotNil(m.Override(MatchEnded, "the bar is closing"))
+	assert.False(m.IsEnded())
+	assert.Equal(MatchPlaying, m.State)
+	assert.Equal(0, len(m.Overrides))
+}
+
+func TestOverrideVoidSemiFails(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTournament(16)
+	tm.StartTournament()
+
+	assert.NotNil(tm.Semis[0].Override(MatchVoided, "no show"))
+	assert.False(tm.Semis[0].IsVoided())
+	assert.Equal(0, len(tm.Semis[0].Overrides))
+}
+
+func TestVoidedTryoutIsBackfilled(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTournament(16)
+	tm.StartTournament()
+
+	voided := tm.Tryouts[0]
+	assert.Nil(voided.Override(MatchVoided, "no show"))
+	for _, p := range voided.Players {
+		assert.True(tm.isRunnerup(p.Name), p.Name)
+	}
+
+	for _, m := range tm.Tryouts[1:] {
+		playMatch(m)
+	}
+	assert.NotEqual(0, len(tm.RunnerupRounds))
+
+	s := tm.Semis[0]
+	assert.Equal(MatchReady, s.State)
+	assert.Nil(s.Start())
+	assert.Equal(4, s.ActualPlayers())
+	assert.Equal(1, len(s.Backfilled))
+}
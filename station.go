@@ -0,0 +1,148 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Station is a TV with a copy of the game where matches are played
+//
+// Stations are numbered from 1, since a zero station on a match means that
+// it has not been assigned to one.
+type Station struct {
+	Number int    `json:"number"`
+	Name   string `json:"name"`
+}
+
+// StationView is what the judge at a station is looking at
+type StationView struct {
+	Station *Station `json:"station"`
+	Match   *Match   `json:"match"`
+}
+
+// SetStationCount sets how many matches can be played at the same time
+//
+// Stations can only be removed as long as they are not playing a match.
+func (t *Tournament) SetStationCount(count int) error {
+	if count < 1 {
+		return errors.New("need at least one station")
+	}
+
+	for n := count + 1; n <= len(t.Stations); n++ {
+		if m := t.StationMatch(n); m != nil {
+			return fmt.Errorf("cannot remove station %d, it has %s", n, m.String())
+		}
+	}
+
+	if count < len(t.Stations) {
+		t.Stations = t.Stations[:count]
+	}
+	t.addStations(count)
+
+	t.StationCount = count
+	return t.Persist()
+}
+
+// GetStation returns the station with the given number
+func (t *Tournament) GetStation(n int) (*Station, error) {
+	if n < 1 || n > len(t.Stations) {
+		return nil, fmt.Errorf("no station %d", n)
+	}
+	return t.Stations[n-1], nil
+}
+
+// StationMatch returns the match currently assigned to a station, if any
+func (t *Tournament) StationMatch(n int) *Match {
+	for _, m := range t.Matches() {
+		if m.Station == n && !m.IsDone() {
+			return m
+		}
+	}
+	return nil
+}
+
+// Playable returns the matches that can be started right away
+//
+// These are all the ready matches whose bracket dependencies are done, i.e.
+// independent tryouts can run in parallel but the semis wait for the tryouts
+// that feed them.
+func (t *Tournament) Playable() []*Match {
+	ms := []*Match{}
+	for _, m := range t.Matches() {
+		if m.IsStarted() || m.IsDone() {
+			continue
+		}
+
+		m.updateReadiness()
+		if m.State != MatchReady {
+			continue
+		}
+
+		done := true
+		for _, d := range t.dependencies(m) {
+			if !d.IsDone() {
+				done = false
+				break
+			}
+		}
+		if done {
+			ms = append(ms, m)
+		}
+	}
+	return ms
+}
+
+// AssignMatch puts a match on a station
+func (t *Tournament) AssignMatch(m *Match, n int) error {
+	if _, err := t.GetStation(n); err != nil {
+		return err
+	}
+	if m.IsStarted() || m.IsDone() {
+		return fmt.Errorf("cannot assign %s match", m.getState())
+	}
+	if o := t.StationMatch(n); o != nil && o != m {
+		return fmt.Errorf("station %d already has %s", n, o.String())
+	}
+
+	m.Station = n
+	return t.Persist()
+}
+
+// NextStationMatch returns the match that a station should play next
+//
+// If the station already has a match assigned, that is returned. Otherwise
+// the first playable match that is not assigned elsewhere is put on it.
+func (t *Tournament) NextStationMatch(n int) (*Match, error) {
+	if _, err := t.GetStation(n); err != nil {
+		return nil, err
+	}
+
+	if m := t.StationMatch(n); m != nil {
+		return m, nil
+	}
+
+	for _, m := range t.Playable() {
+		if m.Station == 0 || m.Station == n {
+			return m, t.AssignMatch(m, n)
+		}
+	}
+
+	return nil, errors.New("no match can be played right now")
+}
+
+// setupStations makes sure that the stations exist for the station count
+//
+// Tournaments stored before stations existed only have the count.
+func (t *Tournament) setupStations() {
+	t.addStations(t.stationCount())
+}
+
+// addStations adds stations until there are `count` of them
+func (t *Tournament) addStations(count int) {
+	for n := len(t.Stations) + 1; n <= count; n++ {
+		t.Stations = append(t.Stations, &Station{
+			Number: n,
+			Name:   fmt.Sprintf("Station %d", n),
+		})
+	}
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestNewTournamentHasOneStation(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTournament(8)
+
+	assert.Equal(1, len(tm.Stations))
+	assert.Equal(1, tm.Stations[0].Number)
+}
+
+func TestSetStationCountAddsStations(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTournament(8)
+
+	assert.Nil(tm.SetStationCount(2))
+	assert.Equal(2, len(tm.Stations))
+	assert.Equal("Station 2", tm.Stations[1].Name)
+}
+
+func TestSetStationCountCannotRemoveBusyStation(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTournament(16)
+	tm.StartTournament()
+	tm.SetStationCount(2)
+
+	assert.Nil(tm.AssignMatch(tm.Tryouts[1], 2))
+	assert.NotNil(tm.SetStationCount(1))
+	assert.Equal(2, len(tm.Stations))
+}
+
+func TestLoadedTournamentGetsStations(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTournament(8)
+	tm.Stations = nil
+	tm.StationCount = 2
+
+	tm.setupStations()
+	assert.Equal(2, len(tm.Stations))
+}
+
+func TestPlayableTryoutsInParallel(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTournament(16)
+	tm.StartTournament()
+
+	ms := tm.Playable()
+	assert.Equal(4, len(ms))
+	for _, m := range ms {
+		assert.Equal("tryout", m.Kind)
+	}
+}
+
+func TestPlayableSemisWaitForTryouts(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTournament(16)
+	tm.StartTournament()
+
+	for _, m := range tm.Tryouts[:3] {
+		playMatch(m)
+	}
+
+	ms := tm.Playable()
+	assert.Equal(1, len(ms))
+	assert.Equal(tm.Tryouts[3], ms[0])
+}
+
+func TestPlayableSemiWithOnlyItsTryoutsDone(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTournament(32)
+	tm.StartTournament()
+
+	// Every other tryout feeds the first semi
+	for i := 0; i < len(tm.Tryouts); i += 2 {
+		playMatch(tm.Tryouts[i])
+	}
+
+	ms := tm.Playable()
+	assert.Contains(ms, tm.Semis[0])
+	assert.NotContains(ms, tm.Semis[1])
+}
+
+func TestNextStationMatchAssignsDifferentMatches(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTournament(16)
+	tm.StartTournament()
+	tm.SetStationCount(2)
+
+	m1, err := tm.NextStationMatch(1)
+	assert.Nil(err)
+	m2, err := tm.NextStationMatch(2)
+	assert.Nil(err)
+
+	assert.NotEqual(m1, m2)
+	assert.Equal(1, m1.Station)
+	assert.Equal(2, m2.Station)
+
+	// Asking again gives the same match until it is done
+	again, err := tm.NextStationMatch(1)
+	assert.Nil(err)
+	assert.Equal(m1, again)
+
+	playMatch(m1)
+	m3, err := tm.NextStationMatch(1)
+	assert.Nil(err)
+	assert.NotEqual(m1, m3)
+	assert.NotEqual(m2, m3)
+}
+
+func TestNextStationMatchUnknownStation(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTournament(16)
+	tm.StartTournament()
+
+	_, err := tm.NextStationMatch(3)
+	assert.NotNil(err)
+}
+
+func TestAssignMatchToBusyStationFails(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTournament(16)
+	tm.StartTournament()
+
+	assert.Nil(tm.AssignMatch(tm.Tryouts[0], 1))
+	assert.NotNil(tm.AssignMatch(tm.Tryouts[1], 1))
+}
+
+func TestLockedHandlersTakeTurns(t *testing.T) {
+	assert := assert.New(t)
+	s := &Server{}
+
+	var active, overlaps int32
+	r := mux.NewRouter()
+	r.HandleFunc("/{id}/commit/", s.locked(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&active, 1) != 1 {
+			atomic.AddInt32(&overlaps, 1)
+		}
+		time.Sleep(time.Millisecond)
+		atomic.AddInt32(&active, -1)
+	}))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req := httptest.NewRequest("POST", "/1/commit/", nil)
+			r.ServeHTTP(httptest.NewRecorder(), req)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(int32(0), overlaps)
+	assert.True(s.tournamentLock("1") == s.tournamentLock("1"))
+	assert.False(s.tournamentLock("1") == s.tournamentLock("2"))
+}
@@ -0,0 +1,397 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Store is where the tournaments are persisted
+//
+// Tournaments are stored as the JSON records written by Tournament.JSON, so
+// that the migrations can upgrade them before they are loaded. A tournament
+// is either active or archived, never both. Archived tournaments are kept
+// apart with a summary of each, so that they don't have to be loaded to be
+// listed.
+type Store interface {
+	// Tournaments returns the records of all the active tournaments, by ID
+	Tournaments() (map[string][]byte, error)
+	// Save stores the record of an active tournament, taking it out of the
+	// archive if it was there
+	Save(id string, data []byte) error
+	// Archive moves the record of a tournament into the archive
+	Archive(id string, data []byte, summary Summary) error
+	// Archived returns the record of an archived tournament, or
+	// ErrNoTournament
+	Archived(id string) ([]byte, error)
+	// Summaries returns the summaries of the archived tournaments
+	Summaries() ([]Summary, error)
+	// Delete removes a tournament, its events and its webhooks
+	Delete(id string) error
+	// Players returns the stats of every player over all the tournaments
+	Players() ([]Player, error)
+	// AddEvent records something that happened in a tournament
+	AddEvent(e Event) error
+	// Events returns the events of a tournament, oldest first
+	Events(id string) ([]Event, error)
+	// Webhooks returns the webhooks of a tournament
+	Webhooks(id string) ([]Webhook, error)
+	// SaveWebhooks replaces the webhooks of a tournament
+	SaveWebhooks(id string, hooks []Webhook) error
+	// AddDelivery logs an attempt to deliver an event to a webhook
+	AddDelivery(d Delivery) error
+	// Deliveries returns the delivery log of a tournament, oldest first
+	Deliveries(id string) ([]Delivery, error)
+	// Close closes the store
+	Close() error
+}
+
+// Backupper is a store that can make a consistent copy of itself while in use
+type Backupper interface {
+	Backup(path string) error
+}
+
+// Querier is a store that can answer ad-hoc queries
+type Querier interface {
+	Query(q string, args ...interface{}) ([]map[string]interface{}, error)
+}
+
+// ErrNoTournament is returned when a tournament does not exist
+var ErrNoTournament = errors.New("no such tournament")
+
+// Event is something that happened in a tournament
+type Event struct {
+	Tournament string    `json:"tournament"`
+	Match      string    `json:"match,omitempty"`
+	Kind       string    `json:"kind"`
+	Player     string    `json:"player,omitempty"`
+	Time       time.Time `json:"time"`
+}
+
+// OpenStore opens the store described by spec
+//
+// "memory" is a store that is gone when the program exits, "sqlite:<file>"
+// is an SQLite database and anything else is the path to a bolt database.
+// Existing SQLite files are recognized without the prefix.
+func OpenStore(spec string) (Store, error) {
+	if spec == "memory" {
+		return NewMemoryStore(), nil
+	}
+	if strings.HasPrefix(spec, "sqlite:") {
+		return NewSQLiteStore(strings.TrimPrefix(spec, "sqlite:"))
+	}
+	if isSQLite(spec) {
+		return NewSQLiteStore(spec)
+	}
+	return NewBoltStore(spec)
+}
+
+// CopyStore copies all the tournaments with their events and webhooks from
+// one store into another, and returns how many tournaments were copied
+func CopyStore(dst, src Store) (int, error) {
+	ts, err := src.Tournaments()
+	if err != nil {
+		return 0, err
+	}
+	for id, data := range ts {
+		if err := dst.Save(id, data); err != nil {
+			return 0, fmt.Errorf("%s: %s", id, err)
+		}
+	}
+
+	summaries, err := src.Summaries()
+	if err != nil {
+		return 0, err
+	}
+	for _, s := range summaries {
+		data, err := src.Archived(s.ID)
+		if err != nil {
+			return 0, fmt.Errorf("%s: %s", s.ID, err)
+		}
+		if err := dst.Archive(s.ID, data, s); err != nil {
+			return 0, fmt.Errorf("%s: %s", s.ID, err)
+		}
+		ts[s.ID] = data
+	}
+
+	for id := range ts {
+		events, err := src.Events(id)
+		if err != nil {
+			return 0, err
+		}
+		for _, e := range events {
+			if err := dst.AddEvent(e); err != nil {
+				return 0, err
+			}
+		}
+
+		hooks, err := src.Webhooks(id)
+		if err != nil {
+			return 0, err
+		}
+		if len(hooks) != 0 {
+			if err := dst.SaveWebhooks(id, hooks); err != nil {
+				return 0, err
+			}
+		}
+
+		deliveries, err := src.Deliveries(id)
+		if err != nil {
+			return 0, err
+		}
+		for _, d := range deliveries {
+			if err := dst.AddDelivery(d); err != nil {
+				return 0, err
+			}
+		}
+	}
+	return len(ts), nil
+}
+
+// isSQLite returns boolean whether a file is an SQLite database
+func isSQLite(fn string) bool {
+	f, err := os.Open(fn)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	header := make([]byte, 16)
+	if _, err := f.Read(header); err != nil {
+		return false
+	}
+	return bytes.Equal(header, []byte("SQLite format 3\x00"))
+}
+
+// careerStats sums up the player stats of stored tournament records
+//
+// The players are sorted by their total score, best first.
+func careerStats(records map[string][]byte) ([]Player, error) {
+	ids := make([]string, 0, len(records))
+	for id := range records {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	index := make(map[string]int)
+	out := []Player{}
+	for _, id := range ids {
+		var t struct {
+			Players []Player `json:"players"`
+		}
+		if err := json.Unmarshal(records[id], &t); err != nil {
+			return nil, fmt.Errorf("%s: %s", id, err)
+		}
+
+		for _, p := range t.Players {
+			i, ok := index[p.Name]
+			if !ok {
+				i = len(out)
+				index[p.Name] = i
+				out = append(out, Player{Name: p.Name, PreferredColor: p.PreferredColor})
+			}
+
+			total := &out[i]
+			total.Shots += p.Shots
+			total.Sweeps += p.Sweeps
+			total.Kills += p.Kills
+			total.Self += p.Self
+			total.Explosions += p.Explosions
+			total.Matches += p.Matches
+			total.Forfeits += p.Forfeits
+			for a, n := range p.Achievements {
+				if total.Achievements == nil {
+					total.Achievements = make(map[string]int)
+				}
+				total.Achievements[a] += n
+			}
+		}
+	}
+
+	for i := range out {
+		out[i].TotalScore = out[i].Score()
+	}
+	sortCareer(out)
+	return out, nil
+}
+
+// sortCareer sorts players by their total score, best first
+func sortCareer(ps []Player) {
+	sort.SliceStable(ps, func(i, j int) bool {
+		if ps[i].TotalScore == ps[j].TotalScore {
+			return ps[i].Name < ps[j].Name
+		}
+		return ps[i].TotalScore > ps[j].TotalScore
+	})
+}
+
+// MemoryStore keeps the tournaments in memory only
+//
+// It is what the tests use, so that they don't have to juggle files.
+type MemoryStore struct {
+	sync.RWMutex
+	tournaments map[string][]byte
+	archive     map[string][]byte
+	summaries   map[string]Summary
+	events      map[string][]Event
+	webhooks    map[string][]Webhook
+	deliveries  map[string][]Delivery
+}
+
+// NewMemoryStore returns an empty memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		tournaments: make(map[string][]byte),
+		archive:     make(map[string][]byte),
+		summaries:   make(map[string]Summary),
+		events:      make(map[string][]Event),
+		webhooks:    make(map[string][]Webhook),
+		deliveries:  make(map[string][]Delivery),
+	}
+}
+
+// Tournaments returns the stored records of all the tournaments, by ID
+func (s *MemoryStore) Tournaments() (map[string][]byte, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	out := make(map[string][]byte, len(s.tournaments))
+	for id, data := range s.tournaments {
+		out[id] = data
+	}
+	return out, nil
+}
+
+// Save stores the record of a tournament
+func (s *MemoryStore) Save(id string, data []byte) error {
+	s.Lock()
+	defer s.Unlock()
+
+	s.tournaments[id] = append([]byte{}, data...)
+	delete(s.archive, id)
+	delete(s.summaries, id)
+	return nil
+}
+
+// Archive moves the record of a tournament into the archive
+func (s *MemoryStore) Archive(id string, data []byte, summary Summary) error {
+	s.Lock()
+	defer s.Unlock()
+
+	delete(s.tournaments, id)
+	s.archive[id] = append([]byte{}, data...)
+	s.summaries[id] = summary
+	return nil
+}
+
+// Archived returns the record of an archived tournament
+func (s *MemoryStore) Archived(id string) ([]byte, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	data, ok := s.archive[id]
+	if !ok {
+		return nil, ErrNoTournament
+	}
+	return data, nil
+}
+
+// Summaries returns the summaries of the archived tournaments
+func (s *MemoryStore) Summaries() ([]Summary, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	out := make([]Summary, 0, len(s.summaries))
+	for _, summary := range s.summaries {
+		out = append(out, summary)
+	}
+	return out, nil
+}
+
+// Delete removes a tournament, its events and its webhooks
+func (s *MemoryStore) Delete(id string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	delete(s.tournaments, id)
+	delete(s.archive, id)
+	delete(s.summaries, id)
+	delete(s.events, id)
+	delete(s.webhooks, id)
+	delete(s.deliveries, id)
+	return nil
+}
+
+// Players returns the stats of every player over all the tournaments
+func (s *MemoryStore) Players() ([]Player, error) {
+	ts, _ := s.Tournaments()
+
+	s.RLock()
+	for id, data := range s.archive {
+		ts[id] = data
+	}
+	s.RUnlock()
+	return careerStats(ts)
+}
+
+// AddEvent records something that happened in a tournament
+func (s *MemoryStore) AddEvent(e Event) error {
+	s.Lock()
+	defer s.Unlock()
+
+	s.events[e.Tournament] = append(s.events[e.Tournament], e)
+	return nil
+}
+
+// Events returns the events of a tournament, oldest first
+func (s *MemoryStore) Events(id string) ([]Event, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	return append([]Event{}, s.events[id]...), nil
+}
+
+// Webhooks returns the webhooks of a tournament
+func (s *MemoryStore) Webhooks(id string) ([]Webhook, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	return append([]Webhook{}, s.webhooks[id]...), nil
+}
+
+// SaveWebhooks replaces the webhooks of a tournament
+func (s *MemoryStore) SaveWebhooks(id string, hooks []Webhook) error {
+	s.Lock()
+	defer s.Unlock()
+
+	s.webhooks[id] = append([]Webhook{}, hooks...)
+	return nil
+}
+
+// AddDelivery logs an attempt to deliver an event to a webhook
+func (s *MemoryStore) AddDelivery(d Delivery) error {
+	s.Lock()
+	defer s.Unlock()
+
+	s.deliveries[d.Tournament] = append(s.deliveries[d.Tournament], d)
+	return nil
+}
+
+// Deliveries returns the delivery log of a tournament, oldest first
+func (s *MemoryStore) Deliveries(id string) ([]Delivery, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	return append([]Delivery{}, s.deliveries[id]...), nil
+}
+
+// Close does nothing, since there is nothing to close
+func (s *MemoryStore) Close() error {
+	return nil
+}
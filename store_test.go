@@ -0,0 +1,272 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"os"
+	"testing"
+	"time"
+)
+
+// testStores returns a clean store of every kind
+func testStores() map[string]Store {
+	os.Mkdir("test/", 0700)
+	os.Remove("test/store.db")
+	os.Remove("test/store.sqlite")
+
+	bolt, err := NewBoltStore("test/store.db")
+	if err != nil {
+		panic(err)
+	}
+	sqlite, err := NewSQLiteStore("test/store.sqlite")
+	if err != nil {
+		panic(err)
+	}
+
+	return map[string]Store{
+		"memory": NewMemoryStore(),
+		"bolt":   bolt,
+		"sqlite": sqlite,
+	}
+}
+
+func TestStoreSaveAndDelete(t *testing.T) {
+	for kind, s := range testStores() {
+		assert := assert.New(t)
+		tm := testTournament(8)
+		data, _ := tm.JSON()
+
+		assert.Nil(s.Save(tm.ID, data), kind)
+		assert.Nil(s.Save(tm.ID, data), kind)
+		ts, err := s.Tournaments()
+		assert.Nil(err, kind)
+		assert.Equal(1, len(ts), kind)
+		assert.Equal(data, ts[tm.ID], kind)
+
+		assert.Nil(s.AddEvent(Event{Tournament: tm.ID, Kind: "tournament_started", Time: time.Now()}), kind)
+		assert.Nil(s.Delete(tm.ID), kind)
+		ts, _ = s.Tournaments()
+		assert.Equal(0, len(ts), kind)
+		events, _ := s.Events(tm.ID)
+		assert.Equal(0, len(events), kind)
+
+		s.Close()
+	}
+}
+
+func TestStoreArchive(t *testing.T) {
+	for kind, s := range testStores() {
+		assert := assert.New(t)
+		tm := testTournament(8)
+		data, _ := tm.JSON()
+		s.Save(tm.ID, data)
+
+		_, err := s.Archived(tm.ID)
+		assert.Equal(ErrNoTournament, err, kind)
+
+		summary := tm.Summary()
+		summary.Archived = true
+		assert.Nil(s.Archive(tm.ID, data, summary), kind)
+
+		ts, _ := s.Tournaments()
+		assert.Equal(0, len(ts), kind)
+		archived, err := s.Archived(tm.ID)
+		assert.Nil(err, kind)
+		assert.Equal(data, archived, kind)
+
+		summaries, err := s.Summaries()
+		assert.Nil(err, kind)
+		assert.Equal(1, len(summaries), kind)
+		assert.Equal(tm.Name, summaries[0].Name, kind)
+		assert.True(summaries[0].Archived, kind)
+		assert.True(tm.Opened.Equal(summaries[0].Opened), kind)
+
+		// Archived players still count for the career stats
+		ps, _ := s.Players()
+		assert.Equal(8, len(ps), kind)
+
+		// Saving it again takes it out of the archive
+		assert.Nil(s.Save(tm.ID, data), kind)
+		summaries, _ = s.Summaries()
+		assert.Equal(0, len(summaries), kind)
+
+		s.Close()
+	}
+}
+
+func TestStoreEventsAreInOrder(t *testing.T) {
+	for kind, s := range testStores() {
+		assert := assert.New(t)
+		now := time.Now().UTC().Truncate(time.Second)
+
+		for i, k := range []string{"match_started", "match_ended", "tournament_ended"} {
+			e := Event{Tournament: "a", Match: "final-0", Kind: k, Player: "winner", Time: now.Add(time.Duration(i) * time.Second)}
+			assert.Nil(s.AddEvent(e), kind)
+		}
+		s.AddEvent(Event{Tournament: "b", Kind: "tournament_started", Time: now})
+
+		events, err := s.Events("a")
+		assert.Nil(err, kind)
+		assert.Equal(3, len(events), kind)
+		assert.Equal("match_started", events[0].Kind, kind)
+		assert.Equal("tournament_ended", events[2].Kind, kind)
+		assert.Equal("winner", events[2].Player, kind)
+		assert.True(now.Add(2*time.Second).Equal(events[2].Time), kind)
+
+		s.Close()
+	}
+}
+
+func TestStorePlayersAreSummedOverTournaments(t *testing.T) {
+	for kind, s := range testStores() {
+		assert := assert.New(t)
+		for _, tm := range []*Tournament{testTournament(8), testTournament(9)} {
+			tm.StartTournament()
+			playMatch(tm.Tryouts[0])
+			tm.UpdatePlayers()
+			data, _ := tm.JSON()
+			s.Save(tm.ID, data)
+		}
+
+		ps, err := s.Players()
+		assert.Nil(err, kind)
+		assert.Equal(9, len(ps), kind)
+		for i := 1; i < len(ps); i++ {
+			assert.True(ps[i-1].TotalScore >= ps[i].TotalScore, kind)
+		}
+		for _, p := range ps {
+			assert.Equal(p.Score(), p.TotalScore, kind)
+		}
+
+		s.Close()
+	}
+}
+
+func TestStorePlayersCountAchievements(t *testing.T) {
+	for kind, s := range testStores() {
+		assert := assert.New(t)
+		for _, tm := range []*Tournament{testTournament(8), testTournament(9)} {
+			tm.StartTournament()
+			playMatch(tm.Tryouts[0])
+			data, _ := tm.JSON()
+			s.Save(tm.ID, data)
+		}
+
+		ps, err := s.Players()
+		assert.Nil(err, kind)
+
+		// Both winners made it without killing themselves
+		flawless := 0
+		for _, p := range ps {
+			flawless += p.Achievements["flawless"]
+		}
+		assert.Equal(2, flawless, kind)
+
+		s.Close()
+	}
+}
+
+func TestStoreWebhooks(t *testing.T) {
+	for kind, s := range testStores() {
+		assert := assert.New(t)
+		now := time.Now().Truncate(time.Second)
+		hooks := []Webhook{
+			{ID: "a", URL: "http://a/", Secret: "x", Events: []string{}, Created: now},
+			{ID: "b", URL: "http://b/", Secret: "y", Events: []string{"match_ended", "tournament_ended"}, Created: now.Add(time.Second)},
+		}
+
+		assert.Nil(s.SaveWebhooks("1", hooks), kind)
+		assert.Nil(s.AddDelivery(Delivery{Tournament: "1", Webhook: "a", ID: "d", Event: "match_ended", Attempt: 1, Status: 500, Error: "no", Time: now}), kind)
+		assert.Nil(s.AddDelivery(Delivery{Tournament: "1", Webhook: "a", ID: "d", Event: "match_ended", Attempt: 2, Status: 200, Time: now}), kind)
+
+		stored, err := s.Webhooks("1")
+		assert.Nil(err, kind)
+		assert.Equal(2, len(stored), kind)
+		assert.Equal("y", stored[1].Secret, kind)
+		assert.Equal(hooks[1].Events, stored[1].Events, kind)
+		assert.True(now.Equal(stored[0].Created), kind)
+
+		ds, err := s.Deliveries("1")
+		assert.Nil(err, kind)
+		assert.Equal(2, len(ds), kind)
+		assert.Equal(1, ds[0].Attempt, kind)
+		assert.Equal("no", ds[0].Error, kind)
+		assert.Equal(200, ds[1].Status, kind)
+
+		assert.Nil(s.Delete("1"), kind)
+		stored, _ = s.Webhooks("1")
+		ds, _ = s.Deliveries("1")
+		assert.Equal(0, len(stored), kind)
+		assert.Equal(0, len(ds), kind)
+
+		s.Close()
+	}
+}
+
+func TestTournamentRecordsEvents(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTournament(8)
+	tm.StartTournament()
+	playMatch(tm.Tryouts[0])
+
+	events, err := tm.db.Store.Events(tm.ID)
+	assert.Nil(err)
+	assert.Equal(3, len(events))
+	assert.Equal("tournament_started", events[0].Kind)
+	assert.Equal("match_started", events[1].Kind)
+	assert.Equal("tryout-0", events[1].Match)
+	assert.Equal("match_ended", events[2].Kind)
+	assert.Equal(tm.Tryouts[0].Players[0].Name, events[2].Player)
+}
+
+func TestOpenStore(t *testing.T) {
+	assert := assert.New(t)
+	os.Mkdir("test/", 0700)
+	os.Remove("test/open.db")
+	os.Remove("test/open.sqlite")
+
+	s, err := OpenStore("memory")
+	assert.Nil(err)
+	assert.IsType(&MemoryStore{}, s)
+
+	s, err = OpenStore("test/open.db")
+	assert.Nil(err)
+	assert.IsType(&BoltStore{}, s)
+	s.Close()
+
+	s, err = OpenStore("sqlite:test/open.sqlite")
+	assert.Nil(err)
+	assert.IsType(&SQLiteStore{}, s)
+	s.Close()
+
+	// Existing SQLite files are recognized without the prefix
+	s, err = OpenStore("test/open.sqlite")
+	assert.Nil(err)
+	assert.IsType(&SQLiteStore{}, s)
+	s.Close()
+}
+
+func TestCopyStore(t *testing.T) {
+	assert := assert.New(t)
+	stores := testStores()
+	defer stores["bolt"].Close()
+	defer stores["sqlite"].Close()
+
+	tm := testTournament(8)
+	tm.StartTournament()
+	tm.db.Persist(tm)
+
+	n, err := CopyStore(stores["bolt"], tm.db.Store)
+	assert.Nil(err)
+	assert.Equal(1, n)
+
+	n, err = CopyStore(stores["sqlite"], stores["bolt"])
+	assert.Nil(err)
+	assert.Equal(1, n)
+
+	db := NewStoreDatabase(stores["sqlite"])
+	assert.Nil(db.LoadTournaments())
+	assert.Equal(tm.Name, db.tournamentRef[tm.ID].Name)
+
+	events, _ := stores["sqlite"].Events(tm.ID)
+	assert.Equal(1, len(events))
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"sort"
+)
+
+// Tournament modes
+const (
+	// SoloMode is the classic every-archer-for-themselves tournament
+	SoloMode = "solo"
+	// TeamMode is a tournament where teams of two face off in 2v2 matches
+	TeamMode = "team"
+)
+
+// TeamSize is the number of players in a team
+const TeamSize = 2
+
+// MinTeams is the number of teams a team tournament needs to start
+//
+// With that few teams, only two tryouts are played. One team advances from
+// each of them, and the two that lost fill up the semis.
+const MinTeams = 4
+
+// Team is a pair of players competing together in team mode
+type Team struct {
+	Name    string   `json:"name"`
+	Color   string   `json:"color"`
+	Members []string `json:"members"`
+}
+
+// HasMember returns whether the named player is a member of the team
+func (t *Team) HasMember(name string) bool {
+	for _, m := range t.Members {
+		if m == name {
+			return true
+		}
+	}
+	return false
+}
+
+// HasTeam returns whether any member of the named team is in the match
+func (m *Match) HasTeam(name string) bool {
+	for _, p := range m.Players {
+		if !p.IsPrefill() && p.Team == name {
+			return true
+		}
+	}
+	return false
+}
+
+// TeamScore is the combined score of the members of a team in a match
+type TeamScore struct {
+	Name    string   `json:"name"`
+	Kills   int      `json:"kills"`
+	Self    int      `json:"self"`
+	Shots   int      `json:"shots"`
+	Players []Player `json:"-"`
+}
+
+// TeamStandings returns the teams in a match ordered by their placement
+//
+// Players that are not in a team (i.e. prefills) are ignored.
+func (m *Match) TeamStandings() []TeamScore {
+	scores := make([]TeamScore, 0, 2)
+	for _, p := range m.Players {
+		if p.IsPrefill() || p.Team == "" {
+			continue
+		}
+
+		found := false
+		for i := range scores {
+			if scores[i].Name == p.Team {
+				scores[i].add(p)
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			ts := TeamScore{Name: p.Team}
+			ts.add(p)
+			scores = append(scores, ts)
+		}
+	}
+
+	sort.Stable(byTeamPlacement{m: m, ts: scores})
+	return scores
+}
+
+func (ts *TeamScore) add(p Player) {
+	ts.Kills += p.Kills
+	ts.Self += p.Self
+	ts.Shots += p.Shots
+	ts.Players = append(ts.Players, p)
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"log"
+	"strconv"
+	"testing"
+)
+
+// testTeamTournament makes a test team tournament with `count` teams.
+func testTeamTournament(count int) (t *Tournament) {
+	s := strconv.Itoa(count)
+	db := MockDatabase()
+	t, err := NewTournament("Team Tournament "+s, "team"+s, testServer(db))
+	if err != nil {
+		log.Fatal("tournament creation failed")
+	}
+	_ = t.SetMode(TeamMode)
+
+	for i := 1; i <= count; i++ {
+		name := strconv.Itoa(i)
+		members := []Player{
+			{Name: name + "a", PreferredColor: Colors[(i*2)%len(Colors)]},
+			{Name: name + "b", PreferredColor: Colors[(i*2+1)%len(Colors)]},
+		}
+		err = t.AddTeam("team "+name, Colors[i%len(Colors)], members)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	return
+}
+
+func TestAddTeamToSoloTournamentFails(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTournament(0)
+
+	err := tm.AddTeam("team", "red", []Player{{Name: "a"}, {Name: "b"}})
+	assert.NotNil(err)
+}
+
+func TestAddPlayerToTeamTournamentFails(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTeamTournament(0)
+
+	err := tm.AddPlayer("a", "green")
+	assert.NotNil(err)
+}
+
+func TestAddTeamNeedsTwoMembers(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTeamTournament(0)
+
+	err := tm.AddTeam("team", "red", []Player{{Name: "a"}})
+	assert.NotNil(err)
+	assert.Equal(0, len(tm.Teams))
+}
+
+func TestAddTeamWithExistingPlayerFails(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTeamTournament(1)
+
+	err := tm.AddTeam("other", "red", []Player{{Name: "1a"}, {Name: "x"}})
+	assert.NotNil(err)
+	assert.Equal(1, len(tm.Teams))
+	assert.Equal(2, len(tm.Players))
+}
+
+func TestAddTeamSetsTeamOnPlayers(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTeamTournament(1)
+
+	assert.Equal(2, len(tm.Players))
+	assert.Equal("team 1", tm.getPlayer("1a").Team)
+	assert.Equal("team 1", tm.getPlayer("1b").Team)
+}
+
+func TestTeamTournamentIsFullAtEightTeams(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTeamTournament(8)
+
+	err := tm.AddTeam("late", "red", []Player{{Name: "x"}, {Name: "y"}})
+	assert.NotNil(err)
+	assert.False(tm.IsJoinable())
+}
+
+func TestShuffleKeepsTeamsTogether(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTeamTournament(8)
+
+	for _, m := range tm.Tryouts {
+		standings := m.TeamStandings()
+		assert.Equal(2, len(standings))
+		assert.Equal(2, len(standings[0].Players))
+		assert.Equal(2, len(standings[1].Players))
+	}
+}
+
+func TestStartingTeamTournamentWithFewerThan4TeamsFail(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTeamTournament(3)
+
+	// Pad the tournament with solo players to make sure the teams are counted
+	tm.Players = append(tm.Players, Player{Name: "x"}, Player{Name: "y"})
+
+	err := tm.StartTournament()
+	assert.NotNil(err)
+}
+
+func TestEndTeamTryoutMovesWinningTeamIntoSemis(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTeamTournament(8)
+	tm.StartTournament()
+
+	m, err := tm.NextMatch()
+	assert.Nil(err)
+	m.Start()
+
+	standings := m.TeamStandings()
+	winners := standings[0].Name
+	for i, p := range m.Players {
+		if p.Team == winners {
+			m.Players[i].AddKill(5)
+		} else {
+			m.Players[i].AddKill(4)
+		}
+	}
+
+	m.End()
+
+	assert.Equal(2, tm.Semis[0].ActualPlayers())
+	assert.Equal(0, tm.Semis[1].ActualPlayers())
+	assert.True(tm.Semis[0].HasTeam(winners))
+	assert.Equal(2, len(tm.Runnerups))
+}
+
+func TestTeamStandingsSumsMemberKills(t *testing.T) {
+	assert := assert.New(t)
+
+	m := NewMatch(tm, 0, "tryout")
+	_ = m.AddPlayer(Player{Name: "1", Team: "a"})
+	_ = m.AddPlayer(Player{Name: "2", Team: "b"})
+	_ = m.AddPlayer(Player{Name: "3", Team: "a"})
+	_ = m.AddPlayer(Player{Name: "4", Team: "b"})
+
+	m.Players[0].AddKill(3)
+	m.Players[1].AddKill(6)
+	m.Players[2].AddKill(4)
+	m.Players[3].AddSelf()
+
+	standings := m.TeamStandings()
+	assert.Equal("a", standings[0].Name)
+	assert.Equal(7, standings[0].Kills)
+	assert.Equal("b", standings[1].Name)
+	assert.Equal(6, standings[1].Kills)
+	assert.Equal(1, standings[1].Self)
+}
+
+func TestEndCompleteFourTeamTournament(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTeamTournament(4)
+	assert.Nil(tm.StartTournament())
+	assert.Equal(2, len(tm.Tryouts))
+
+	played := []string{}
+	for {
+		m, err := tm.NextMatch()
+		if err != nil {
+			break
+		}
+
+		assert.Nil(m.Start())
+		assert.Equal(4, m.ActualPlayers())
+		assert.Equal(2, len(m.TeamStandings()))
+
+		// The team of the first player always wins
+		for i, p := range m.Players {
+			if p.Team == m.Players[0].Team {
+				m.Players[i].AddKill(m.Length() / 2)
+			}
+		}
+		assert.Nil(m.End())
+		played = append(played, m.bracketID())
+	}
+
+	assert.Equal([]string{"tryout-0", "tryout-1", "semi-0", "semi-1", "final-0"}, played)
+	assert.Equal(0, len(tm.RunnerupRounds))
+	assert.Equal(4, len(tm.Winners))
+	assert.True(tm.Final.IsEnded())
+}
+
+func TestEndCompleteTeamTournament(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTeamTournament(5)
+	tm.StartTournament()
+
+	for {
+		m, err := tm.NextMatch()
+		if err != nil {
+			break
+		}
+
+		assert.Nil(m.Start())
+		assert.Equal(4, m.ActualPlayers())
+		assert.Equal(2, len(m.TeamStandings()))
+
+		// The team of the first player always wins
+		for i, p := range m.Players {
+			if p.Team == m.Players[0].Team {
+				m.Players[i].AddKill(m.Length() / 2)
+			}
+		}
+		assert.Nil(m.End())
+	}
+
+	assert.Equal(4, len(tm.Winners))
+	assert.Equal(tm.Winners[0].Team, tm.Winners[1].Team)
+	assert.NotEqual(tm.Winners[0].Team, tm.Winners[2].Team)
+}
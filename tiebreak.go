@@ -0,0 +1,286 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Ties are broken by the following rules, in order:
+//
+//   1. Most kills
+//   2. Fewest self-kills
+//   3. Head-to-head, i.e. who had the most kills in the previous matches where
+//      both players met. This only applies to two tied players, since three
+//      or more can beat each other in a circle.
+//   4. Sudden death, i.e. an extra round played out and reported by the judge
+//
+// If the first three rules cannot separate players whose placement matters
+// for the outcome of the match, the match is tie pending and cannot end until
+// a sudden death winner has been set via ResolveTie().
+
+// Standings returns the players of the match ordered by their placement
+//
+// Prefill players are always placed last. Players that are still tied after
+// all rules keep the order they have in the match.
+func (m *Match) Standings() []Player {
+	ps := make([]Player, len(m.Players))
+	copy(ps, m.Players)
+	sort.Stable(byPlacement{m: m, ps: ps})
+	return ps
+}
+
+// IsTiePending returns boolean whether the match is waiting for a sudden
+// death round to be resolved
+func (m *Match) IsTiePending() bool {
+	return len(m.Tied) != 0
+}
+
+// ResolveTie sets the winner of a sudden death round between the tied players
+// or teams
+func (m *Match) ResolveTie(winner string) error {
+	if !m.IsTiePending() {
+		return errors.New("no tie pending")
+	}
+
+	found := false
+	for _, n := range m.Tied {
+		if n == winner {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return fmt.Errorf("%s is not part of the tie", winner)
+	}
+
+	m.SuddenDeath = append(m.SuddenDeath, winner)
+
+	// There might be more than two tied, in which case the rest still need
+	// to be resolved.
+	m.Tied = m.unresolvedTies()
+
+	if m.Tournament != nil {
+		return m.Tournament.Persist()
+	}
+	return nil
+}
+
+// placements returns the amount of top placements that matter for the
+// outcome of the match, i.e. advancement or medals.
+func (m *Match) placements() int {
+	if m.Tournament != nil && m.Tournament.IsTeamMode() {
+		return 1
+	}
+
+	switch m.Kind {
+	case "tryout":
+		if m.Tournament != nil && len(m.Tournament.Tryouts) == 4 {
+			return 2
+		}
+	case "semi":
+		return 2
+	case "final":
+		return 3
+	case "runnerup":
+		// Nothing rides on the runner-up rounds
+		return 0
+	}
+	return 1
+}
+
+// unresolvedTies returns the names of the players or teams that cannot be
+// separated by the rules and whose placement matters
+func (m *Match) unresolvedTies() []string {
+	if m.Tournament != nil && m.Tournament.IsTeamMode() {
+		ts := m.TeamStandings()
+		if len(ts) > 1 && m.compareTeams(ts[0], ts[1]) == 0 {
+			return []string{ts[0].Name, ts[1].Name}
+		}
+		return nil
+	}
+
+	ps := m.Standings()
+	for i := 0; i < m.placements() && i+1 < len(ps); i++ {
+		if ps[i+1].IsPrefill() {
+			break
+		}
+		if m.compare(ps[i], ps[i+1]) != 0 {
+			continue
+		}
+
+		names := []string{}
+		for _, p := range ps {
+			if !p.IsPrefill() && m.compare(ps[i], p) == 0 {
+				names = append(names, p.Name)
+			}
+		}
+		return names
+	}
+	return nil
+}
+
+// compare returns -1 if a is placed before b, 1 if b is placed before a and 0
+// if they cannot be separated
+func (m *Match) compare(a, b Player) int {
+	if a.IsPrefill() != b.IsPrefill() {
+		if a.IsPrefill() {
+			return 1
+		}
+		return -1
+	}
+
+	if a.Kills != b.Kills {
+		if a.Kills > b.Kills {
+			return -1
+		}
+		return 1
+	}
+
+	if a.Self != b.Self {
+		if a.Self < b.Self {
+			return -1
+		}
+		return 1
+	}
+
+	if m.tiedWith(a) == 2 {
+		if h := m.headToHead(a.Name, b.Name); h != 0 {
+			return h
+		}
+	}
+
+	return m.suddenDeath(a.Name, b.Name)
+}
+
+// compareTeams is compare() for teams, without the head-to-head rule since
+// teams never meet twice.
+func (m *Match) compareTeams(a, b TeamScore) int {
+	if a.Kills != b.Kills {
+		if a.Kills > b.Kills {
+			return -1
+		}
+		return 1
+	}
+
+	if a.Self != b.Self {
+		if a.Self < b.Self {
+			return -1
+		}
+		return 1
+	}
+
+	return m.suddenDeath(a.Name, b.Name)
+}
+
+// headToHead compares how two players did against each other in the other
+// ended matches of the tournament
+func (m *Match) headToHead(a, b string) int {
+	if m.Tournament == nil || a == "" || b == "" {
+		return 0
+	}
+
+	wins := 0
+	for _, o := range m.Tournament.Matches() {
+		if o == m || !o.IsEnded() {
+			continue
+		}
+
+		pa, pb := o.getPlayer(a), o.getPlayer(b)
+		if pa == nil || pb == nil {
+			continue
+		}
+
+		if pa.Kills > pb.Kills {
+			wins++
+		} else if pb.Kills > pa.Kills {
+			wins--
+		}
+	}
+
+	if wins > 0 {
+		return -1
+	} else if wins < 0 {
+		return 1
+	}
+	return 0
+}
+
+// tiedWith returns how many players of the match, the player included, have
+// the same kills and self-kills as the player
+func (m *Match) tiedWith(p Player) int {
+	n := 0
+	for _, o := range m.Players {
+		if !o.IsPrefill() && o.Kills == p.Kills && o.Self == p.Self {
+			n++
+		}
+	}
+	return n
+}
+
+// suddenDeath compares two names by who won a sudden death round first
+func (m *Match) suddenDeath(a, b string) int {
+	for _, n := range m.SuddenDeath {
+		if n == a {
+			return -1
+		} else if n == b {
+			return 1
+		}
+	}
+	return 0
+}
+
+func (m *Match) getPlayer(name string) *Player {
+	for i := range m.Players {
+		if m.Players[i].Name == name {
+			return &m.Players[i]
+		}
+	}
+	return nil
+}
+
+// tieMessage returns a human readable explanation of a pending tie
+func (m *Match) tieMessage() string {
+	return fmt.Sprintf(
+		"%s tied between %s - needs a sudden death round",
+		m.String(),
+		strings.Join(m.Tied, " / "),
+	)
+}
+
+// byPlacement is a sort.Interface that sorts players by the tie-break rules
+type byPlacement struct {
+	m  *Match
+	ps []Player
+}
+
+func (s byPlacement) Len() int {
+	return len(s.ps)
+
+}
+func (s byPlacement) Swap(i, j int) {
+	s.ps[i], s.ps[j] = s.ps[j], s.ps[i]
+
+}
+func (s byPlacement) Less(i, j int) bool {
+	return s.m.compare(s.ps[i], s.ps[j]) < 0
+}
+
+// byTeamPlacement is a sort.Interface that sorts teams by the tie-break rules
+type byTeamPlacement struct {
+	m  *Match
+	ts []TeamScore
+}
+
+func (s byTeamPlacement) Len() int {
+	return len(s.ts)
+
+}
+func (s byTeamPlacement) Swap(i, j int) {
+	s.ts[i], s.ts[j] = s.ts[j], s.ts[i]
+
+}
+func (s byTeamPlacement) Less(i, j int) bool {
+	return s.m.compareTeams(s.ts[i], s.ts[j]) < 0
+}
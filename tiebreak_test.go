@@ -0,0 +1,206 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func testTiedMatch(kind string) *Match {
+	m := NewMatch(tm, 0, kind)
+	_ = m.AddPlayer(Player{Name: "a"})
+	_ = m.AddPlayer(Player{Name: "b"})
+	_ = m.AddPlayer(Player{Name: "c"})
+	_ = m.AddPlayer(Player{Name: "d"})
+	_ = m.Start()
+	return m
+}
+
+func TestStandingsSortsByKills(t *testing.T) {
+	assert := assert.New(t)
+	m := testTiedMatch("tryout")
+
+	m.Players[0].AddKill(2)
+	m.Players[1].AddKill(10)
+	m.Players[2].AddKill(5)
+	m.Players[3].AddKill(7)
+
+	ps := m.Standings()
+	assert.Equal("b", ps[0].Name)
+	assert.Equal("d", ps[1].Name)
+	assert.Equal("c", ps[2].Name)
+	assert.Equal("a", ps[3].Name)
+
+	// The match itself should not be reordered
+	assert.Equal("a", m.Players[0].Name)
+}
+
+func TestStandingsFewestSelfKillsBreaksTie(t *testing.T) {
+	assert := assert.New(t)
+	m := testTiedMatch("tryout")
+
+	m.Players[0].AddKill(11)
+	m.Players[0].AddSelf()
+	m.Players[1].AddKill(10)
+
+	ps := m.Standings()
+	assert.Equal("b", ps[0].Name)
+	assert.Equal("a", ps[1].Name)
+}
+
+func TestEndWithTieForWinnerIsPending(t *testing.T) {
+	assert := assert.New(t)
+	m := testTiedMatch("tryout")
+
+	m.Players[0].AddKill(10)
+	m.Players[1].AddKill(10)
+	m.Players[2].AddKill(3)
+
+	err := m.End()
+	assert.NotNil(err)
+	assert.False(m.IsEnded())
+	assert.True(m.IsTiePending())
+	assert.Equal([]string{"a", "b"}, m.Tied)
+}
+
+func TestEndWithTieOutsidePlacementsEnds(t *testing.T) {
+	assert := assert.New(t)
+	m := testTiedMatch("tryout")
+
+	m.Players[0].AddKill(10)
+	m.Players[1].AddKill(3)
+	m.Players[2].AddKill(3)
+
+	err := m.End()
+	assert.Nil(err)
+	assert.True(m.IsEnded())
+	assert.False(m.IsTiePending())
+}
+
+func TestEndFinalWithTieForBronzeIsPending(t *testing.T) {
+	assert := assert.New(t)
+	m := testTiedMatch("final")
+
+	m.Players[0].AddKill(20)
+	m.Players[1].AddKill(15)
+	m.Players[2].AddKill(4)
+	m.Players[3].AddKill(4)
+
+	err := m.End()
+	assert.NotNil(err)
+	assert.Equal([]string{"c", "d"}, m.Tied)
+}
+
+func TestResolveTieAllowsEnd(t *testing.T) {
+	assert := assert.New(t)
+	m := testTiedMatch("tryout")
+
+	m.Players[0].AddKill(10)
+	m.Players[1].AddKill(10)
+
+	assert.NotNil(m.End())
+	assert.Nil(m.ResolveTie("b"))
+	assert.False(m.IsTiePending())
+
+	assert.Nil(m.End())
+	assert.Equal("b", m.Standings()[0].Name)
+	assert.Equal(1, m.Players[1].Shots)
+}
+
+func TestResolveTieWithThreeTiedPlayersNeedsTwoRounds(t *testing.T) {
+	assert := assert.New(t)
+	m := testTiedMatch("final")
+
+	m.Players[0].AddKill(20)
+	m.Players[1].AddKill(8)
+	m.Players[2].AddKill(8)
+	m.Players[3].AddKill(8)
+
+	assert.NotNil(m.End())
+	assert.Equal(3, len(m.Tied))
+
+	assert.Nil(m.ResolveTie("d"))
+	assert.True(m.IsTiePending())
+	assert.Equal([]string{"b", "c"}, m.Tied)
+
+	assert.Nil(m.ResolveTie("c"))
+	assert.False(m.IsTiePending())
+
+	ps := m.Standings()
+	assert.Equal("d", ps[1].Name)
+	assert.Equal("c", ps[2].Name)
+	assert.Equal("b", ps[3].Name)
+}
+
+func TestResolveTieWithPlayerNotInTieFails(t *testing.T) {
+	assert := assert.New(t)
+	m := testTiedMatch("tryout")
+
+	m.Players[0].AddKill(10)
+	m.Players[1].AddKill(10)
+
+	assert.NotNil(m.End())
+	assert.NotNil(m.ResolveTie("c"))
+	assert.True(m.IsTiePending())
+}
+
+func TestResolveTieWithoutTieFails(t *testing.T) {
+	assert := assert.New(t)
+	m := testTiedMatch("tryout")
+
+	assert.NotNil(m.ResolveTie("a"))
+}
+
+func TestHeadToHeadBreaksTie(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTournament(16)
+	tm.StartTournament()
+	endTryouts(tm)
+	endSemis(tm)
+
+	// The first two players in the final met in the first semi, where the
+	// first one won. Tie them in the final and the semi should decide.
+	f := tm.Final
+	f.Start()
+	winner := f.Players[0].Name
+	loser := f.Players[1].Name
+
+	f.Players[0].AddKill(20)
+	f.Players[1].AddKill(20)
+	f.Players[2].AddKill(5)
+	f.Players[3].AddKill(3)
+
+	assert.Nil(f.End())
+	assert.Equal(winner, tm.Winners[0].Name)
+	assert.Equal(loser, tm.Winners[1].Name)
+}
+
+func TestHeadToHeadCycleIsPending(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTournament(8)
+
+	// a beat b, b beat c and c beat a
+	met := func(winner, loser string) *Match {
+		o := NewMatch(tm, 0, "tryout")
+		_ = o.AddPlayer(Player{Name: winner})
+		_ = o.AddPlayer(Player{Name: loser})
+		o.Players[0].AddKill(5)
+		o.Players[1].AddKill(3)
+		o.Ended = time.Now()
+		return o
+	}
+	tm.Tryouts = []*Match{met("a", "b"), met("b", "c"), met("c", "a")}
+
+	m := NewMatch(tm, 0, "final")
+	for _, n := range []string{"a", "b", "c", "d"} {
+		_ = m.AddPlayer(Player{Name: n})
+	}
+	_ = m.Start()
+	m.Players[0].AddKill(8)
+	m.Players[1].AddKill(8)
+	m.Players[2].AddKill(8)
+	m.Players[3].AddKill(20)
+
+	assert.NotNil(m.End())
+	assert.Equal([]string{"a", "b", "c"}, m.Tied)
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"time"
+
+	"github.com/thiderman/drunkenfall/websockets"
+)
+
+// DefaultReplayInterval is how long a replay waits between the rounds
+const DefaultReplayInterval = 2 * time.Second
+
+// Round is the state of a match after a round was committed
+//
+// Ups and Downs are what the judge committed, and Kills and Shots are the
+// totals of the players after the round.
+type Round struct {
+	Time  time.Time `json:"time"`
+	Ups   []int     `json:"ups"`
+	Downs []int     `json:"downs"`
+	Kills []int     `json:"kills"`
+	Shots []int     `json:"shots"`
+}
+
+// Timeline is how a match unfolded, round by round
+type Timeline struct {
+	Tournament string          `json:"tournament"`
+	Match      string          `json:"match"`
+	URL        string          `json:"url"`
+	Players    []string        `json:"players"`
+	Started    time.Time       `json:"started"`
+	Ended      time.Time       `json:"ended"`
+	Rounds     []TimelineRound `json:"rounds"`
+}
+
+// TimelineRound is one round in the timeline
+//
+// Elapsed is the number of seconds since the match started. The leader is
+// empty while the lead is shared.
+type TimelineRound struct {
+	Round   int       `json:"round"`
+	Time    time.Time `json:"time"`
+	Elapsed int       `json:"elapsed"`
+	Scored  []string  `json:"scored"`
+	Drank   []string  `json:"drank"`
+	Kills   []int     `json:"kills"`
+	Leader  string    `json:"leader"`
+}
+
+// ReplayMessage is a round of a match being replayed over the websocket
+type ReplayMessage struct {
+	Replay ReplayFrame `json:"replay"`
+}
+
+// ReplayFrame is one step of a replay
+type ReplayFrame struct {
+	Tournament string        `json:"tournament"`
+	Match      string        `json:"match"`
+	Players    []string      `json:"players"`
+	Round      TimelineRound `json:"round"`
+	Rounds     int           `json:"rounds"`
+}
+
+// recordRound adds the current state of the players as a round
+func (m *Match) recordRound(scores [][]int) {
+	r := Round{
+		Time:  time.Now(),
+		Ups:   make([]int, len(m.Players)),
+		Downs: make([]int, len(m.Players)),
+		Kills: make([]int, len(m.Players)),
+		Shots: make([]int, len(m.Players)),
+	}
+	for i, p := range m.Players {
+		r.Ups[i] = scores[i][0]
+		r.Downs[i] = scores[i][1]
+		r.Kills[i] = p.Kills
+		r.Shots[i] = p.Shots
+	}
+	m.Rounds = append(m.Rounds, r)
+}
+
+// Timeline returns the rounds of the match as they were played
+func (m *Match) Timeline() Timeline {
+	tl := Timeline{
+		Match:   m.Title(),
+		Players: make([]string, len(m.Players)),
+		Started: m.Started,
+		Ended:   m.Ended,
+		Rounds:  make([]TimelineRound, 0, len(m.Rounds)),
+	}
+	if m.Tournament != nil {
+		tl.Tournament = m.Tournament.ID
+		tl.URL = m.URL()
+	}
+	for i, p := range m.Players {
+		tl.Players[i] = p.Name
+	}
+
+	shots := make([]int, len(m.Players))
+	for n, r := range m.Rounds {
+		tr := TimelineRound{
+			Round:  n + 1,
+			Time:   r.Time,
+			Scored: []string{},
+			Drank:  []string{},
+			Kills:  r.Kills,
+			Leader: leader(tl.Players, r.Kills),
+		}
+		if !m.Started.IsZero() {
+			tr.Elapsed = int(r.Time.Sub(m.Started).Seconds())
+		}
+
+		for i, name := range tl.Players {
+			if i < len(r.Ups) && r.Ups[i] > 0 {
+				tr.Scored = append(tr.Scored, name)
+			}
+			if i < len(r.Shots) && i < len(shots) {
+				if r.Shots[i] > shots[i] {
+					tr.Drank = append(tr.Drank, name)
+				}
+				shots[i] = r.Shots[i]
+			}
+		}
+		tl.Rounds = append(tl.Rounds, tr)
+	}
+	return tl
+}
+
+// leader returns the player with the most kills, or an empty string if more
+// than one player has them
+func leader(players []string, kills []int) string {
+	best, name := 0, ""
+	for i, k := range kills {
+		if i >= len(players) {
+			break
+		}
+		if k > best {
+			best, name = k, players[i]
+		} else if k == best {
+			name = ""
+		}
+	}
+	return name
+}
+
+// Replay sends the rounds of a timeline to the websocket one at a time,
+// waiting the interval between each of them
+func (s *Server) Replay(tl Timeline, interval time.Duration) {
+	for i, r := range tl.Rounds {
+		if i != 0 {
+			time.Sleep(interval)
+		}
+
+		s.ws.SendAll(&websockets.Message{
+			Data: ReplayMessage{
+				Replay: ReplayFrame{
+					Tournament: tl.Tournament,
+					Match:      tl.Match,
+					Players:    tl.Players,
+					Round:      r,
+					Rounds:     len(tl.Rounds),
+				},
+			},
+			Transient: true,
+		})
+	}
+}
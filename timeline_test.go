@@ -0,0 +1,83 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func playingMatch() *Match {
+	tm := testTournament(8)
+	_ = tm.StartTournament()
+	m := tm.Tryouts[0]
+	_ = m.Start()
+	return m
+}
+
+func TestCommitRecordsRound(t *testing.T) {
+	assert := assert.New(t)
+	m := playingMatch()
+
+	scores := [][]int{{1, 0}, {0, 1}, {3, 0}, {0, 0}}
+	shots := []bool{false, false, false, true}
+	assert.Nil(m.Commit(scores, shots))
+
+	assert.Equal(1, len(m.Rounds))
+	r := m.Rounds[0]
+	assert.Equal([]int{1, 0, 3, 0}, r.Ups)
+	assert.Equal([]int{0, 1, 0, 0}, r.Downs)
+	assert.Equal([]int{1, 0, 3, 0}, r.Kills)
+	assert.Equal([]int{0, 1, 1, 1}, r.Shots)
+	assert.False(r.Time.IsZero())
+}
+
+func TestTimeline(t *testing.T) {
+	assert := assert.New(t)
+	m := playingMatch()
+	none := []bool{false, false, false, false}
+
+	assert.Nil(m.Commit([][]int{{1, 0}, {1, 0}, {0, 0}, {0, 0}}, none))
+	assert.Nil(m.Commit([][]int{{0, 0}, {2, 0}, {0, 1}, {0, 0}}, none))
+	assert.Nil(m.Commit([][]int{{0, 0}, {0, 0}, {0, 0}, {0, 0}}, []bool{true, false, false, false}))
+
+	tl := m.Timeline()
+	assert.Equal(m.Tournament.ID, tl.Tournament)
+	assert.Equal(m.Title(), tl.Match)
+	assert.Equal(m.URL(), tl.URL)
+	assert.Equal(m.Players[0].Name, tl.Players[0])
+	assert.Equal(3, len(tl.Rounds))
+
+	r := tl.Rounds[0]
+	assert.Equal(1, r.Round)
+	assert.Equal([]string{tl.Players[0], tl.Players[1]}, r.Scored)
+	assert.Equal([]string{}, r.Drank)
+	assert.Equal("", r.Leader)
+
+	r = tl.Rounds[1]
+	assert.Equal([]string{tl.Players[1]}, r.Scored)
+	assert.Equal([]string{tl.Players[2]}, r.Drank)
+	assert.Equal(tl.Players[1], r.Leader)
+	assert.Equal([]int{1, 3, 0, 0}, r.Kills)
+
+	r = tl.Rounds[2]
+	assert.Equal([]string{}, r.Scored)
+	assert.Equal([]string{tl.Players[0]}, r.Drank)
+	assert.True(r.Elapsed >= 0)
+}
+
+func TestTimelineWithoutRounds(t *testing.T) {
+	assert := assert.New(t)
+
+	tl := NewMatch(nil, 0, "final").Timeline()
+	assert.Equal("", tl.Tournament)
+	assert.Equal(0, len(tl.Rounds))
+}
+
+func TestLeader(t *testing.T) {
+	assert := assert.New(t)
+	ps := []string{"a", "b", "c"}
+
+	assert.Equal("b", leader(ps, []int{1, 4, 2}))
+	assert.Equal("", leader(ps, []int{4, 4, 2}))
+	assert.Equal("", leader(ps, []int{0, 0, 0}))
+	assert.Equal("c", leader(ps, []int{-1, 0, 1}))
+}
@@ -11,32 +11,45 @@ import (
 
 // Tournament is the main container of data for this app.
 type Tournament struct {
-	Name        string    `json:"name"`
-	ID          string    `json:"id"`
-	Players     []Player  `json:"players"`
-	Winners     []Player  `json:"winners"` // TODO: Refactor to pointer
-	Runnerups   []string  `json:"runnerups"`
-	Judges      []Judge   `json:"judges"`
-	Tryouts     []*Match  `json:"tryouts"`
-	Semis       []*Match  `json:"semis"`
-	Final       *Match    `json:"final"`
-	Opened      time.Time `json:"opened"`
-	Started     time.Time `json:"started"`
-	Ended       time.Time `json:"ended"`
-	db          *Database
-	server      *Server
-	length      int
-	finalLength int
+	Version        int        `json:"version"`
+	Name           string     `json:"name"`
+	ID             string     `json:"id"`
+	Mode           string     `json:"mode"`
+	Players        []Player   `json:"players"`
+	Teams          []Team     `json:"teams"`
+	Waitlist       []Player   `json:"waitlist"`
+	Winners        []Player   `json:"winners"` // TODO: Refactor to pointer
+	Runnerups      []string   `json:"runnerups"`
+	Judges         []Judge    `json:"judges"`
+	Tryouts        []*Match   `json:"tryouts"`
+	Semis          []*Match   `json:"semis"`
+	RunnerupRounds []*Match   `json:"runnerup_rounds"`
+	Final          *Match     `json:"final"`
+	StationCount   int        `json:"station_count"`
+	RequireCheckIn bool       `json:"require_check_in"`
+	Stations       []*Station `json:"stations"`
+	Opened         time.Time  `json:"opened"`
+	Started        time.Time  `json:"started"`
+	Ended          time.Time  `json:"ended"`
+	Deleted        time.Time  `json:"deleted"`
+	db             *Database
+	server         *Server
+	archived       bool
+	redrafted      []string
+	length         int
+	finalLength    int
 }
 
 // NewTournament returns a completely new Tournament
 func NewTournament(name, id string, server *Server) (*Tournament, error) {
 	t := Tournament{
-		Name:   name,
-		ID:     id,
-		Opened: time.Now(),
-		db:     server.DB,
-		server: server,
+		Version: SchemaVersion,
+		Name:    name,
+		ID:      id,
+		Mode:    SoloMode,
+		Opened:  time.Now(),
+		db:      server.DB,
+		server:  server,
 	}
 
 	// No matches yet - add four
@@ -51,15 +64,24 @@ func NewTournament(name, id string, server *Server) (*Tournament, error) {
 	t.Final = NewMatch(&t, 0, "final")
 	t.Final.Prefill()
 
+	t.setupStations()
 	t.SetMatchPointers()
 	t.Persist()
 	return &t, nil
 }
 
 // LoadTournament loads a tournament from persisted JSON data
+//
+// Data stored by older versions is migrated to the current schema first.
 func LoadTournament(data []byte, db *Database) (t *Tournament, e error) {
 	t = &Tournament{}
-	err := json.Unmarshal(data, t)
+	data, err := Migrate(data)
+	if err != nil {
+		log.Print(err)
+		return t, err
+	}
+
+	err = json.Unmarshal(data, t)
 	if err != nil {
 		log.Print(err)
 		return t, err
@@ -68,25 +90,70 @@ func LoadTournament(data []byte, db *Database) (t *Tournament, e error) {
 	t.db = db
 	t.server = db.Server
 
+	t.setupStations()
 	t.SetMatchPointers()
+	t.updateReadiness()
 	return
 }
 
+// PersistError is a tournament that could not be saved
+//
+// Handlers answer it as the fault of the server, rather than of the request.
+type PersistError struct {
+	Err error
+}
+
+func (e *PersistError) Error() string {
+	return e.Err.Error()
+}
+
 // Persist tells the database to save this tournament to disk
 func (t *Tournament) Persist() error {
 	if t.db == nil {
 		// This might happen in tests.
 		return errors.New("no database instantiated")
 	}
+	if t.archived {
+		return fmt.Errorf("%s is archived and cannot be changed", t.ID)
+	}
+
+	data, err := t.JSON()
+	if err != nil {
+		return &PersistError{err}
+	}
 
 	go t.server.SendWebsocketUpdate()
+	t.server.queueOverlays(t.ID, data)
+	t.sendDrafts()
 
-	return t.db.Persist(t)
+	if err := t.db.Store.Save(t.ID, data); err != nil {
+		return &PersistError{err}
+	}
+	return nil
+}
+
+// event records something that happened in the tournament
+func (t *Tournament) event(kind string, m *Match, player string) {
+	if t.db == nil {
+		return
+	}
+
+	match := ""
+	if m != nil {
+		match = m.bracketID()
+	}
+	if err := t.db.AddEvent(t, kind, match, player); err != nil {
+		log.Printf("Recording %s event failed: %s", kind, err)
+	}
 }
 
 // JSON returns a JSON representation of the Tournament
+//
+// It is always written in the current schema version.
 func (t *Tournament) JSON() (out []byte, err error) {
-	out, err = json.Marshal(t)
+	c := *t
+	c.Version = SchemaVersion
+	out, err = json.Marshal(&c)
 	return
 }
 
@@ -102,13 +169,110 @@ func (t *Tournament) URL() string {
 //   Generating tryouts
 //   Shuffling players into positions
 func (t *Tournament) AddPlayer(name, color string) error {
+	if t.IsTeamMode() {
+		return errors.New("team tournaments can only be joined by teams")
+	}
+	if !t.Started.IsZero() {
+		return errors.New("tournament has started, register late instead")
+	}
+
 	p := Player{Name: name, PreferredColor: color}
 	if !t.CanJoin(name) {
 		return errors.New("player already in match")
 	}
 
 	t.Players = append(t.Players, p)
+	t.addPlayerMatches()
+
+	t.ShufflePlayers()
+	t.Persist() // TODO: Error handling
+
+	return nil
+}
+
+// AddTeam adds a team of two players into a team mode tournament
+func (t *Tournament) AddTeam(name, color string, members []Player) error {
+	if !t.IsTeamMode() {
+		return errors.New("teams can only join team tournaments")
+	}
+	if !t.Started.IsZero() {
+		return errors.New("tournament has started")
+	}
+	if name == "" {
+		return errors.New("team needs a name")
+	}
+	if len(members) != TeamSize {
+		return fmt.Errorf("team needs %d members, got %d", TeamSize, len(members))
+	}
+	if t.getTeam(name) != nil {
+		return fmt.Errorf("team %s already exists", name)
+	}
+	if len(t.Players)+TeamSize > t.MaxPlayers() {
+		return errors.New("tournament is full")
+	}
+
+	team := Team{Name: name, Color: color}
+	for _, p := range members {
+		if p.Name == "" {
+			return errors.New("team member needs a name")
+		}
+		if !t.CanJoin(p.Name) || team.HasMember(p.Name) {
+			return fmt.Errorf("player %s already in tournament", p.Name)
+		}
+		team.Members = append(team.Members, p.Name)
+	}
+
+	for _, p := range members {
+		t.Players = append(t.Players, Player{
+			Name:           p.Name,
+			PreferredColor: p.PreferredColor,
+			Team:           name,
+		})
+	}
+	t.Teams = append(t.Teams, team)
+
+	t.ShufflePlayers()
+	t.Persist() // TODO: Error handling
 
+	return nil
+}
+
+// SetMode sets the tournament mode
+//
+// The mode can only be changed as long as nobody has joined.
+func (t *Tournament) SetMode(mode string) error {
+	if mode == "" {
+		mode = SoloMode
+	}
+	if mode != SoloMode && mode != TeamMode {
+		return fmt.Errorf("unknown tournament mode %s", mode)
+	}
+	if len(t.Players) != 0 {
+		return errors.New("cannot change mode after players have joined")
+	}
+
+	t.Mode = mode
+	return nil
+}
+
+// IsTeamMode returns boolean true if the tournament is played in teams
+func (t *Tournament) IsTeamMode() bool {
+	return t.Mode == TeamMode
+}
+
+// MaxPlayers returns the maximum amount of players the tournament can host
+//
+// Team tournaments only have room for eight teams since every tryout only
+// holds two of them.
+func (t *Tournament) MaxPlayers() int {
+	if t.IsTeamMode() {
+		return 16
+	}
+	return 32
+}
+
+// addPlayerMatches adds more tryouts if the amount of players requires it
+func (t *Tournament) addPlayerMatches() {
 	ts := len(t.Tryouts)
 	ps := len(t.Players)
 	if ts == 4 && ps == 17 {
@@ -118,11 +282,6 @@ func (t *Tournament) AddPlayer(name, color string) error {
 			t.Tryouts = append(t.Tryouts, match)
 		}
 	}
-
-	t.ShufflePlayers()
-	t.Persist() // TODO: Error handling
-
-	return nil
 }
 
 // ShufflePlayers will reposition players into matches
@@ -132,8 +291,13 @@ func (t *Tournament) ShufflePlayers() {
 		t.Players = []Player{}
 	}
 
-	// Shuffle all the players
-	slice := t.Players
+	if t.IsTeamMode() {
+		t.shuffleTeams()
+		return
+	}
+
+	// Shuffle all the players, keeping the tournament in the order they joined
+	slice := append([]Player{}, t.Players...)
 	for i := range slice {
 		j := rand.Intn(i + 1)
 		slice[i], slice[j] = slice[j], slice[i]
@@ -150,6 +314,26 @@ func (t *Tournament) ShufflePlayers() {
 	}
 }
 
+// shuffleTeams will reposition teams into matches, two teams per match
+func (t *Tournament) shuffleTeams() {
+	slice := t.Teams
+	for i := range slice {
+		j := rand.Intn(i + 1)
+		slice[i], slice[j] = slice[j], slice[i]
+	}
+
+	for i, team := range slice {
+		m := t.Tryouts[i/2]
+		for _, name := range team.Members {
+			m.AddPlayer(*t.getPlayer(name))
+		}
+	}
+
+	for _, m := range t.Tryouts {
+		m.Prefill()
+	}
+}
+
 // StartTournament will generate the tournament.
 //
 // This includes:
@@ -165,8 +349,16 @@ func (t *Tournament) StartTournament() error {
 	if ps > 32 {
 		return fmt.Errorf("Tournament can only host 32 players, got %d", ps)
 	}
+	if t.IsTeamMode() && len(t.Teams) < MinTeams {
+		return fmt.Errorf("Tournament needs at least %d teams, got %d", MinTeams, len(t.Teams))
+	}
+	if t.IsTeamMode() && len(t.Teams) == MinTeams {
+		t.Tryouts = t.Tryouts[:2]
+	}
 
 	t.Started = time.Now()
+	t.updateReadiness()
+	t.event("tournament_started", nil, "")
 	t.Persist()
 	return nil
 }
@@ -178,9 +370,53 @@ func (t *Tournament) PopulateRunnerups(m *Match) error {
 		return err
 	}
 
-	for i := 0; m.ActualPlayers() < 4; i++ {
-		p := r[i]
+	if t.IsTeamMode() {
+		return t.populateRunnerupTeams(m, r)
+	}
+
+	for _, p := range r {
+		if m.ActualPlayers() >= 4 {
+			break
+		}
+
+		// Skip anyone already in the match, who did not show up for it or
+		// who is busy playing a runner-up round
+		if m.getPlayer(p.Name) != nil || m.HasForfeited(p.Name) || t.isPlaying(p.Name) {
+			continue
+		}
 		m.AddPlayer(p)
+		m.Backfilled = append(m.Backfilled, p.Name)
+	}
+
+	if m.ActualPlayers() < 4 {
+		return errors.New("not enough runnerups")
+	}
+	return nil
+}
+
+// populateRunnerupTeams fills a match with the teams of the best runnerups
+//
+// Teams are always moved into the runnerups together, so picking the team of
+// the best remaining runnerup keeps the teams intact.
+func (t *Tournament) populateRunnerupTeams(m *Match, r []Player) error {
+	for _, p := range r {
+		if m.ActualPlayers() >= 4 {
+			break
+		}
+
+		team := t.getTeam(p.Team)
+		if team == nil || m.HasTeam(team.Name) || m.HasForfeited(p.Name) || t.isPlaying(p.Name) {
+			continue
+		}
+
+		for _, name := range team.Members {
+			m.AddPlayer(*t.getPlayer(name))
+			m.Backfilled = append(m.Backfilled, name)
+		}
+	}
+
+	if m.ActualPlayers() < 4 {
+		return errors.New("not enough runnerup teams")
 	}
 	return nil
 }
@@ -207,50 +443,57 @@ func (t *Tournament) GetRunnerups() (ps []Player, err error) {
 // UpdatePlayers updates all the player objects with their scores from
 // all the matches they have participated in.
 func (t *Tournament) UpdatePlayers() error {
-	// Make sure all players have their score reset to nothing
-	for i := range t.Players {
-		t.Players[i].Reset()
-	}
+	copy(t.Players, t.playerTotals())
+	return nil
+}
 
-	for _, m := range t.Tryouts {
-		for _, p := range m.Players {
-			if !p.IsPrefill() {
-				t.getPlayer(p.Name).Update(p)
-			}
-		}
+// playerTotals returns copies of the players with their scores from all the
+// matches they have participated in, leaving the players of the tournament
+// as they are
+func (t *Tournament) playerTotals() []Player {
+	ps := make([]Player, len(t.Players))
+	index := make(map[string]int, len(t.Players))
+	for i, p := range t.Players {
+		// Reset drops the achievements, so the copy does not share them
+		p.Reset()
+		ps[i] = p
+		index[p.Name] = i
 	}
 
-	for _, m := range t.Semis {
+	for _, m := range t.Matches() {
 		for _, p := range m.Players {
-			if !p.IsPrefill() {
-				t.getPlayer(p.Name).Update(p)
+			if i, ok := index[p.Name]; ok && !p.IsPrefill() {
+				ps[i].Update(p)
 			}
 		}
-	}
 
-	for _, p := range t.Final.Players {
-		if !p.IsPrefill() {
-			t.getPlayer(p.Name).Update(p)
+		for _, name := range m.Forfeits {
+			if i, ok := index[name]; ok {
+				ps[i].Forfeits++
+			}
 		}
 	}
-
-	return nil
+	return ps
 }
 
 // MovePlayers moves the winner(s) of a Match into the next bracket of matches
 // or into the Runnerup bracket.
 func (t *Tournament) MovePlayers(m *Match) error {
+	if t.IsTeamMode() {
+		return t.moveTeams(m)
+	}
+
 	if m.Kind == "tryout" {
-		ps := SortByKills(m.Players)
+		// Players that have withdrawn forfeit their placement and leave room
+		// for the next in line.
+		ps := t.withoutWithdrawn(m.Standings())
 		for i := 0; i < len(ps); i++ {
 			p := ps[i]
 			// If we are in a four-match tryout, both the winner and the second-place
-			// are to be sent to the semis
-			if len(t.Tryouts) == 4 && i < 2 || i == 0 {
-				// This spreads the winners into the semis so that the winners do not
-				// face off immediately in the semis
-				index := (i + m.Index) % 2
-				t.Semis[index].AddPlayer(p)
+			// are to be sent to the semis. They are spread so that the winners do
+			// not face off immediately in the semis.
+			if next := t.advanceTo(m, i); next != nil {
+				next.AddPlayer(p)
 
 				// If the player is also inside of the runnerups, move them from the
 				// runnerup roster since they now have advanced to the finals. This
@@ -283,9 +526,9 @@ func (t *Tournament) MovePlayers(m *Match) error {
 
 	if m.Kind == "semi" {
 		// For the semis, just place the winner and silver into the final
-		for i, p := range SortByKills(m.Players) {
-			if i < 2 {
-				t.Final.AddPlayer(p)
+		for i, p := range t.withoutWithdrawn(m.Standings()) {
+			if next := t.advanceTo(m, i); next != nil {
+				next.AddPlayer(p)
 			}
 		}
 	}
@@ -299,15 +542,117 @@ func (t *Tournament) MovePlayers(m *Match) error {
 	for _, p := range ps {
 		t.Runnerups = append(t.Runnerups, p.Name)
 	}
+
+	if m.Kind == "tryout" {
+		t.addRunnerupRounds()
+	}
 	return nil
 }
 
+// byeTryout moves the players of a voided tryout into the runnerups
+//
+// The semi slots the tryout would have filled stay empty until the semis
+// start, when they are backfilled with the best runnerups.
+func (t *Tournament) byeTryout(m *Match) {
+	for _, p := range m.Players {
+		if p.IsPrefill() || t.isRunnerup(p.Name) {
+			continue
+		}
+		t.Runnerups = append(t.Runnerups, p.Name)
+	}
+
+	ps, err := t.GetRunnerups()
+	if err != nil {
+		log.Print(err)
+		return
+	}
+	t.Runnerups = make([]string, 0, len(ps))
+	for _, p := range ps {
+		t.Runnerups = append(t.Runnerups, p.Name)
+	}
+
+	t.addRunnerupRounds()
+}
+
+// isRunnerup returns boolean whether the player is in the runnerup roster
+func (t *Tournament) isRunnerup(name string) bool {
+	for _, r := range t.Runnerups {
+		if r == name {
+			return true
+		}
+	}
+	return false
+}
+
+// moveTeams moves the winning team of a Match into the next bracket of matches
+// and the losing team into the Runnerup bracket.
+//
+// Only one team per match advances, and the tryout winners are spread so that
+// tryouts 1 and 3 meet in the first semi and tryouts 2 and 4 in the second.
+func (t *Tournament) moveTeams(m *Match) error {
+	for i, ts := range t.withoutWithdrawnTeams(m.TeamStandings()) {
+		if i == 0 {
+			if next := t.advanceTo(m, i); next != nil {
+				for _, p := range ts.Players {
+					next.AddPlayer(p)
+				}
+			}
+			t.removeRunnerups(ts.Players)
+		} else if m.Kind == "tryout" {
+			t.addRunnerups(ts.Players)
+		}
+	}
+
+	// Get the runnerups and sort their names into the Runnerup array
+	ps, err := t.GetRunnerups()
+	if err != nil {
+		return err
+	}
+	t.Runnerups = make([]string, 0)
+	for _, p := range ps {
+		t.Runnerups = append(t.Runnerups, p.Name)
+	}
+
+	if m.Kind == "tryout" {
+		t.addRunnerupRounds()
+	}
+	return nil
+}
+
+// addRunnerups adds players into the runnerup roster unless already there
+func (t *Tournament) addRunnerups(ps []Player) {
+	for _, p := range ps {
+		found := false
+		for _, r := range t.Runnerups {
+			if r == p.Name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Runnerups = append(t.Runnerups, p.Name)
+		}
+	}
+}
+
+// removeRunnerups removes players from the runnerup roster
+func (t *Tournament) removeRunnerups(ps []Player) {
+	for _, p := range ps {
+		for j := 0; j < len(t.Runnerups); j++ {
+			if t.Runnerups[j] == p.Name {
+				t.Runnerups = append(t.Runnerups[:j], t.Runnerups[j+1:]...)
+				break
+			}
+		}
+	}
+}
+
 // NextMatch returns the next match
 func (t *Tournament) NextMatch() (m *Match, err error) {
 	// Firstly, check the tryouts
 	for x := range t.Tryouts {
 		m = t.Tryouts[x]
-		if !m.IsEnded() {
+		if !m.IsDone() {
 			return
 		}
 	}
@@ -316,28 +661,51 @@ func (t *Tournament) NextMatch() (m *Match, err error) {
 	// check the semis
 	for x := range t.Semis {
 		m = t.Semis[x]
-		if !m.IsEnded() {
+		if !m.IsDone() {
 			return
 		}
 	}
 
-	if !t.Final.IsEnded() {
+	if !t.Final.IsDone() {
 		return t.Final, nil
 	}
 
 	return m, errors.New("all matches have been played")
 }
 
+// updateReadiness refreshes which of the matches are ready to be started
+func (t *Tournament) updateReadiness() {
+	for _, m := range t.Matches() {
+		m.updateReadiness()
+	}
+}
+
 // AwardMedals places the winning players in the Winners position
 func (t *Tournament) AwardMedals(m *Match) error {
 	if m.Kind != "final" {
 		return errors.New("awarding medals outside of the final")
 	}
 
-	ps := SortByKills(m.Players)
-	t.Winners = ps[0:3]
+	if t.IsTeamMode() {
+		// In team mode, the winners are all the finalists ordered by how their
+		// team placed.
+		t.Winners = make([]Player, 0, 4)
+		for _, ts := range t.withoutWithdrawnTeams(m.TeamStandings()) {
+			t.Winners = append(t.Winners, ts.Players...)
+		}
+	} else {
+		ps := t.withoutWithdrawn(m.Standings())
+		if len(ps) > 3 {
+			ps = ps[0:3]
+		}
+		t.Winners = ps
+	}
 
 	t.Ended = time.Now()
+	if len(t.Winners) != 0 {
+		t.event("medals_awarded", m, t.Winners[0].Name)
+		t.event("tournament_ended", nil, t.Winners[0].Name)
+	}
 	t.Persist()
 
 	return nil
@@ -350,7 +718,7 @@ func (t *Tournament) IsOpen() bool {
 
 // IsJoinable returns boolean true if the tournament is joinable
 func (t *Tournament) IsJoinable() bool {
-	if len(t.Players) >= 32 {
+	if len(t.Players) >= t.MaxPlayers() {
 		return false
 	}
 	return t.IsOpen() && t.Started.IsZero()
@@ -369,7 +737,7 @@ func (t *Tournament) IsRunning() bool {
 
 // CanJoin checks if a player is allowed to join or is already in the tournament
 func (t *Tournament) CanJoin(name string) bool {
-	if len(t.Players) >= 32 {
+	if len(t.Players) >= t.MaxPlayers() {
 		return false
 	}
 	for _, p := range t.Players {
@@ -380,6 +748,20 @@ func (t *Tournament) CanJoin(name string) bool {
 	return true
 }
 
+// Matches returns all the matches of the tournament in the order they are played
+//
+// The runner-up rounds are played alongside the semis.
+func (t *Tournament) Matches() []*Match {
+	ms := make([]*Match, 0, len(t.Tryouts)+len(t.RunnerupRounds)+len(t.Semis)+1)
+	ms = append(ms, t.Tryouts...)
+	ms = append(ms, t.RunnerupRounds...)
+	ms = append(ms, t.Semis...)
+	if t.Final != nil {
+		ms = append(ms, t.Final)
+	}
+	return ms
+}
+
 // SetMatchPointers loops over all matches in the tournament and sets the tournament reference
 //
 // When loading tournaments from the database, these references will not be set.
@@ -394,6 +776,16 @@ func (t *Tournament) SetMatchPointers() error {
 		for j := range m.Players {
 			m.Players[j].Match = m
 		}
+		m.deriveState()
+	}
+
+	for i := range t.RunnerupRounds {
+		m = t.RunnerupRounds[i]
+		m.Tournament = t
+		for j := range m.Players {
+			m.Players[j].Match = m
+		}
+		m.deriveState()
 	}
 
 	for i := range t.Semis {
@@ -402,11 +794,13 @@ func (t *Tournament) SetMatchPointers() error {
 		for j := range m.Players {
 			m.Players[j].Match = m
 		}
+		m.deriveState()
 	}
 	t.Final.Tournament = t
 	for i := range t.Final.Players {
 		t.Final.Players[i].Match = t.Final
 	}
+	t.Final.deriveState()
 
 	// log.Printf("%s: Pointers loaded.", t.ID)
 	return nil
@@ -423,3 +817,12 @@ func (t *Tournament) getPlayer(name string) (p *Player) {
 	log.Print(fmt.Sprintf("no player named %s found", name))
 	return
 }
+
+func (t *Tournament) getTeam(name string) *Team {
+	for i := range t.Teams {
+		if t.Teams[i].Name == name {
+			return &t.Teams[i]
+		}
+	}
+	return nil
+}
@@ -11,7 +11,7 @@ import (
 func testTournament(count int) (t *Tournament) {
 	s := strconv.Itoa(count)
 	db := MockDatabase()
-	t, err := NewTournament("Tournament "+s, s, db)
+	t, err := NewTournament("Tournament "+s, s, testServer(db))
 	if err != nil {
 		log.Fatal("tournament creation failed")
 	}
@@ -36,17 +36,25 @@ func testTournament(count int) (t *Tournament) {
 	return
 }
 
+// playMatch starts and ends a match, with the first player winning and
+// every other player placing in order so that there are no ties.
+func playMatch(m *Match) {
+	m.Start()
+	for i := range m.Players {
+		m.Players[i].AddKill(m.Length() - i)
+	}
+	m.End()
+}
+
 func endTryouts(t *Tournament) {
 	for x := range t.Tryouts {
-		t.Tryouts[x].Start()
-		t.Tryouts[x].End()
+		playMatch(t.Tryouts[x])
 	}
 }
 
 func endSemis(t *Tournament) {
 	for x := range t.Semis {
-		t.Semis[x].Start()
-		t.Semis[x].End()
+		playMatch(t.Semis[x])
 	}
 }
 
@@ -152,8 +160,7 @@ func TestNextMatchNoMatchesAreStartedWithTryouts(t *testing.T) {
 	assert.Equal(0, m.Index)
 	assert.Equal("tryout", m.Kind)
 
-	m.Start()
-	m.End()
+	playMatch(m)
 
 	m, err = tm.NextMatch()
 	assert.Nil(err)
@@ -192,8 +199,7 @@ func TestNextMatchEverythingDone(t *testing.T) {
 	tm.StartTournament()
 	endTryouts(tm)
 	endSemis(tm)
-	tm.Final.Start()
-	tm.Final.End()
+	playMatch(tm.Final)
 
 	_, err := tm.NextMatch()
 	assert.NotNil(err)
@@ -201,7 +207,7 @@ func TestNextMatchEverythingDone(t *testing.T) {
 
 func TestUpdatePlayer(t *testing.T) {
 	assert := assert.New(t)
-	tm, _ := NewTournament("player test", "test", nil)
+	tm, _ := NewTournament("player test", "test", testServer(MockDatabase()))
 	tm.AddPlayer("winner", "yellow")
 	tm.AddPlayer("loser1", "green")
 	tm.AddPlayer("loser2", "blue")
@@ -410,7 +416,7 @@ func TestEndComplete16PlayerTournamentKillsOnly(t *testing.T) {
 	f.Players[0].AddKill(7)
 	f.Players[1].AddKill(2)
 	f.Players[2].AddKill(9)
-	f.Players[3].AddKill(10)
+	f.Players[3].AddKill(20)
 	gold := f.Players[3].Name
 	lowe := f.Players[2].Name
 	bronze := f.Players[0].Name
@@ -667,7 +673,7 @@ func TestEndComplete19PlayerTournamentKillsOnly(t *testing.T) {
 	f.Players[0].AddKill(7)
 	f.Players[1].AddKill(2)
 	f.Players[2].AddKill(9)
-	f.Players[3].AddKill(10)
+	f.Players[3].AddKill(20)
 	gold := f.Players[3].Name
 	lowe := f.Players[2].Name
 	bronze := f.Players[0].Name
@@ -681,7 +687,7 @@ func TestEndComplete19PlayerTournamentKillsOnly(t *testing.T) {
 
 func TestAddPlayerColorIsSet(t *testing.T) {
 	assert := assert.New(t)
-	tm, _ := NewTournament("Hehe", "hehe", MockDatabase())
+	tm, _ := NewTournament("Hehe", "hehe", testServer(MockDatabase()))
 
 	err := tm.AddPlayer("DinMamma", "mother")
 	assert.Nil(err)
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Join adds a player into the tournament, or onto the waitlist if it is full
+//
+// The returned position is the 1-indexed place on the waitlist, or 0 if the
+// player joined the tournament directly.
+func (t *Tournament) Join(name, color string) (int, error) {
+	if name == "" {
+		return 0, errors.New("need a name")
+	}
+	if t.hasPlayer(name) || t.WaitlistPosition(name) != 0 {
+		return 0, fmt.Errorf("%s has already joined", name)
+	}
+
+	if t.CanJoin(name) {
+		return 0, t.AddPlayer(name, color)
+	}
+
+	if !t.Started.IsZero() {
+		return 0, errors.New("tournament has started")
+	}
+	if t.IsTeamMode() {
+		return 0, errors.New("tournament is full")
+	}
+
+	t.Waitlist = append(t.Waitlist, Player{Name: name, PreferredColor: color})
+	t.Persist()
+	return len(t.Waitlist), nil
+}
+
+// WaitlistPosition returns the 1-indexed place of a player on the waitlist,
+// or 0 if the player is not waiting
+func (t *Tournament) WaitlistPosition(name string) int {
+	for i, p := range t.Waitlist {
+		if p.Name == name {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// LeaveWaitlist removes a player from the waitlist
+func (t *Tournament) LeaveWaitlist(name string) error {
+	pos := t.WaitlistPosition(name)
+	if pos == 0 {
+		return fmt.Errorf("%s is not on the waitlist", name)
+	}
+
+	t.Waitlist = append(t.Waitlist[:pos-1], t.Waitlist[pos:]...)
+	t.Persist()
+	return nil
+}
+
+// promoteWaitlist moves players from the waitlist into the tournament for
+// as long as there is room
+func (t *Tournament) promoteWaitlist() {
+	for len(t.Waitlist) != 0 && t.Started.IsZero() {
+		p := t.Waitlist[0]
+		if !t.CanJoin(p.Name) {
+			return
+		}
+
+		t.Waitlist = t.Waitlist[1:]
+		if err := t.AddPlayer(p.Name, p.PreferredColor); err != nil {
+			return
+		}
+	}
+}
+
+func (t *Tournament) hasPlayer(name string) bool {
+	for _, p := range t.Players {
+		if p.Name == name {
+			return true
+		}
+	}
+	return false
+}
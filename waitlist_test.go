@@ -0,0 +1,80 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestJoinWhenFullGoesToWaitlist(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTournament(32)
+
+	pos, err := tm.Join("first", "green")
+	assert.Nil(err)
+	assert.Equal(1, pos)
+
+	pos, err = tm.Join("second", "blue")
+	assert.Nil(err)
+	assert.Equal(2, pos)
+
+	assert.Equal(32, len(tm.Players))
+	assert.Equal(2, tm.WaitlistPosition("second"))
+}
+
+func TestJoinWithRoomJoinsDirectly(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTournament(8)
+
+	pos, err := tm.Join("ninth", "green")
+	assert.Nil(err)
+	assert.Equal(0, pos)
+	assert.Equal(9, len(tm.Players))
+	assert.Equal(0, len(tm.Waitlist))
+}
+
+func TestJoinTwiceFails(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTournament(32)
+
+	_, err := tm.Join("1", "green")
+	assert.NotNil(err)
+
+	_, err = tm.Join("waiting", "green")
+	assert.Nil(err)
+	_, err = tm.Join("waiting", "green")
+	assert.NotNil(err)
+}
+
+func TestWithdrawPromotesFromWaitlist(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTournament(32)
+	tm.Join("first", "green")
+	tm.Join("second", "blue")
+
+	assert.Nil(tm.WithdrawPlayer("5"))
+	assert.Equal(32, len(tm.Players))
+	assert.True(tm.hasPlayer("first"))
+	assert.Equal(1, tm.WaitlistPosition("second"))
+}
+
+func TestLeaveWaitlist(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTournament(32)
+	tm.Join("first", "green")
+	tm.Join("second", "blue")
+
+	assert.Nil(tm.WithdrawPlayer("first"))
+	assert.Equal(0, tm.WaitlistPosition("first"))
+	assert.Equal(1, tm.WaitlistPosition("second"))
+	assert.NotNil(tm.LeaveWaitlist("first"))
+}
+
+func TestCannotWaitlistAfterStart(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTournament(32)
+	tm.StartTournament()
+
+	_, err := tm.Join("late", "green")
+	assert.NotNil(err)
+	assert.Equal(0, len(tm.Waitlist))
+}
@@ -0,0 +1,394 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// WebhookEvents are the events that can be sent to webhooks
+//
+// The medals are awarded when the final ends, right before the tournament
+// ends.
+var WebhookEvents = []string{
+	"tournament_started",
+	"match_started",
+	"match_ended",
+	"medals_awarded",
+	"tournament_ended",
+}
+
+// WebhookAttempts is how many times a delivery is tried before giving up,
+// and WebhookBackoff is how long to wait before the first retry. The wait is
+// doubled for every retry after that.
+var (
+	WebhookAttempts = 5
+	WebhookBackoff  = 2 * time.Second
+)
+
+// webhookClient is what the webhooks are posted with
+//
+// It refuses to connect to internal addresses, whatever the host resolves to
+// when the webhook is posted.
+var webhookClient = &http.Client{
+	Timeout: 10 * time.Second,
+	Transport: &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout: 5 * time.Second,
+			Control: webhookDialControl,
+		}).DialContext,
+	},
+}
+
+// webhookInternal lets the webhooks reach internal addresses, which only the
+// tests need
+var webhookInternal = false
+
+// Webhook is a URL that is posted to when things happen in a tournament
+//
+// The payloads are signed with the secret, and the signature is sent in the
+// X-Drunkenfall-Signature header as `sha256=<hex>`. Without any events, the
+// webhook gets all of them.
+type Webhook struct {
+	ID      string    `json:"id"`
+	URL     string    `json:"url"`
+	Secret  string    `json:"secret,omitempty"`
+	Events  []string  `json:"events"`
+	Created time.Time `json:"created"`
+}
+
+// WebhookPayload is what is posted to a webhook
+type WebhookPayload struct {
+	Event      string          `json:"event"`
+	Tournament string          `json:"tournament"`
+	Name       string          `json:"name"`
+	URL        string          `json:"url"`
+	Match      string          `json:"match,omitempty"`
+	Player     string          `json:"player,omitempty"`
+	Results    []WebhookResult `json:"results,omitempty"`
+	Time       time.Time       `json:"time"`
+}
+
+// WebhookResult is how a player placed, in the results of a payload
+//
+// The standings of the match are the results when a match ends, and the
+// medalists when the medals are awarded and the tournament ends. The players
+// of a team share their place.
+type WebhookResult struct {
+	Place int    `json:"place"`
+	Name  string `json:"name"`
+	Team  string `json:"team,omitempty"`
+	Color string `json:"color,omitempty"`
+	Kills int    `json:"kills"`
+	Self  int    `json:"self"`
+	Shots int    `json:"shots"`
+}
+
+// Delivery is an attempt to post an event to a webhook
+//
+// Every event gets an ID of its own, which is the same for all the attempts
+// to deliver it. The status is 0 if there was no response.
+type Delivery struct {
+	Tournament string    `json:"tournament"`
+	Webhook    string    `json:"webhook"`
+	ID         string    `json:"id"`
+	Event      string    `json:"event"`
+	Attempt    int       `json:"attempt"`
+	Status     int       `json:"status"`
+	Error      string    `json:"error,omitempty"`
+	Time       time.Time `json:"time"`
+}
+
+// Wants returns boolean whether the webhook gets the event
+func (h Webhook) Wants(event string) bool {
+	if len(h.Events) == 0 {
+		return true
+	}
+	for _, e := range h.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// Sign returns the signature of a payload
+func (h Webhook) Sign(body []byte) string {
+	mac := hmac.New(sha256.New, []byte(h.Secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// AddWebhook adds a webhook to a tournament
+//
+// Without a secret, a random one is made. The webhook is returned with the
+// secret, since it is not shown again.
+func (d *Database) AddWebhook(t *Tournament, hook Webhook) (Webhook, error) {
+	u, err := url.Parse(hook.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return hook, fmt.Errorf("'%s' is not an http or https URL", hook.URL)
+	}
+	if isInternalHost(u.Hostname()) && !webhookInternal {
+		return hook, fmt.Errorf("'%s' is an internal address", u.Hostname())
+	}
+	for _, e := range hook.Events {
+		if !isWebhookEvent(e) {
+			return hook, fmt.Errorf("unknown event '%s'", e)
+		}
+	}
+	if hook.Events == nil {
+		hook.Events = []string{}
+	}
+
+	hook.ID = randomHex(8)
+	hook.Created = time.Now()
+	if hook.Secret == "" {
+		hook.Secret = randomHex(32)
+	}
+
+	hooks, err := d.Store.Webhooks(t.ID)
+	if err != nil {
+		return hook, err
+	}
+	if err := d.Store.SaveWebhooks(t.ID, append(hooks, hook)); err != nil {
+		return hook, err
+	}
+
+	log.Printf("Added webhook %s to %s", hook.ID, t.ID)
+	return hook, nil
+}
+
+// RemoveWebhook takes a webhook off a tournament
+func (d *Database) RemoveWebhook(t *Tournament, id string) error {
+	hooks, err := d.Store.Webhooks(t.ID)
+	if err != nil {
+		return err
+	}
+
+	for i, h := range hooks {
+		if h.ID == id {
+			hooks = append(hooks[:i], hooks[i+1:]...)
+			return d.Store.SaveWebhooks(t.ID, hooks)
+		}
+	}
+	return fmt.Errorf("%s has no webhook %s", t.ID, id)
+}
+
+// Webhooks returns the webhooks of a tournament, without their secrets
+func (d *Database) Webhooks(t *Tournament) ([]Webhook, error) {
+	hooks, err := d.Store.Webhooks(t.ID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range hooks {
+		hooks[i].Secret = ""
+	}
+	return hooks, nil
+}
+
+// notify sends an event to the webhooks of the tournament that want it
+//
+// The deliveries happen in the background, so that a slow webhook does not
+// hold up the tournament.
+func (d *Database) notify(t *Tournament, e Event) {
+	hooks, err := d.Store.Webhooks(t.ID)
+	if err != nil {
+		log.Printf("Loading the webhooks of %s failed: %s", t.ID, err)
+		return
+	}
+
+	for _, h := range hooks {
+		if !h.Wants(e.Kind) {
+			continue
+		}
+
+		body, err := json.Marshal(WebhookPayload{
+			Event:      e.Kind,
+			Tournament: t.ID,
+			Name:       t.Name,
+			URL:        t.URL(),
+			Match:      e.Match,
+			Player:     e.Player,
+			Results:    t.webhookResults(e),
+			Time:       e.Time,
+		})
+		if err != nil {
+			log.Print(err)
+			return
+		}
+		go d.deliver(t.ID, h, e.Kind, body)
+	}
+}
+
+// deliver posts a payload to a webhook, retrying with a growing wait until
+// it is accepted or the attempts run out
+//
+// Every attempt is logged. Client errors other than timeouts and rate
+// limiting are not retried, since they will not go away.
+func (d *Database) deliver(id string, h Webhook, event string, body []byte) {
+	delivery := randomHex(8)
+	wait := WebhookBackoff
+
+	for attempt := 1; attempt <= WebhookAttempts; attempt++ {
+		status, err := postWebhook(h, delivery, event, body)
+
+		entry := Delivery{
+			Tournament: id,
+			Webhook:    h.ID,
+			ID:         delivery,
+			Event:      event,
+			Attempt:    attempt,
+			Status:     status,
+			Time:       time.Now(),
+		}
+		if err != nil {
+			entry.Error = err.Error()
+		}
+		if err := d.Store.AddDelivery(entry); err != nil {
+			log.Printf("Logging delivery %s failed: %s", delivery, err)
+		}
+
+		if err == nil {
+			return
+		}
+		if status >= 400 && status < 500 && status != 408 && status != 429 {
+			break
+		}
+		if attempt < WebhookAttempts {
+			time.Sleep(wait)
+			wait *= 2
+		}
+	}
+	log.Printf("Giving up on delivering %s to webhook %s", event, h.ID)
+}
+
+// postWebhook makes one attempt to deliver a payload, and returns the status
+// of the response
+func postWebhook(h Webhook, delivery, event string, body []byte) (int, error) {
+	req, err := http.NewRequest("POST", h.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Drunkenfall-Event", event)
+	req.Header.Set("X-Drunkenfall-Delivery", delivery)
+	req.Header.Set("X-Drunkenfall-Signature", h.Sign(body))
+
+	res, err := webhookClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return res.StatusCode, fmt.Errorf("webhook answered %s", res.Status)
+	}
+	return res.StatusCode, nil
+}
+
+// webhookResults returns the results that go with an event, if it has any
+func (t *Tournament) webhookResults(e Event) []WebhookResult {
+	switch e.Kind {
+	case "match_ended":
+		for _, m := range t.Matches() {
+			if m.bracketID() != e.Match {
+				continue
+			}
+			if t.IsTeamMode() {
+				ps := []Player{}
+				for _, ts := range m.TeamStandings() {
+					ps = append(ps, ts.Players...)
+				}
+				return placings(ps)
+			}
+			return placings(m.Standings())
+		}
+	case "medals_awarded", "tournament_ended":
+		return placings(t.Winners)
+	}
+	return nil
+}
+
+// placings numbers the places of players in the order they placed
+func placings(ps []Player) []WebhookResult {
+	rs := make([]WebhookResult, 0, len(ps))
+	place := 0
+	for i, p := range ps {
+		if i == 0 || p.Team == "" || p.Team != ps[i-1].Team {
+			place++
+		}
+		rs = append(rs, WebhookResult{
+			Place: place,
+			Name:  p.Name,
+			Team:  p.Team,
+			Color: p.PreferredColor,
+			Kills: p.Kills,
+			Self:  p.Self,
+			Shots: p.Shots,
+		})
+	}
+	return rs
+}
+
+// webhookDialControl stops the webhooks from connecting to internal
+// addresses
+func webhookDialControl(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if isInternalHost(host) && !webhookInternal {
+		return fmt.Errorf("%s is an internal address", host)
+	}
+	return nil
+}
+
+// isInternalHost returns boolean whether the host is this machine or on its
+// network
+//
+// Names other than localhost are only known to be internal once they are
+// resolved, which the dialer checks.
+func isInternalHost(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return true
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast()
+}
+
+// isWebhookEvent returns boolean whether webhooks can get the event
+func isWebhookEvent(e string) bool {
+	for _, o := range WebhookEvents {
+		if o == e {
+			return true
+		}
+	}
+	return false
+}
+
+// randomHex returns n random bytes as hex
+func randomHex(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
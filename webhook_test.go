@@ -0,0 +1,283 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// hookServer answers webhooks with the statuses in order, and then with 200
+type hookServer struct {
+	sync.Mutex
+	*httptest.Server
+	statuses []int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func newHookServer(statuses ...int) *hookServer {
+	h := &hookServer{statuses: statuses}
+	h.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+
+		h.Lock()
+		defer h.Unlock()
+		h.requests = append(h.requests, r)
+		h.bodies = append(h.bodies, body)
+
+		status := 200
+		if len(h.statuses) != 0 {
+			status, h.statuses = h.statuses[0], h.statuses[1:]
+		}
+		w.WriteHeader(status)
+	}))
+	return h
+}
+
+// deliveries waits until the tournament has logged n deliveries
+func deliveries(tm *Tournament, n int) []Delivery {
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		ds, _ := tm.db.Store.Deliveries(tm.ID)
+		if len(ds) >= n || time.Now().After(deadline) {
+			return ds
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// internalWebhooks lets the webhooks post to the test servers
+func internalWebhooks() func() {
+	webhookInternal = true
+	return func() { webhookInternal = false }
+}
+
+func fastRetries() func() {
+	backoff := WebhookBackoff
+	WebhookBackoff = time.Millisecond
+	return func() { WebhookBackoff = backoff }
+}
+
+func TestAddWebhook(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTournament(8)
+	db := tm.db
+
+	_, err := db.AddWebhook(tm, Webhook{URL: "ftp://example.com/"})
+	assert.NotNil(err)
+	_, err = db.AddWebhook(tm, Webhook{URL: "http://example.com/", Events: []string{"nope"}})
+	assert.NotNil(err)
+	for _, u := range []string{"http://localhost:8080/", "http://127.0.0.1/", "http://[::1]/", "http://10.0.0.1/", "http://169.254.169.254/"} {
+		_, err = db.AddWebhook(tm, Webhook{URL: u})
+		assert.NotNil(err, u)
+	}
+
+	hook, err := db.AddWebhook(tm, Webhook{URL: "http://example.com/"})
+	assert.Nil(err)
+	assert.NotEqual("", hook.ID)
+	assert.Equal(64, len(hook.Secret))
+	assert.Equal([]string{}, hook.Events)
+
+	given, err := db.AddWebhook(tm, Webhook{URL: "https://example.com/", Secret: "s3cret"})
+	assert.Nil(err)
+	assert.Equal("s3cret", given.Secret)
+
+	hooks, err := db.Webhooks(tm)
+	assert.Nil(err)
+	assert.Equal(2, len(hooks))
+	assert.Equal(hook.ID, hooks[0].ID)
+	assert.Equal("", hooks[0].Secret)
+	assert.Equal("", hooks[1].Secret)
+}
+
+func TestRemoveWebhook(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTournament(8)
+	db := tm.db
+
+	hook, _ := db.AddWebhook(tm, Webhook{URL: "http://example.com/"})
+	assert.NotNil(db.RemoveWebhook(tm, "nope"))
+	assert.Nil(db.RemoveWebhook(tm, hook.ID))
+
+	hooks, _ := db.Webhooks(tm)
+	assert.Equal(0, len(hooks))
+}
+
+func TestWebhookWants(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.True(Webhook{}.Wants("match_ended"))
+	assert.True(Webhook{Events: []string{"match_ended"}}.Wants("match_ended"))
+	assert.False(Webhook{Events: []string{"match_ended"}}.Wants("match_started"))
+}
+
+func TestWebhookDelivery(t *testing.T) {
+	assert := assert.New(t)
+	defer internalWebhooks()()
+	hs := newHookServer()
+	defer hs.Close()
+
+	tm := testTournament(8)
+	hook, err := tm.db.AddWebhook(tm, Webhook{URL: hs.URL, Events: []string{"tournament_started"}})
+	assert.Nil(err)
+	assert.Nil(tm.StartTournament())
+
+	ds := deliveries(tm, 1)
+	assert.Equal(1, len(ds))
+	assert.Equal(hook.ID, ds[0].Webhook)
+	assert.Equal("tournament_started", ds[0].Event)
+	assert.Equal(1, ds[0].Attempt)
+	assert.Equal(200, ds[0].Status)
+	assert.Equal("", ds[0].Error)
+
+	hs.Lock()
+	defer hs.Unlock()
+	r, body := hs.requests[0], hs.bodies[0]
+	assert.Equal("application/json", r.Header.Get("Content-Type"))
+	assert.Equal("tournament_started", r.Header.Get("X-Drunkenfall-Event"))
+	assert.Equal(ds[0].ID, r.Header.Get("X-Drunkenfall-Delivery"))
+	assert.Equal(hook.Sign(body), r.Header.Get("X-Drunkenfall-Signature"))
+
+	var payload WebhookPayload
+	assert.Nil(json.Unmarshal(body, &payload))
+	assert.Equal("tournament_started", payload.Event)
+	assert.Equal(tm.ID, payload.Tournament)
+	assert.Equal(tm.Name, payload.Name)
+}
+
+func TestWebhookResults(t *testing.T) {
+	assert := assert.New(t)
+	defer internalWebhooks()()
+	hs := newHookServer()
+	defer hs.Close()
+
+	tm := testTournament(16)
+	_, _ = tm.db.AddWebhook(tm, Webhook{URL: hs.URL, Events: []string{"match_ended", "medals_awarded"}})
+	playTournament(tm)
+
+	// Every played match ends, and then the medals are awarded
+	n := 1
+	for _, m := range tm.Matches() {
+		if m.IsEnded() {
+			n++
+		}
+	}
+	assert.Equal(n, len(deliveries(tm, n)))
+
+	hs.Lock()
+	defer hs.Unlock()
+	payloads := map[string]WebhookPayload{}
+	for _, body := range hs.bodies {
+		var p WebhookPayload
+		assert.Nil(json.Unmarshal(body, &p))
+		payloads[p.Event+p.Match] = p
+	}
+
+	final := payloads["match_ended"+tm.Final.bracketID()]
+	assert.Equal(4, len(final.Results))
+	assert.Equal(final.Player, final.Results[0].Name)
+	assert.Equal(1, final.Results[0].Place)
+	assert.Equal(4, final.Results[3].Place)
+
+	medals := payloads["medals_awarded"+tm.Final.bracketID()]
+	assert.Equal(3, len(medals.Results))
+	assert.Equal(tm.Winners[0].Name, medals.Results[0].Name)
+}
+
+func TestWebhooksCannotDialInternalAddresses(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.NotNil(webhookDialControl("tcp", "127.0.0.1:80", nil))
+	assert.NotNil(webhookDialControl("tcp", "[fe80::1]:80", nil))
+	assert.NotNil(webhookDialControl("tcp", "192.168.1.10:443", nil))
+	assert.Nil(webhookDialControl("tcp", "93.184.216.34:443", nil))
+}
+
+func TestWebhooksNeedAdminToken(t *testing.T) {
+	assert := assert.New(t)
+	tm := testTournament(8)
+	s := tm.server
+	s.adminToken = "s3cret"
+	router := mux.NewRouter()
+	router.HandleFunc("/{id}/webhooks/", s.admin(s.WebhooksHandler))
+
+	add := func(token string) int {
+		body := strings.NewReader(`{"url": "http://example.com/"}`)
+		req := httptest.NewRequest("POST", "/"+tm.ID+"/webhooks/", body)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(401, add(""))
+	assert.Equal(401, add("guess"))
+	hooks, _ := tm.db.Webhooks(tm)
+	assert.Equal(0, len(hooks))
+}
+
+func TestWebhookRetries(t *testing.T) {
+	assert := assert.New(t)
+	defer internalWebhooks()()
+	defer fastRetries()()
+	hs := newHookServer(500, 503)
+	defer hs.Close()
+
+	tm := testTournament(8)
+	_, _ = tm.db.AddWebhook(tm, Webhook{URL: hs.URL, Events: []string{"tournament_started"}})
+	assert.Nil(tm.StartTournament())
+
+	ds := deliveries(tm, 3)
+	assert.Equal(3, len(ds))
+	for i, d := range ds {
+		assert.Equal(i+1, d.Attempt)
+		assert.Equal(ds[0].ID, d.ID)
+	}
+	assert.Equal(500, ds[0].Status)
+	assert.NotEqual("", ds[0].Error)
+	assert.Equal(503, ds[1].Status)
+	assert.Equal(200, ds[2].Status)
+}
+
+func TestWebhookGivesUp(t *testing.T) {
+	assert := assert.New(t)
+	defer internalWebhooks()()
+	defer fastRetries()()
+	hs := newHookServer(500, 500, 500, 500, 500, 500)
+	defer hs.Close()
+
+	tm := testTournament(8)
+	_, _ = tm.db.AddWebhook(tm, Webhook{URL: hs.URL, Events: []string{"tournament_started"}})
+	assert.Nil(tm.StartTournament())
+
+	ds := deliveries(tm, WebhookAttempts)
+	assert.Equal(WebhookAttempts, len(ds))
+	assert.Equal(500, ds[len(ds)-1].Status)
+}
+
+func TestWebhookClientErrorsAreNotRetried(t *testing.T) {
+	assert := assert.New(t)
+	defer internalWebhooks()()
+	defer fastRetries()()
+	hs := newHookServer(404, 404)
+	defer hs.Close()
+
+	tm := testTournament(8)
+	_, _ = tm.db.AddWebhook(tm, Webhook{URL: hs.URL, Events: []string{"tournament_started"}})
+	assert.Nil(tm.StartTournament())
+
+	deliveries(tm, 1)
+	time.Sleep(50 * time.Millisecond)
+	ds, _ := tm.db.Store.Deliveries(tm.ID)
+	assert.Equal(1, len(ds))
+	assert.Equal(404, ds[0].Status)
+}
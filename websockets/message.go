@@ -1,8 +1,12 @@
 package websockets
 
 // Message is the data to send back
+//
+// Transient messages are only sent to the clients that are connected, and
+// not to the ones that connect later.
 type Message struct {
-	Data interface{} `json:"data"`
+	Data      interface{} `json:"data"`
+	Transient bool        `json:"-"`
 }
 
 // Ping is a simple ping message
@@ -132,7 +132,9 @@ func (s *Server) Listen() {
 		// broadcast message for all clients
 		case msg := <-s.sendAllCh:
 			log.Println("Send all:", msg)
-			s.messages = append(s.messages, msg)
+			if !msg.Transient {
+				s.messages = append(s.messages, msg)
+			}
 			s.sendAll(msg)
 
 		case err := <-s.errCh: